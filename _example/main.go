@@ -10,7 +10,7 @@ import (
 
 func main() {
 	// Create a new SSEPubSubService
-	ssePubSub := pubsubsse.NewSSEPubSubService()
+	ssePubSub := pubsubsse.MustNewSSEPubSubService()
 
 	// Handle endpoints
 	http.Handle("/", http.FileServer(http.Dir("./web"))) // Serve static files
@@ -0,0 +1,137 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"time"
+)
+
+type autoDeleteKind int
+
+const (
+	autoDeleteNever autoDeleteKind = iota
+	autoDeleteOnEmpty
+	autoDeleteAfter
+)
+
+// AutoDeletePolicy controls when a topic is removed automatically.
+type AutoDeletePolicy struct {
+	kind  autoDeleteKind
+	after time.Duration
+}
+
+var (
+	// AutoDeleteNever keeps the topic until it is removed. This is the default.
+	AutoDeleteNever = AutoDeletePolicy{kind: autoDeleteNever}
+	// AutoDeleteOnEmpty removes the topic when the last subscriber leaves.
+	AutoDeleteOnEmpty = AutoDeletePolicy{kind: autoDeleteOnEmpty}
+)
+
+// AutoDeleteAfter removes the topic when it had no subscribers for d.
+// A new subscriber within d keeps the topic.
+func AutoDeleteAfter(d time.Duration) AutoDeletePolicy {
+	return AutoDeletePolicy{kind: autoDeleteAfter, after: d}
+}
+
+// Set the auto delete policy of a topic
+// remove is called to delete the topic when the policy applies.
+// 0. Stop a pending auto delete
+// 1. Store the policy
+// 2. AutoDeleteAfter: start the timer if the topic is empty already
+func (t *Topic) setAutoDelete(policy AutoDeletePolicy, remove func()) {
+	t.lock.Lock()
+	if t.autoDeleteTimer != nil {
+		t.autoDeleteTimer.Stop()
+		t.autoDeleteTimer = nil
+	}
+	t.autoDelete = policy
+	t.autoDeleteRemove = remove
+	t.lock.Unlock()
+
+	if policy.kind == autoDeleteAfter {
+		t.checkAutoDelete()
+	}
+}
+
+// Disable auto delete, e.g. because the topic is removed
+func (t *Topic) stopAutoDelete() {
+	t.setAutoDelete(AutoDeleteNever, nil)
+}
+
+// Check the auto delete policy after the subscribers changed
+// 1. Stop a pending auto delete if the topic has subscribers
+// 2. AutoDeleteOnEmpty: remove the topic if it is empty
+// 3. AutoDeleteAfter: remove the topic if it is still empty after the duration
+func (t *Topic) checkAutoDelete() {
+	t.lock.Lock()
+
+	// Stop a pending auto delete if the topic has subscribers
+	if len(t.clients) > 0 {
+		if t.autoDeleteTimer != nil {
+			t.autoDeleteTimer.Stop()
+			t.autoDeleteTimer = nil
+		}
+		t.lock.Unlock()
+		return
+	}
+
+	remove := t.autoDeleteRemove
+	switch t.autoDelete.kind {
+	case autoDeleteOnEmpty:
+		t.autoDelete = AutoDeleteNever
+		t.lock.Unlock()
+
+		// Remove the topic without holding the lock
+		remove()
+		return
+	case autoDeleteAfter:
+		if t.autoDeleteTimer == nil {
+			var timer *time.Timer
+			timer = time.AfterFunc(t.autoDelete.after, func() {
+				t.lock.Lock()
+				if t.autoDeleteTimer != timer || len(t.clients) > 0 {
+					t.lock.Unlock()
+					return
+				}
+				t.autoDeleteTimer = nil
+				t.autoDelete = AutoDeleteNever
+				t.lock.Unlock()
+
+				remove()
+			})
+			t.autoDeleteTimer = timer
+		}
+	}
+	t.lock.Unlock()
+}
+
+// Set the auto delete policy of a public or group topic
+// 0. Get the topic by name
+// 1. Find out how to remove the topic
+// 2. Set the policy on the topic
+func (s *SSEPubSubService) SetTopicAutoDelete(topicName string, when AutoDeletePolicy) error {
+	if when.kind == autoDeleteAfter && when.after <= 0 {
+		return fmt.Errorf("auto delete duration must be positive")
+	}
+
+	// Get the topic by name
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	// Find out how to remove the topic
+	var remove func()
+	if t.GetTopicType() == TopicTypePublic {
+		remove = func() { s.RemovePublicTopic(t) }
+	} else {
+		g, ok := s.groupOfTopic(t)
+		if !ok {
+			return fmt.Errorf("group of topic %s does not exist", topicName)
+		}
+		remove = func() { g.RemoveTopic(t) }
+	}
+
+	// Set the policy on the topic
+	t.setAutoDelete(when, remove)
+	return nil
+}
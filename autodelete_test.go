@@ -0,0 +1,116 @@
+package pubsubsse
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +SetTopicAutoDelete(topicName string, when AutoDeletePolicy): error
+// Topic:
+// +IsEmpty(): bool
+
+// TestSetTopicAutoDelete_OnEmpty tests AutoDeleteOnEmpty on public and group topics
+func TestSetTopicAutoDelete_OnEmpty(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.SetTopicAutoDelete("test", AutoDeleteOnEmpty); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	removed := 0
+	ssePubSub.OnRemovePublicTopic(func(*Topic) { removed++ })
+
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := ssePubSub.SetTopicAutoDelete("test", AutoDeleteOnEmpty); err != nil {
+		t.Error(err)
+	}
+
+	// A new topic without subscribers is kept
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok || !topic.IsEmpty() {
+		t.Fatal("Expected empty topic to be kept")
+	}
+
+	client1 := ssePubSub.NewClient()
+	client2 := ssePubSub.NewClient()
+	client1.Sub(topic)
+	client2.Sub(topic)
+	if topic.IsEmpty() {
+		t.Error("Expected topic with subscribers")
+	}
+
+	client1.Unsub(topic)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+		t.Error("Expected topic to be kept while it has subscribers")
+	}
+	client2.Unsub(topic)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); ok {
+		t.Error("Expected topic to be deleted when the last subscriber left")
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed topic, got %d", removed)
+	}
+
+	// Removing the topic does not remove it twice
+	topic = ssePubSub.NewPublicTopic("test")
+	ssePubSub.SetTopicAutoDelete("test", AutoDeleteOnEmpty)
+	client1.Sub(topic)
+	ssePubSub.RemovePublicTopic(topic)
+	if removed != 2 {
+		t.Errorf("Expected 2 removed topics, got %d", removed)
+	}
+
+	// Group topics are removed from their group
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client1)
+	groupTopic := group.NewTopic("grouptopic")
+	if err := ssePubSub.SetTopicAutoDelete("grouptopic", AutoDeleteOnEmpty); err != nil {
+		t.Error(err)
+	}
+	client1.Sub(groupTopic)
+	client1.Unsub(groupTopic)
+	if _, ok := group.GetTopicByName("grouptopic"); ok {
+		t.Error("Expected group topic to be deleted when the last subscriber left")
+	}
+}
+
+// TestSetTopicAutoDelete_After tests AutoDeleteAfter
+func TestSetTopicAutoDelete_After(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.SetTopicAutoDelete("test", AutoDeleteAfter(0)); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test")
+	client := ssePubSub.NewClient()
+	if err := ssePubSub.SetTopicAutoDelete("test", AutoDeleteAfter(40*time.Millisecond)); err != nil {
+		t.Error(err)
+	}
+
+	// A subscriber within the duration keeps the topic
+	time.Sleep(20 * time.Millisecond)
+	client.Sub(topic)
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+		t.Fatal("Expected topic with subscriber to be kept")
+	}
+
+	// The topic is deleted after it was empty for the duration
+	client.Unsub(topic)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+		t.Error("Expected topic to be kept before the duration")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); ok {
+		t.Error("Expected topic to be deleted after the duration")
+	}
+
+	// AutoDeleteNever stops a pending auto delete
+	ssePubSub.NewPublicTopic("test")
+	ssePubSub.SetTopicAutoDelete("test", AutoDeleteAfter(20*time.Millisecond))
+	ssePubSub.SetTopicAutoDelete("test", AutoDeleteNever)
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+		t.Error("Expected topic to be kept with AutoDeleteNever")
+	}
+}
@@ -0,0 +1,153 @@
+package pubsubsse
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Topic.Pub while the circuit breaker of the topic is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreakerOptions configures a circuit breaker on the publish path of a topic.
+type CircuitBreakerOptions struct {
+	// Threshold is the number of publish errors within Window that are tolerated. One more opens the circuit.
+	// A publish fails if a send to a subscriber fails, e.g. because its stream is full until the write timeout.
+	// Subscribers without an event stream do not count as failures.
+	Threshold int
+	// Window is the time span in which publish errors are counted.
+	Window time.Duration
+	// OpenDuration is how long the circuit stays open before a probe publish is allowed.
+	OpenDuration time.Duration
+}
+
+// circuitBreaker guards the publish path of a topic.
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	lock sync.Mutex
+
+	state    circuitState
+	failures []time.Time
+	openedAt time.Time
+	probing  bool
+}
+
+// Create a new circuit breaker
+func newCircuitBreaker(opts CircuitBreakerOptions) *circuitBreaker {
+	return &circuitBreaker{
+		opts:  opts,
+		state: circuitClosed,
+	}
+}
+
+// allow reports if a publish may pass the circuit breaker.
+// 1. Closed: always allow
+// 2. Open: deny until OpenDuration is over, then switch to half-open
+// 3. Half-open: allow a single probe publish
+func (cb *circuitBreaker) allow() bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.state == circuitOpen && time.Since(cb.openedAt) >= cb.opts.OpenDuration {
+		cb.state = circuitHalfOpen
+		cb.probing = false
+	}
+
+	switch cb.state {
+	case circuitOpen:
+		return false
+	case circuitHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	}
+	return true
+}
+
+// success records a successful publish. A successful probe closes the circuit.
+func (cb *circuitBreaker) success() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.state == circuitHalfOpen {
+		cb.state = circuitClosed
+		cb.probing = false
+		cb.failures = nil
+	}
+}
+
+// release gives up a publish without a result, e.g. because it was cancelled.
+// A pending probe can be retried by the next publish.
+func (cb *circuitBreaker) release() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	cb.probing = false
+}
+
+// failure records a failed publish and opens the circuit if the threshold is exceeded.
+func (cb *circuitBreaker) failure() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	now := time.Now()
+
+	// A failed probe opens the circuit again
+	if cb.state == circuitHalfOpen {
+		cb.state = circuitOpen
+		cb.openedAt = now
+		cb.probing = false
+		return
+	}
+
+	// Drop failures outside of the window
+	failures := cb.failures[:0]
+	for _, f := range cb.failures {
+		if now.Sub(f) < cb.opts.Window {
+			failures = append(failures, f)
+		}
+	}
+	cb.failures = append(failures, now)
+
+	if len(cb.failures) > cb.opts.Threshold {
+		cb.state = circuitOpen
+		cb.openedAt = now
+		cb.failures = nil
+	}
+}
+
+// NewCircuitBreaker attaches a circuit breaker to the publish path of a topic.
+// 0. Validate the options
+// 1. Get the topic by name
+// 2. Attach the circuit breaker to the topic
+func (s *SSEPubSubService) NewCircuitBreaker(topicName string, opts CircuitBreakerOptions) error {
+	// Validate the options
+	if opts.Threshold <= 0 || opts.Window <= 0 || opts.OpenDuration <= 0 {
+		return fmt.Errorf("invalid circuit breaker options for topic %s", topicName)
+	}
+
+	// Get the topic by name
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	// Attach the circuit breaker to the topic
+	t.lock.Lock()
+	t.breaker = newCircuitBreaker(opts)
+	t.lock.Unlock()
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package pubsubsse
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +NewCircuitBreaker(topicName string, opts CircuitBreakerOptions): error
+
+// Start a client with a stream of size 1 whose event stream blocks until release is called
+// The first message fills the stream, e.g. the subscribed message of Sub. Every further send fails after the timeout.
+func startBlockedClient(t *testing.T, ssePubSub *SSEPubSubService) (client *Client, release func()) {
+	client, err := ssePubSub.NewClientWithOptions("", ClientOptions{MaxBuffer: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(string) {
+			select {
+			case <-blocked:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+
+	return client, func() {
+		close(blocked)
+		cancel()
+		<-done
+	}
+}
+
+// TestNewCircuitBreaker tests SSEPubSubService.NewCircuitBreaker()
+func TestNewCircuitBreaker(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	opts := CircuitBreakerOptions{Threshold: 2, Window: time.Second, OpenDuration: 50 * time.Millisecond}
+	if err := ssePubSub.NewCircuitBreaker("test", opts); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := ssePubSub.NewCircuitBreaker("test", CircuitBreakerOptions{}); err == nil {
+		t.Error("Expected error for invalid options")
+	}
+	if err := ssePubSub.NewCircuitBreaker("test", opts); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.SetTopicPublishTimeout("test", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client that is not receiving is no failure
+	idle := ssePubSub.NewClient()
+	if err := idle.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := topic.Pub("test"); err != nil {
+			t.Errorf("Expected publish %d to a client that is not receiving to pass: %s", i, err)
+		}
+	}
+
+	// A client with a full stream makes every publish fail
+	client, release := startBlockedClient(t, ssePubSub)
+	defer release()
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+
+	// Exactly Threshold failures keep the circuit closed, one more opens it
+	for i := 0; i < opts.Threshold+1; i++ {
+		if err := topic.Pub("test"); err != nil {
+			t.Errorf("Expected publish %d to pass the circuit breaker: %s", i, err)
+		}
+	}
+	if err := topic.Pub("test"); err != ErrCircuitOpen {
+		t.Error("Expected ErrCircuitOpen")
+	}
+
+	// After OpenDuration a single probe is allowed. It fails and opens the circuit again.
+	time.Sleep(60 * time.Millisecond)
+	if err := topic.Pub("test"); err != nil {
+		t.Error("Expected probe publish to pass the circuit breaker")
+	}
+	if err := topic.Pub("test"); err != ErrCircuitOpen {
+		t.Error("Expected ErrCircuitOpen after failed probe")
+	}
+
+	// A successful probe closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	if err := client.Unsub(topic); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := topic.Pub("test"); err != nil {
+			t.Errorf("Expected publish %d to pass the closed circuit breaker: %s", i, err)
+		}
+	}
+}
+
+// TestCircuitBreaker_Threshold tests that the circuit opens only when the failures exceed the threshold
+func TestCircuitBreaker_Threshold(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerOptions{Threshold: 3, Window: time.Minute, OpenDuration: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		cb.failure()
+	}
+	if !cb.allow() {
+		t.Error("Expected the circuit to stay closed at exactly Threshold failures")
+	}
+	cb.failure()
+	if cb.allow() {
+		t.Error("Expected the circuit to open after Threshold+1 failures")
+	}
+}
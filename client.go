@@ -3,11 +3,13 @@ package pubsubsse
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
-	"github.com/apex/log"
 	"github.com/google/uuid"
 )
 
@@ -18,24 +20,100 @@ const (
 	Receving
 )
 
+// Priority of a message. High priority messages are delivered before all other messages.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// Size of the stream buffers of a client
+const streamSize = 100
+
+// ErrClientNotReceiving is returned when a message is sent to a client without an event stream.
+var ErrClientNotReceiving = errors.New("client is not receiving")
+
 type OnEventFunc func(string)
 
+// connection is an open event stream of a client, e.g. a browser tab.
+type connection struct {
+	id string
+
+	stream     chan string
+	highStream chan string // high priority messages
+
+	stopchan chan struct{}
+}
+
+// Create a new connection with streams of the given size
+func newConnection(size int) *connection {
+	return &connection{
+		id: uuid.New().String(),
+
+		stream:     make(chan string, size),
+		highStream: make(chan string, size),
+
+		stopchan: make(chan struct{}),
+	}
+}
+
 // Client represents a subscriber with a channel to send messages.
 type Client struct {
 	id     string
 	status status
 
-	stream chan string
+	// Open event streams of the client, keyed by connection ID
+	connections map[string]*connection
 
-	stopchan chan struct{}
+	// Closed and replaced when an event stream is started. Used by WaitForStream.
+	streamStarted chan struct{}
 
-	lock sync.Mutex
+	// Frames of PubToClient for a client without an event stream, sent when the next event stream starts
+	direct []string
+
+	lock sync.Mutex // see the lock ordering of SSEPubSubService
 
 	sSEPubSubService *SSEPubSubService
 
 	privateTopics map[string]*Topic
 
 	groups map[string]*Group
+
+	// Names of the groups whose topics are subscribed automatically, see SubGroup
+	groupSubs map[string]bool
+
+	logger Logger
+
+	// Application data. Opaque to the pub-sub layer.
+	customData interface{}
+
+	// Validated bearer token of the client
+	authToken string
+
+	// Set by NewClientWithOptions, see ClientOptions
+	tags       map[string]string
+	maxBuffer  int
+	dropPolicy DropPolicy
+
+	// The client does not receive ping updates, see WithPingInterval
+	pingDisabled bool
+
+	// Events:
+	onConnected          hooks[*Client]
+	onDisconnected       hooks[*Client]
+	onNewTopic           hooks[*Topic]
+	onRemoveTopic        hooks[*Topic]
+	onNewPublicTopic     hooks[*Topic]
+	onRemovePublicTopic  hooks[*Topic]
+	onNewPrivateTopic    hooks[*Topic]
+	onNewGroupTopic      hooks[*Topic]
+	onRemovePrivateTopic hooks[*Topic]
+	onNewGroup           hooks[*Group]
+	onRemoveGroup        hooks[*Group]
+	onNewSubToTopic      hooks[*Topic]
+	onUnsubToTopic       hooks[*Topic]
 }
 
 // Create a new client
@@ -44,17 +122,22 @@ func newClient(sSEPubSubService *SSEPubSubService) *Client {
 		id:     uuid.New().String(),
 		status: Waiting,
 
-		stream: make(chan string, 100),
+		connections:   make(map[string]*connection),
+		streamStarted: make(chan struct{}),
 
 		lock: sync.Mutex{},
 
-		stopchan: nil,
-
 		sSEPubSubService: sSEPubSubService,
 
 		privateTopics: make(map[string]*Topic),
 
-		groups: make(map[string]*Group),
+		groups:    make(map[string]*Group),
+		groupSubs: make(map[string]bool),
+
+		logger: sSEPubSubService.logger,
+
+		tags:      make(map[string]string),
+		maxBuffer: streamSize,
 	}
 }
 
@@ -71,9 +154,92 @@ func (c *Client) stop() {
 	// Stop the client
 	c.status = Waiting
 
-	// Close the stream
-	if c.stream != nil {
-		close(c.stream)
+	// Stop all event streams and all pending sends.
+	// The streams are not closed, so a concurrent send can not panic.
+	for id, conn := range c.connections {
+		close(conn.stopchan)
+		delete(c.connections, id)
+	}
+}
+
+// Disconnect the client gracefully
+// Only the event stream of this client is closed. With the TabManager every browser tab is a client of its own,
+// so the other tabs of the browser keep their streams.
+// A tab without an event stream is only removed from its primary tab, the stream of the primary tab stays open.
+// The client stays in the sSEPubSubService and can open a new event stream, e.g. when the browser reconnects.
+// 0. Send a "server_disconnect" message to the client. It is queued after all other messages.
+// 1. Wait until the queued messages are delivered, at most the shutdown timeout of the sSEPubSubService
+// 2. Close the event streams of the client and set status to Waiting
+func (c *Client) Disconnect() error {
+	fulldata := &eventData{
+		Sys: []eventDataSys{
+			{
+				Type: "server_disconnect",
+			},
+		},
+	}
+
+	// A tab is disconnected by stopping the routing over its primary tab, even if the primary tab is not receiving
+	if c.GetStatus() != Receving && c.sSEPubSubService.tabs.isTab(c) {
+		if err := c.send(fulldata); err != nil {
+			c.logger.Errorf("[C:%s]: Error sending server disconnect to tab: %s", c.GetID(), err)
+		}
+		c.sSEPubSubService.tabs.RemoveTab(c)
+		return nil
+	}
+
+	// Send a "server_disconnect" message to the client
+	if err := c.send(fulldata); err != nil {
+		return err
+	}
+
+	// Wait until the queued messages are delivered, then close the event streams
+	waitUntil(time.Now().Add(c.sSEPubSubService.shutdownTimeout), func() bool { return c.queued() == 0 })
+	c.stop()
+	return nil
+}
+
+// Disconnect the client and every tab of the TabManager that is routed over it
+// The tabs are disconnected first, so their "server_disconnect" messages are sent before the stream of the client is closed.
+func (c *Client) DisconnectAll() error {
+	for _, id := range c.sSEPubSubService.tabs.tabsOf(c) {
+		tab, ok := c.sSEPubSubService.GetClientByID(id)
+		if !ok {
+			continue
+		}
+		if err := tab.Disconnect(); err != nil {
+			c.logger.Errorf("[C:%s]: Error disconnecting tab %s: %s", c.GetID(), id, err)
+		}
+	}
+	return c.Disconnect()
+}
+
+// Get the number of messages waiting in the streams of all connections
+func (c *Client) queued() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := 0
+	for _, conn := range c.connections {
+		n += len(conn.stream) + len(conn.highStream)
+	}
+	return n
+}
+
+// Remove a connection when its event stream ends
+func (c *Client) removeConnection(conn *connection) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Already removed by stop
+	if _, ok := c.connections[conn.id]; !ok {
+		return
+	}
+
+	close(conn.stopchan)
+	delete(c.connections, conn.id)
+	if len(c.connections) == 0 {
+		c.status = Waiting
 	}
 }
 
@@ -93,11 +259,70 @@ func (c *Client) GetStatus() status {
 	return c.status
 }
 
+// Wait until the client receives over an event stream
+// Returns ctx.Err() if ctx is done before an event stream is started.
+func (c *Client) WaitForStream(ctx context.Context) error {
+	for {
+		c.lock.Lock()
+		receiving := c.status == Receving
+		started := c.streamStarted
+		c.lock.Unlock()
+		if receiving {
+			return nil
+		}
+
+		// Wait for the next event stream or the end of ctx
+		select {
+		case <-started:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Get custom data
+func (c *Client) GetCustomData() interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.customData
+}
+
+// Set custom data
+// The data is owned by the application, e.g. parsed JWT claims or session state.
+func (c *Client) SetCustomData(data interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.customData = data
+}
+
+// Get auth token
+func (c *Client) GetAuthToken() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.authToken
+}
+
+// Set auth token
+func (c *Client) SetAuthToken(token string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.authToken = token
+}
+
 // Get public topics
 func (c *Client) GetPublicTopics() map[string]*Topic {
 	return c.sSEPubSubService.GetPublicTopics()
 }
 
+// Get public topics sorted by name
+func (c *Client) ListPublicTopics() []*Topic {
+	return c.sSEPubSubService.ListPublicTopics()
+}
+
 // Get public topic by name
 func (c *Client) GetPublicTopicByName(name string) (*Topic, bool) {
 	return c.sSEPubSubService.GetPublicTopicByName(name)
@@ -117,6 +342,12 @@ func (c *Client) GetPrivateTopics() map[string]*Topic {
 	return newmap
 }
 
+// Get private topics sorted by name
+// Unlike GetPrivateTopics, the order is stable, e.g. for listings.
+func (c *Client) ListPrivateTopics() []*Topic {
+	return sortTopicsByName(c.GetPrivateTopics())
+}
+
 // Get private topic by name
 func (c *Client) GetPrivateTopicByName(name string) (*Topic, bool) {
 	c.lock.Lock()
@@ -128,18 +359,23 @@ func (c *Client) GetPrivateTopicByName(name string) (*Topic, bool) {
 
 // Add group
 func (c *Client) addGroup(g *Group) {
+	name := g.GetName()
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	c.groups[g.GetName()] = g
+	c.groups[name] = g
 }
 
 // Remove group
 func (c *Client) removeGroup(g *Group) {
+	name := g.GetName()
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	delete(c.groups, g.GetName())
+	delete(c.groups, name)
+	delete(c.groupSubs, name)
 }
 
 // Get groups
@@ -156,6 +392,18 @@ func (c *Client) GetGroups() map[string]*Group {
 	return newmap
 }
 
+// Get groups sorted by name
+// Unlike GetGroups, the order is stable, e.g. for listings.
+func (c *Client) ListGroups() []*Group {
+	groups := c.GetGroups()
+	sorted := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	return sorted
+}
+
 // Get group by name
 func (c *Client) GetGroupByName(name string) (*Group, bool) {
 	c.lock.Lock()
@@ -166,25 +414,29 @@ func (c *Client) GetGroupByName(name string) (*Group, bool) {
 }
 
 // Get all topics
+// The lock of the client is not held while the topics of the sSEPubSubService and the groups are read.
 func (c *Client) GetAllTopics() map[string]*Topic {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
 	newmap := make(map[string]*Topic)
 	for k, v := range c.GetPublicTopics() {
 		newmap[k] = v
 	}
-	for _, v := range c.groups {
+	for _, v := range c.GetGroups() {
 		for k, v := range v.GetTopics() {
 			newmap[k] = v
 		}
 	}
-	for k, v := range c.privateTopics {
+	for k, v := range c.GetPrivateTopics() {
 		newmap[k] = v
 	}
 	return newmap
 }
 
+// Get all topics sorted by name
+// Unlike GetAllTopics, the order is stable, e.g. for listings.
+func (c *Client) ListAllTopics() []*Topic {
+	return sortTopicsByName(c.GetAllTopics())
+}
+
 // Get topic by name
 func (c *Client) GetTopicByName(name string) (*Topic, bool) {
 	topics := c.GetAllTopics()
@@ -204,71 +456,129 @@ func (c *Client) GetSubscribedTopics() map[string]*Topic {
 	return topics
 }
 
+// Get subscribed topics sorted by name
+// Unlike GetSubscribedTopics, the order is stable, e.g. for listings.
+func (c *Client) ListSubscribedTopics() []*Topic {
+	return sortTopicsByName(c.GetSubscribedTopics())
+}
+
 // New private topic
+// The options are ignored if the topic already exists.
 // 0. Check if topic already exists, return it if it does
 // 1. Create a new private topic
 // 2. Add the topic to the client
 // 3. Inform the client about the new topic
-func (c *Client) NewPrivateTopic(name string) *Topic {
+func (c *Client) NewPrivateTopic(name string, opts ...TopicOption) *Topic {
 	// if topic exists, return it
 	if t, ok := c.GetPrivateTopicByName(name); ok {
 		return t
 	}
 
-	t := newTopic(name, TPrivate)
+	// Create the topic. If it was created concurrently, return the existing one.
+	t, added := c.addPrivateTopic(newTopic(name, TPrivate, c.logger, opts...))
+
+	// Emit event
+	if added {
+		c.onNewPrivateTopic.emit(t)
+	}
+
+	return t
+}
+
+// Add a private topic to the client and inform the client about the new topic
+// If a topic with the same name already exists, it is returned with false.
+func (c *Client) addPrivateTopic(t *Topic) (*Topic, bool) {
+	name := t.GetName()
 
 	c.lock.Lock()
-	c.privateTopics[t.GetName()] = t
+	if existing, ok := c.privateTopics[name]; ok {
+		c.lock.Unlock()
+		return existing, false
+	}
+	t.pubErrors = &c.sSEPubSubService.onPubError
+	c.privateTopics[name] = t
 	c.lock.Unlock()
 
 	// Inform the client about the new topic
 	if err := c.sendTopicList(); err != nil {
-		log.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+		c.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
 	}
 
-	return t
+	// Emit event
+	c.onNewTopic.emit(t)
+
+	return t, true
 }
 
 // Remove private topic
 // 0. Check if topic exists, return error if it does not
-// 1. Unsubscribe from the topic
-// 2. Remove the topic from the client
-// 3. Inform the client about the removed topic by sending the new topic list
+// 1. Emit OnRemovePrivateTopic
+// 2. Unsubscribe from the topic
+// 3. Remove the topic from the client and cancel its scheduled publishes
+// 4. Inform the client about the removed topic by sending the new topic list
 func (c *Client) RemovePrivateTopic(t *Topic) {
 	// if topic does not exist, return
 	if _, ok := c.GetPrivateTopicByName(t.GetName()); !ok {
-		log.Errorf("[C:%s]: topic %s does not exist", c.GetID(), t.GetName())
+		c.logger.Errorf("[C:%s]: topic %s does not exist", c.GetID(), t.GetName())
 		return
 	}
 
+	// Emit event
+	c.onRemovePrivateTopic.emit(t)
+
+	// The topic is removed anyway, so it must not remove itself when the last client leaves
+	t.stopAutoDelete()
+
 	// Remove this topic from all clients
 	for _, c := range t.GetClients() {
 		c.Unsub(t) // Try to unsubscribe from the topic
 	}
 
 	// Remove topic from client
+	name := t.GetName()
 	c.lock.Lock()
-	delete(c.privateTopics, t.GetName())
+	delete(c.privateTopics, name)
 	c.lock.Unlock()
 
+	// Cancel all pending scheduled publishes
+	t.CancelAllScheduled()
+
 	// Inform the client about the removed topic by sending the new topic list
 	if err := c.sendTopicList(); err != nil {
-		log.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+		c.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
 	}
+
+	// Emit event
+	c.onRemoveTopic.emit(t)
 }
 
 // Subscribe to a topic
-// 1. If client can subscribe to this topic, add client to topic and return nil
+// 0. Check if topic is write only, return ErrWriteOnlyTopic if it is
+// 1. If client can subscribe to this topic, add client to topic and return nil. Return ErrTopicFull if the topic is full.
 // 2. Inform the client about the new topic by sending this topic as subscribed
 func (c *Client) Sub(topic *Topic) error {
+	// Clients can not subscribe to write only topics
+	if topic.IsWriteOnly() {
+		return ErrWriteOnlyTopic
+	}
+
 	// if topic exists, add client to topic and return nil
 	if t, ok := c.GetTopicByName(topic.GetName()); ok {
 		if topic == t {
-			t.addClient(c)
+			added, err := t.addClient(c)
+			if err != nil {
+				return err
+			}
 
 			// Inform the client about the new topic by sending this topic as subscribed
 			if err := c.sendSubscribedTopic(t); err != nil {
-				log.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+				c.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+			}
+
+			// Emit events
+			if added {
+				c.onNewSubToTopic.emit(t)
+				t.onNewSubOfClient.emit(c)
 			}
 
 			return nil
@@ -288,11 +598,17 @@ func (c *Client) Unsub(topic *Topic) error {
 			if !t.IsSubscribed(c) {
 				return fmt.Errorf("[C:%s]: client is not subscribed to topic %s", c.GetID(), topic.GetName())
 			}
-			t.removeClient(c)
+			removed := t.removeClient(c)
 
 			// Inform the client about the new topic by sending this topic as unsubscribed
 			if err := c.sendUnsubscribedTopic(t); err != nil {
-				log.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+				c.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.GetID(), err)
+			}
+
+			// Emit events
+			if removed {
+				c.onUnsubToTopic.emit(t)
+				t.onUnsubOfClient.emit(c)
 			}
 
 			return nil
@@ -302,42 +618,362 @@ func (c *Client) Unsub(topic *Topic) error {
 	return fmt.Errorf("[C:%s]: topic %s does not exist or client can not unsubscribe from it", c.GetID(), topic.GetName())
 }
 
-// send a message to the client
-// 1. Marshal the data
-// 2. Put the data into the stream to send it to the client
-func (c *Client) send(msg interface{}) error {
+// Subscribe to all current and future topics of a group
+// The client has to be in the group. Topics added to the group later are subscribed automatically
+// until UnsubGroup is called or the client leaves the group. Write only topics are skipped.
+// 0. Get the group of the client
+// 1. Store the group subscription, so new topics are subscribed from now on
+// 2. Subscribe to all topics of the group
+func (c *Client) SubGroup(groupName string) error {
+	// Get the group of the client
+	g, ok := c.GetGroupByName(groupName)
+	if !ok {
+		return fmt.Errorf("[C:%s]: %w: %s", c.GetID(), ErrGroupNotFound, groupName)
+	}
+
+	// Store the group subscription
+	c.lock.Lock()
+	c.groupSubs[groupName] = true
+	c.lock.Unlock()
+
+	// Subscribe to all topics of the group
+	for _, t := range g.GetTopics() {
+		if t.IsWriteOnly() {
+			continue
+		}
+		if err := c.Sub(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unsubscribe from all topics of a group and stop subscribing to new ones
+// 0. Get the group of the client
+// 1. Remove the group subscription
+// 2. Unsubscribe from all subscribed topics of the group
+func (c *Client) UnsubGroup(groupName string) error {
+	// Get the group of the client
+	g, ok := c.GetGroupByName(groupName)
+	if !ok {
+		return fmt.Errorf("[C:%s]: %w: %s", c.GetID(), ErrGroupNotFound, groupName)
+	}
+
+	// Remove the group subscription
+	c.lock.Lock()
+	delete(c.groupSubs, groupName)
+	c.lock.Unlock()
+
+	// Unsubscribe from all subscribed topics of the group
+	for _, t := range g.GetTopics() {
+		if !t.IsSubscribed(c) {
+			continue
+		}
+		if err := c.Unsub(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check if the client subscribes to new topics of the group automatically
+func (c *Client) hasGroupSub(groupName string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.groupSubs[groupName]
+}
+
+// Event: When an event stream of the client is opened
+// Called after the status is set to Receving and the init message was sent, so messages sent by f are delivered to the new stream.
+func (c *Client) OnConnected(f funcClient) string {
+	return c.onConnected.add(f)
+}
+
+// Remove Event: When an event stream of the client is opened
+func (c *Client) RemoveOnConnected(id string) {
+	c.onConnected.remove(id)
+}
+
+// Event: When an event stream of the client is closed
+// Called after the stream of Start, and so of the Event handler, ended because the browser disconnected,
+// the context is done or the client was stopped. It is called once for every stream of the client.
+func (c *Client) OnDisconnected(f funcClient) string {
+	return c.onDisconnected.add(f)
+}
+
+// Remove Event: When an event stream of the client is closed
+func (c *Client) RemoveOnDisconnected(id string) {
+	c.onDisconnected.remove(id)
+}
+
+// Event: When a new topic becomes available to the client
+func (c *Client) OnNewTopic(f funcTopic) string {
+	return c.onNewTopic.add(f)
+}
+
+// Remove Event: When a new topic becomes available to the client
+func (c *Client) RemoveOnNewTopic(id string) {
+	c.onNewTopic.remove(id)
+}
+
+// Event: When a topic is no longer available to the client
+func (c *Client) OnRemoveTopic(f funcTopic) string {
+	return c.onRemoveTopic.add(f)
+}
+
+// Remove Event: When a topic is no longer available to the client
+func (c *Client) RemoveOnRemoveTopic(id string) {
+	c.onRemoveTopic.remove(id)
+}
+
+// Event: When a new public topic becomes available to the client
+// Called after the client was informed about the new topic list.
+func (c *Client) OnNewPublicTopic(f funcTopic) string {
+	return c.onNewPublicTopic.add(f)
+}
+
+// Remove Event: When a new public topic becomes available to the client
+func (c *Client) RemoveOnNewPublicTopic(id string) {
+	c.onNewPublicTopic.remove(id)
+}
+
+// Event: When a public topic is no longer available to the client
+// Called after the client was informed about the new topic list.
+func (c *Client) OnRemovePublicTopic(f funcTopic) string {
+	return c.onRemovePublicTopic.add(f)
+}
+
+// Remove Event: When a public topic is no longer available to the client
+func (c *Client) RemoveOnRemovePublicTopic(id string) {
+	c.onRemovePublicTopic.remove(id)
+}
+
+// Event: When a private topic of the client is created
+// Called at the end of NewPrivateTopic, but not if the topic already existed.
+func (c *Client) OnNewPrivateTopic(f funcTopic) string {
+	return c.onNewPrivateTopic.add(f)
+}
+
+// Remove Event: When a private topic of the client is created
+func (c *Client) RemoveOnNewPrivateTopic(id string) {
+	c.onNewPrivateTopic.remove(id)
+}
+
+// Event: When a private topic of the client is removed
+// Called at the start of RemovePrivateTopic, so the topic still has its subscribers.
+func (c *Client) OnRemovePrivateTopic(f funcTopic) string {
+	return c.onRemovePrivateTopic.add(f)
+}
+
+// Remove Event: When a private topic of the client is removed
+func (c *Client) RemoveOnRemovePrivateTopic(id string) {
+	c.onRemovePrivateTopic.remove(id)
+}
+
+// Event: When a group topic becomes available to the client
+// Called when the client is added to a group, once for every topic of the group,
+// and when a topic is created in a group of the client.
+func (c *Client) OnNewGroupTopic(f funcTopic) string {
+	return c.onNewGroupTopic.add(f)
+}
+
+// Remove Event: When a group topic becomes available to the client
+func (c *Client) RemoveOnNewGroupTopic(id string) {
+	c.onNewGroupTopic.remove(id)
+}
+
+// Event: When the client is added to a group
+func (c *Client) OnNewGroup(f funcGroup) string {
+	return c.onNewGroup.add(f)
+}
+
+// Remove Event: When the client is added to a group
+func (c *Client) RemoveOnNewGroup(id string) {
+	c.onNewGroup.remove(id)
+}
+
+// Event: When the client is removed from a group
+// Called before the client leaves the group, so it is still subscribed to the group topics.
+func (c *Client) OnRemoveGroup(f funcGroup) string {
+	return c.onRemoveGroup.add(f)
+}
+
+// Remove Event: When the client is removed from a group
+func (c *Client) RemoveOnRemoveGroup(id string) {
+	c.onRemoveGroup.remove(id)
+}
+
+// Event: When client subscribes to a topic
+// Fires in Sub after the client was added to the topic, but not if it was already subscribed.
+func (c *Client) OnNewSubToTopic(f funcTopic) string {
+	return c.onNewSubToTopic.add(f)
+}
+
+// Remove Event: When client subscribes to a topic
+func (c *Client) RemoveOnNewSubToTopic(id string) {
+	c.onNewSubToTopic.remove(id)
+}
+
+// Event: When client unsubscribes from a topic
+// Fires in Unsub after the client was removed from the topic, also if the topic or group is removed.
+func (c *Client) OnUnsubToTopic(f funcTopic) string {
+	return c.onUnsubToTopic.add(f)
+}
+
+// Remove Event: When client unsubscribes from a topic
+func (c *Client) RemoveOnUnsubToTopic(id string) {
+	c.onUnsubToTopic.remove(id)
+}
+
+// send a message to the client with normal priority
+func (c *Client) send(d *eventData) error {
+	return c.sendCtx(context.Background(), d, PriorityNormal, 0)
+}
+
+// sendCtx sends a message to all event streams of the client
+// See sendEncoded.
+func (c *Client) sendCtx(ctx context.Context, d *eventData, p Priority, timeout time.Duration) error {
 	// Marshal the data
-	jsonData, err := json.Marshal(msg)
+	e, err := encodeEvent(d)
 	if err != nil {
 		return err
 	}
+	return c.sendEncoded(ctx, e, p, timeout)
+}
 
-	// Send the data
-	if c.GetStatus() == Receving {
-		data := "data: " + string(jsonData) + "\n\n"
+// sendEncoded sends an encoded message to all event streams of the client
+// The same message can be sent to many clients, e.g. by a publish, so it is only encoded once.
+// The send is cancelled if ctx is done or the client stops receiving.
+// If a stream is full, it is retried until timeout. A timeout of 0 uses the write timeout of the sSEPubSubService.
+// A browser tab without an event stream sends the data over the stream of its primary tab, see TabManager.
+// 1. Get all connections
+// 2. Put the frame into the stream of the priority of every connection
+func (c *Client) sendEncoded(ctx context.Context, e *encodedEvent, p Priority, timeout time.Duration) error {
+	// Get all connections
+	c.lock.Lock()
+	conns := make([]*connection, 0, len(c.connections))
+	for _, conn := range c.connections {
+		conns = append(conns, conn)
+	}
+	c.lock.Unlock()
+
+	if len(conns) == 0 {
+		// A browser tab without an event stream receives its messages over the primary tab
+		if primary, ok := c.sSEPubSubService.tabs.route(c); ok && primary != c {
+			return primary.sendCtx(ctx, tabFrame(c.GetID(), e.json), p, timeout)
+		}
+		return fmt.Errorf("[C:%s]: %w", c.GetID(), ErrClientNotReceiving)
+	}
+
+	// Fall back to the write timeout of the sSEPubSubService
+	if timeout <= 0 {
+		timeout = c.sSEPubSubService.writeTimeout
+	}
+
+	// Send the frame to every connection
+	var sendErr error
+	for _, conn := range conns {
+		if err := c.sendToConnection(ctx, conn, e.frame, p, timeout); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			sendErr = err
+		}
+	}
+	return sendErr
+}
+
+// sendToConnection puts the data into the stream of the priority of a connection
+func (c *Client) sendToConnection(ctx context.Context, conn *connection, data string, p Priority, timeout time.Duration) error {
+	// Select the stream of the priority
+	stream := conn.stream
+	if p == PriorityHigh {
+		stream = conn.highStream
+	}
+
+	//Try every 10ms to send data to the stream until the timeout is reached
+	deadline := time.Now().Add(timeout)
+	for i := 0; ; i++ {
+		select {
+		case stream <- data:
+			// successfully sent
+			c.logger.Infof("[C:%s]: push data to stream", c.GetID())
+			c.sSEPubSubService.messagesSent.Add(1)
+			return nil
+		default:
+			c.logger.Infof("[C:%s]: stream is full: try: %d", c.GetID(), i)
+		}
 
-		//Try 10 times with 100ms to send data to the stream
-		for i := 0; i < 10; i++ {
+		// Make space for the new message by dropping the oldest one
+		if c.dropPolicy == DropOldest {
 			select {
-			case c.stream <- data:
-				// successfully sent
-				log.Infof("[C:%s]: push data to stream", c.GetID())
-				return nil
+			case <-stream:
+				c.sSEPubSubService.messagesDropped.Add(1)
 			default:
-				log.Infof("[C:%s]: stream is full: try: %d", c.GetID(), i)
-				time.Sleep(10 * time.Millisecond)		
 			}
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-conn.stopchan:
+			c.sSEPubSubService.messagesDropped.Add(1)
+			return fmt.Errorf("[C:%s]: client stopped receiving", c.GetID())
+		case <-time.After(10 * time.Millisecond):
 		}
-		// handle the case where the channel is full or the client is not receiving
-		return fmt.Errorf("[C:%s]: stream is full", c.GetID())
 	}
-	return fmt.Errorf("[C:%s]: client is not receiving", c.GetID())
+	// handle the case where the channel is full
+	c.sSEPubSubService.messagesDropped.Add(1)
+	return fmt.Errorf("[C:%s]: stream is full", c.GetID())
+}
+
+// sendDirect sends a message to all event streams of the client, whatever its status is
+// A client without an event stream queues the frame until its next event stream starts, at most the stream size of the client.
+// A browser tab without an event stream sends the data over the stream of its primary tab, see TabManager.
+func (c *Client) sendDirect(d *eventData) error {
+	e, err := encodeEvent(d)
+	if err != nil {
+		return err
+	}
+
+	// Queue the frame if the client has no event stream and is no routed tab
+	if primary, ok := c.sSEPubSubService.tabs.route(c); !ok || primary == c {
+		c.lock.Lock()
+		if len(c.connections) == 0 {
+			defer c.lock.Unlock()
+			if len(c.direct) >= c.maxBuffer {
+				c.sSEPubSubService.messagesDropped.Add(1)
+				return fmt.Errorf("[C:%s]: stream is full", c.id)
+			}
+			c.direct = append(c.direct, e.frame)
+			return nil
+		}
+		c.lock.Unlock()
+	}
+
+	return c.sendEncoded(context.Background(), e, PriorityNormal, 0)
+}
+
+// sortTopicsByName returns the topics sorted by name, so messages to the client do not depend on the map order
+func sortTopicsByName(topics map[string]*Topic) []*Topic {
+	sorted := make([]*Topic, 0, len(topics))
+	for _, t := range topics {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	return sorted
 }
 
 // sendTopicList sends a message to the client to inform it about the topics
 func (c *Client) sendTopicList() error {
 	// Get all topics
-	topics := c.GetAllTopics()
+	topics := c.ListAllTopics()
 
 	// Build the JSON data
 	fulldata := &eventData{
@@ -351,12 +987,7 @@ func (c *Client) sendTopicList() error {
 
 	// Append topics data
 	for _, topic := range topics {
-		t := eventDataSysList{
-			Name: topic.GetName(),
-			Type: topic.GetType(),
-		}
-
-		fulldata.Sys[0].List = append(fulldata.Sys[0].List, t)
+		fulldata.Sys[0].List = append(fulldata.Sys[0].List, topic.topicListEntry())
 	}
 
 	// Send the JSON data to the client
@@ -415,26 +1046,95 @@ func (c *Client) sendUnsubscribedTopic(topic *Topic) error {
 	return nil
 }
 
-// sendInitMSG generates the initial message to send to the client
-// It contains all topics and subscribed topics
-func (c *Client) sendInitMSG(onEvent OnEventFunc) error {
-	// Get all topics and subscribed topics
-	topics := c.GetAllTopics()
-	subtopics := c.GetSubscribedTopics()
+// Sys event types used by the pub-sub layer. They can not be sent with SendSysEvent.
+var reservedSysEvents = map[string]bool{
+	"topics":            true,
+	"subscribed":        true,
+	"unsubscribed":      true,
+	"groups":            true,
+	"server_closing":    true,
+	"server_disconnect": true,
+	"topic_renamed":     true,
+	"error":             true,
+}
+
+// Send a custom sys event to the client, e.g. "user_kicked" or "room_locked"
+// The payload is sent as data field of the sys event. Types used by the pub-sub layer are rejected.
+func (c *Client) SendSysEvent(eventType string, payload interface{}) error {
+	// Check the event type
+	if eventType == "" || reservedSysEvents[eventType] {
+		return fmt.Errorf("[C:%s]: invalid sys event type %q", c.GetID(), eventType)
+	}
 
 	// Build the JSON data
+	sys := eventDataSys{Type: eventType}
+	if payload != nil {
+		data, err := MarshalData(payload)
+		if err != nil {
+			return err
+		}
+		sys.Data = data
+	}
 	fulldata := &eventData{
-		Sys: make([]eventDataSys, 0, 2),
+		Sys: []eventDataSys{sys},
+	}
+
+	// Send the JSON data to the client
+	return c.send(fulldata)
+}
+
+// Well-known error codes of Client.SendError. They follow the HTTP status codes.
+const (
+	ErrorCodeBadRequest   = 400 // a request of the client was invalid
+	ErrorCodeUnauthorized = 401 // the authentication of the client expired or was revoked
+	ErrorCodeForbidden    = 403 // the client was kicked or lost a permission
+	ErrorCodeNotFound     = 404 // a topic or group of the client was removed
+	ErrorCodeConflict     = 409 // the client was replaced, e.g. by a newer connection of the same user
+	ErrorCodeInternal     = 500 // an error of the server
+)
+
+// Send an error to the client, e.g. before it is disconnected because it was kicked
+// The sys event has the type "error" and a single list entry with the message as name and the code as type.
+// Use the ErrorCode constants for well-known errors. The message is queued after all other messages, so
+// calling Disconnect afterwards delivers the error before the event stream is closed.
+func (c *Client) SendError(code int, message string) error {
+	// Build the JSON data
+	fulldata := &eventData{
+		Sys: []eventDataSys{
+			{
+				Type: "error",
+				List: []eventDataSysList{
+					{
+						Name: message,
+						Type: strconv.Itoa(code),
+					},
+				},
+			},
+		},
+	}
+
+	// Send the JSON data to the client
+	return c.send(fulldata)
+}
+
+// initData generates the initial message of the client
+// It contains all topics, subscribed topics and groups, each sorted by name
+func (c *Client) initData() *eventData {
+	// Get all topics, subscribed topics and groups, sorted by name
+	topics := c.ListAllTopics()
+	subtopics := c.ListSubscribedTopics()
+	groups := c.ListGroups()
+
+	// Build the JSON data
+	fulldata := &eventData{
+		Sys: make([]eventDataSys, 0, 3),
 	}
 
 	// Append topics data
 	if len(topics) > 0 {
 		topicData := eventDataSys{Type: "topics"}
 		for _, topic := range topics {
-			topicData.List = append(topicData.List, eventDataSysList{
-				Name: topic.GetName(),
-				Type: topic.GetType(),
-			})
+			topicData.List = append(topicData.List, topic.topicListEntry())
 		}
 		fulldata.Sys = append(fulldata.Sys, topicData)
 	}
@@ -448,64 +1148,101 @@ func (c *Client) sendInitMSG(onEvent OnEventFunc) error {
 		fulldata.Sys = append(fulldata.Sys, subTopicData)
 	}
 
+	// Append groups data
+	if len(groups) > 0 {
+		groupData := eventDataSys{Type: "groups"}
+		for _, group := range groups {
+			groupData.List = append(groupData.List, eventDataSysList{Name: group.GetName()})
+		}
+		fulldata.Sys = append(fulldata.Sys, groupData)
+	}
+
+	return fulldata
+}
+
+// sendInitMSG sends the initial message to a new event stream of the client
+func (c *Client) sendInitMSG(onEvent OnEventFunc) error {
 	// Marshal the data
-	jsonData, err := json.Marshal(fulldata)
+	jsonData, err := json.Marshal(c.initData())
 	if err != nil {
 		return err
 	}
 
 	onEvent("data: " + string(jsonData) + "\n\n")
-
-	// Send JSON data to the client
-	return err
+	return nil
 }
 
-// Start the client
-// 0. Check if client is already receiving
-// 1. Set status to Receving and create stop channel
-// 2. Send init message to client
+// Start a new event stream of the client, e.g. for a browser tab
+// A client can have multiple event streams at the same time. Every stream receives all messages.
+// 1. Register a new connection and set status to Receving
+// 2. Send init message to client and emit OnConnected
 // 3. Keep the connection open
-// 4. Send message to client if new data is published over the stream
-// 5. Stop the client if the stop channel is closed
+// 4. Send message to client if new data is published over the streams. High priority messages first.
+// 5. Deregister the connection if ctx is done or the client is stopped
 func (c *Client) Start(ctx context.Context, onEvent OnEventFunc) error {
-	// Set status to Receving and create stop channel
-	if c.GetStatus() == Receving {
-		return fmt.Errorf("[C:%s]: Client is already receiving", c.GetID())
+	// Count the running event streams for Close. No new streams are started after Close.
+	c.sSEPubSubService.activeStreams.Add(1)
+	defer c.sSEPubSubService.activeStreams.Add(-1)
+	if c.sSEPubSubService.closed.Load() {
+		return ErrServiceClosed
 	}
 
-	// Set status to Receving and create stop channel
+	// Register a new connection and set status to Receving
+	conn := newConnection(c.maxBuffer)
 	c.lock.Lock()
-	c.stopchan = make(chan struct{})
+	c.connections[conn.id] = conn
 	c.status = Receving
-	c.stream = make(chan string)
+	direct := c.direct
+	c.direct = nil
+
+	// Wake up WaitForStream
+	close(c.streamStarted)
+	c.streamStarted = make(chan struct{})
 	c.lock.Unlock()
 
-	// Stop the client at the end
+	// Deregister the connection at the end
 	defer func() {
-		c.stop()
+		c.removeConnection(conn)
+		c.onDisconnected.emit(c)
 	}()
 
 	if err := c.sendInitMSG(onEvent); err != nil {
-		log.Errorf("[C:%s]: Error sending init message to client: %s", c.GetID(), err)
+		c.logger.Errorf("[C:%s]: Error sending init message to client: %s", c.GetID(), err)
 		return err
 	}
 
+	// Send the frames that were queued while the client had no event stream
+	for _, msg := range direct {
+		onEvent(msg)
+	}
+
+	// Emit event
+	c.onConnected.emit(c)
+
 	// Keep the connection open until it's closed by the client
 loop:
 	for {
+		// Drain the high priority stream first
 		select {
-		case msg, ok := <-c.stream:
-			if !ok {
-				log.Infof("[C:%s] Client stopped receiving", c.GetID())
-				break loop
-			}
-			log.Infof("[C:%s] Sending message to client: %s", c.GetID(), msg)
+		case msg := <-conn.highStream:
+			c.logger.Infof("[C:%s] Sending high priority message to client: %s", c.GetID(), msg)
+			onEvent(msg)
+			continue
+		default:
+		}
+
+		select {
+		case msg := <-conn.highStream:
+			c.logger.Infof("[C:%s] Sending high priority message to client: %s", c.GetID(), msg)
+			onEvent(msg)
+		case msg := <-conn.stream:
+			c.logger.Infof("[C:%s] Sending message to client: %s", c.GetID(), msg)
 			onEvent(msg)
 		case <-ctx.Done():
-			log.Infof("[C:%s] Client stopped receiving", c.GetID())
+			c.logger.Infof("[C:%s] Client stopped receiving", c.GetID())
 			break loop
-		case <-c.stopchan:
-			log.Infof("[C:%s] Client stopped receiving", c.GetID())
+		case <-conn.stopchan:
+			c.logger.Infof("[C:%s] Client stopped receiving", c.GetID())
 			break loop
 		}
 	}
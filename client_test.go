@@ -1,36 +1,61 @@
 package pubsubsse
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"go.uber.org/goleak"
 )
 
 // Tests for:
 // +GetID(): string
 // +GetStatus(): status
+// +WaitForStream(ctx context.Context): error
 
 // +GetPublicTopics(): map[string]*topic
 // +GetPublicTopicByName(name string): *topic, bool
+// +ListPublicTopics(): []*topic
 
 // +NewPrivateTopic(name string): *topic
 // +RemovePrivateTopic(t *topic)
 // +GetPrivateTopics(): map[string]*topic
 // +GetPrivateTopicByName(name string): *topic, bool
+// +ListPrivateTopics(): []*topic
 
 // +GetGroups(): map[string]*group
 // +GetGroupByName(name string): *group, bool
+// +ListGroups(): []*group
 
 // +GetAllTopics(): map[string]*topic
 // +GetTopicByName(name string): *topic, bool
 // +GetSubscribedTopics(): map[string]*topic
+// +ListAllTopics(): []*topic
+// +ListSubscribedTopics(): []*topic
 
 // +Sub(topic *topic): error
 // +Unsub(topic *topic): error
+// +SubGroup(groupName string): error
+// +UnsubGroup(groupName string): error
+// +SendSysEvent(eventType string, payload interface{}): error
+// +Disconnect(): error
+// +DisconnectAll(): error
+// +SendError(code int, message string): error
 
 // +OnEvent(f OnEventFunc)
 // +RemoveOnEvent()
 // +Start(ctx Context)
 
 // -send(msg interface): error
+// -sendCtx(ctx Context, d *eventData, p Priority): error
 // -sendTopicList(): error
 // -sendSubscribedTopic(topic *topic): error
 // -sendUnsubscribedTopic(topic *topic): error
@@ -45,7 +70,7 @@ import (
 
 // TestClient_GetStatus tests Client.GetStatus()
 func TestClient_GetID(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	c := ssePubSub.NewClient()
 	if c.GetID() == "" {
 		t.Error("Client.GetID() == \"\"")
@@ -54,7 +79,7 @@ func TestClient_GetID(t *testing.T) {
 
 // TestClient_GetStatus tests Client.GetStatus()
 func TestClient_GetStatus(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if client.GetStatus() != Waiting {
 		t.Error("Client.GetStatus() != Waiting")
@@ -80,7 +105,7 @@ func TestClient_GetStatus(t *testing.T) {
 
 // TestClient_GetPublicTopics tests Client.GetPublicTopics()
 func TestClient_GetPublicTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if len(client.GetPublicTopics()) != 0 {
 		t.Error("len(client.GetPublicTopics()) != 0")
@@ -101,7 +126,7 @@ func TestClient_GetPublicTopics(t *testing.T) {
 
 // TestClient_GetPublicTopicByName tests Client.GetPublicTopicByName()
 func TestClient_GetPublicTopicByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a public topic
@@ -123,7 +148,7 @@ func TestClient_GetPublicTopicByName(t *testing.T) {
 
 // TestClient_NewPrivateTopic tests Client.NewPrivateTopic()
 func TestClient_NewPrivateTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a private topic
@@ -141,11 +166,15 @@ func TestClient_NewPrivateTopic(t *testing.T) {
 
 // TestClient_RemovePrivateTopic tests Client.RemovePrivateTopic()
 func TestClient_RemovePrivateTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a private topic
 	privTopic := client.NewPrivateTopic("test")
+	if _, err := privTopic.PubScheduled(time.Hour, "scheduled"); err != nil {
+		t.Fatal(err)
+	}
+	privTopic.setAutoDelete(AutoDeleteAfter(time.Hour), func() { client.RemovePrivateTopic(privTopic) })
 
 	// Remove topic
 	client.RemovePrivateTopic(privTopic)
@@ -155,11 +184,24 @@ func TestClient_RemovePrivateTopic(t *testing.T) {
 	if len(privTopics) != 0 {
 		t.Errorf("%d != 0", len(privTopics))
 	}
+
+	// The scheduled publishes are cancelled
+	privTopic.scheduledPubs.Range(func(key, value interface{}) bool {
+		t.Error("Expected the scheduled publish to be cancelled")
+		return false
+	})
+
+	// The auto delete timer is stopped
+	privTopic.lock.Lock()
+	if privTopic.autoDeleteTimer != nil {
+		t.Error("Expected the auto delete timer to be stopped")
+	}
+	privTopic.lock.Unlock()
 }
 
 // TestClient_GetPrivateTopics tests Client.GetPrivateTopics()
 func TestClient_GetPrivateTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if len(client.GetPrivateTopics()) != 0 {
 		t.Error("len(client.GetPrivateTopics()) != 0")
@@ -178,9 +220,72 @@ func TestClient_GetPrivateTopics(t *testing.T) {
 	}
 }
 
+// TestClient_ListTopics tests Client.ListPublicTopics() and Client.ListPrivateTopics()
+func TestClient_ListTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	pubB := ssePubSub.NewPublicTopic("pub_b")
+	pubA := ssePubSub.NewPublicTopic("pub_a")
+	privB := client.NewPrivateTopic("priv_b")
+	privA := client.NewPrivateTopic("priv_a")
+	ssePubSub.NewGroup("group").NewTopic("grouptopic")
+
+	public := client.ListPublicTopics()
+	if len(public) != 2 || public[0] != pubA || public[1] != pubB {
+		t.Errorf("Expected pub_a and pub_b, got %v", public)
+	}
+	private := client.ListPrivateTopics()
+	if len(private) != 2 || private[0] != privA || private[1] != privB {
+		t.Errorf("Expected priv_a and priv_b, got %v", private)
+	}
+
+	// Modifying the returned slices and maps does not affect the client
+	private[0] = nil
+	private[1] = pubA
+	delete(client.GetPrivateTopics(), "priv_a")
+	if topics := client.ListPrivateTopics(); len(topics) != 2 || topics[0] != privA {
+		t.Errorf("Expected the private topics to be unchanged, got %v", topics)
+	}
+	if _, ok := client.GetPrivateTopicByName("priv_a"); !ok {
+		t.Error("Expected priv_a to still exist")
+	}
+}
+
+// TestClient_ListAllTopics tests Client.ListAllTopics(), Client.ListSubscribedTopics() and Client.ListGroups()
+func TestClient_ListAllTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	pub := ssePubSub.NewPublicTopic("b_pub")
+	priv := client.NewPrivateTopic("c_priv")
+	groupB := ssePubSub.NewGroup("group_b")
+	groupA := ssePubSub.NewGroup("group_a")
+	grouptopic := groupA.NewTopic("a_grouptopic")
+	groupA.AddClient(client)
+	groupB.AddClient(client)
+	if err := client.Sub(priv); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sub(grouptopic); err != nil {
+		t.Fatal(err)
+	}
+
+	all := client.ListAllTopics()
+	if len(all) != 3 || all[0] != grouptopic || all[1] != pub || all[2] != priv {
+		t.Errorf("Expected a_grouptopic, b_pub and c_priv, got %v", all)
+	}
+	subscribed := client.ListSubscribedTopics()
+	if len(subscribed) != 2 || subscribed[0] != grouptopic || subscribed[1] != priv {
+		t.Errorf("Expected a_grouptopic and c_priv, got %v", subscribed)
+	}
+	groups := client.ListGroups()
+	if len(groups) != 2 || groups[0] != groupA || groups[1] != groupB {
+		t.Errorf("Expected group_a and group_b, got %v", groups)
+	}
+}
+
 // TestClient_GetPrivateTopicByName tests Client.GetPrivateTopicByName()
 func TestClient_GetPrivateTopicByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a private topic
@@ -202,7 +307,7 @@ func TestClient_GetPrivateTopicByName(t *testing.T) {
 
 // TestClient_GetGroups tests Client.GetGroups()
 func TestClient_GetGroups(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if len(client.GetGroups()) != 0 {
 		t.Error("len(client.GetGroups()) != 0")
@@ -224,9 +329,48 @@ func TestClient_GetGroups(t *testing.T) {
 	}
 }
 
+// TestClient_GroupsInit tests that the init message contains the groups of the client
+func TestClient_GroupsInit(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	group1 := ssePubSub.NewGroup("test1")
+	group2 := ssePubSub.NewGroup("test2")
+	group1.AddClient(client)
+	group2.AddClient(client)
+
+	// Get the group names of the init message
+	initGroups := func() map[string]bool {
+		events, stop := startClient(t, client)
+		stop()
+
+		groups := make(map[string]bool)
+		for _, sys := range events()[0].Sys {
+			if sys.Type == "groups" {
+				for _, l := range sys.List {
+					groups[l.Name] = true
+				}
+			}
+		}
+		return groups
+	}
+
+	groups := initGroups()
+	if len(groups) != 2 || !groups["test1"] || !groups["test2"] {
+		t.Errorf("Expected groups test1 and test2, got %v", groups)
+	}
+
+	// Reconnect after removing the client from a group
+	group1.RemoveClient(client)
+	groups = initGroups()
+	if len(groups) != 1 || !groups["test2"] {
+		t.Errorf("Expected group test2, got %v", groups)
+	}
+}
+
 // TestClient_GetGroupByName tests Client.GetGroupByName()
 func TestClient_GetGroupByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a group
@@ -251,7 +395,7 @@ func TestClient_GetGroupByName(t *testing.T) {
 
 // TestClient_GetAllTopics tests Client.GetAllTopics()
 func TestClient_GetAllTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if len(client.GetAllTopics()) != 0 {
 		t.Error("len(client.GetAllTopics()) != 0")
@@ -286,7 +430,7 @@ func TestClient_GetAllTopics(t *testing.T) {
 
 // TestClient_GetTopicByName tests Client.GetTopicByName()
 func TestClient_GetTopicByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a public topic
@@ -328,7 +472,7 @@ func TestClient_GetTopicByName(t *testing.T) {
 
 // TestClient_GetSubscribedTopics tests Client.GetSubscribedTopics()
 func TestClient_GetSubscribedTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	if len(client.GetSubscribedTopics()) != 0 {
 		t.Error("len(client.GetSubscribedTopics()) != 0")
@@ -372,7 +516,7 @@ func TestClient_GetSubscribedTopics(t *testing.T) {
 
 // TestClient_Sub tests Client.Sub()
 func TestClient_Sub(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a public topic
@@ -393,7 +537,7 @@ func TestClient_Sub(t *testing.T) {
 
 // TestClient_Unsub tests Client.Unsub()
 func TestClient_Unsub(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create a public topic
@@ -418,7 +562,7 @@ func TestClient_Unsub(t *testing.T) {
 
 // TestClient_send tests Client.send()
 func TestClient_send(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 
 	// Create topic and subscribe
@@ -460,7 +604,7 @@ func TestClient_send(t *testing.T) {
 	}
 	for _, d := range data {
 		if len(d.Updates) > 0 {
-			if d.Updates[0].Data != testData {
+			if decodeData(d.Updates[0].Data) != testData {
 				t.Error("data[].Updates[0].Data != testData")
 				return
 			}
@@ -470,3 +614,457 @@ func TestClient_send(t *testing.T) {
 	t.Error("No Updates received")
 
 }
+
+// TestClient_sendCtx tests that a cancelled context stops a pending send without leaking goroutines
+func TestClient_sendCtx(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+
+	// Block the event stream, so the stream of the client fills up
+	gate := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(msg string) { <-gate })
+	}()
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < streamSize+1; i++ {
+		topic.Pub("fill")
+	}
+
+	// Cancel the publish while it waits for the full stream
+	pubCtx, pubCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer pubCancel()
+	start := time.Now()
+	if err := topic.PubCtx(pubCtx, "test"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 80*time.Millisecond {
+		t.Error("Publish was not cancelled immediately")
+	}
+
+	// Stopping the client stops a pending send
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.stop()
+	}()
+	start = time.Now()
+	if err := client.send(&eventData{}); err == nil {
+		t.Error("Expected error after the client stopped")
+	}
+	if time.Since(start) > 80*time.Millisecond {
+		t.Error("Send was not stopped immediately")
+	}
+
+	cancel()
+	close(gate)
+	<-done
+}
+
+// TestClient_WaitForStream tests Client.WaitForStream()
+func TestClient_WaitForStream(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	// Early cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.WaitForStream(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Wait for an event stream started later
+	done := make(chan error)
+	go func() {
+		done <- client.WaitForStream(context.Background())
+	}()
+	select {
+	case <-done:
+		t.Fatal("Expected WaitForStream to block without event stream")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	_, stop := startClient(t, client)
+	defer stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected WaitForStream to return after the event stream started")
+	}
+
+	// Returns at once while receiving
+	if err := client.WaitForStream(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestClient_InitDeterministic tests that the init message is always the same and sorted by name
+func TestClient_InitDeterministic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	for _, name := range []string{"d", "b", "f", "a", "e", "c"} {
+		topic := ssePubSub.NewPublicTopic("public-" + name)
+		if err := client.Sub(topic); err != nil {
+			t.Fatal(err)
+		}
+		client.NewPrivateTopic("private-" + name)
+		group := ssePubSub.NewGroup("group-" + name)
+		group.NewTopic("grouptopic-" + name)
+		group.AddClient(client)
+	}
+
+	initMSG := func() string {
+		var msg string
+		if err := client.sendInitMSG(func(data string) { msg = data }); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	first := initMSG()
+	for i := 0; i < 1000; i++ {
+		if msg := initMSG(); msg != first {
+			t.Fatalf("Expected the same init message every time\nfirst: %s\ngot:   %s", first, msg)
+		}
+	}
+
+	var data eventData
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(first, "data: "), "\n\n")), &data); err != nil {
+		t.Fatal(err)
+	}
+	for _, sys := range data.Sys {
+		if !sort.SliceIsSorted(sys.List, func(i, j int) bool { return sys.List[i].Name < sys.List[j].Name }) {
+			t.Errorf("Expected the %s list to be sorted by name, got %v", sys.Type, sys.List)
+		}
+	}
+}
+
+// TestClient_SendSysEvent tests Client.SendSysEvent()
+func TestClient_SendSysEvent(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	// The client is not receiving
+	if err := client.SendSysEvent("user_kicked", nil); err == nil {
+		t.Error("Expected error without event stream")
+	}
+
+	events, stop := startClient(t, client)
+	if err := client.SendSysEvent("user_kicked", map[string]string{"reason": "spam"}); err != nil {
+		t.Error(err)
+	}
+	for _, eventType := range []string{"", "topics", "subscribed", "unsubscribed", "groups", "server_closing"} {
+		if err := client.SendSysEvent(eventType, nil); err == nil {
+			t.Errorf("Expected error for sys event type %q", eventType)
+		}
+	}
+	stop()
+
+	received := events()
+	if len(received) != 2 {
+		t.Fatalf("Expected init message and sys event, got %d messages", len(received))
+	}
+	sys := received[1].Sys
+	if len(sys) != 1 || sys[0].Type != "user_kicked" || len(sys[0].List) != 0 {
+		t.Fatalf("Expected sys event user_kicked, got %+v", sys)
+	}
+	if data, ok := decodeData(sys[0].Data).(map[string]interface{}); !ok || data["reason"] != "spam" {
+		t.Errorf("Expected payload {reason: spam}, got %s", sys[0].Data)
+	}
+}
+
+// TestClient_SubGroup tests Client.SubGroup() and Client.UnsubGroup()
+func TestClient_SubGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	other := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	topic1 := group.NewTopic("topic1")
+
+	// The client has to be in the group
+	if err := client.SubGroup("group"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Expected ErrGroupNotFound, got %v", err)
+	}
+
+	group.AddClient(client)
+	group.AddClient(other)
+	if err := client.SubGroup("group"); err != nil {
+		t.Fatal(err)
+	}
+	if !topic1.IsSubscribed(client) {
+		t.Error("Expected the client to be subscribed to the existing topic")
+	}
+
+	// Topics added later are subscribed automatically, only for clients with a group subscription
+	topic2 := group.NewTopic("topic2")
+	if !topic2.IsSubscribed(client) {
+		t.Error("Expected the client to be subscribed to the new topic")
+	}
+	if topic2.IsSubscribed(other) {
+		t.Error("Expected the other client not to be subscribed to the new topic")
+	}
+
+	// UnsubGroup unsubscribes from all topics and stops the automatic subscription
+	if err := client.UnsubGroup("group"); err != nil {
+		t.Fatal(err)
+	}
+	if topic1.IsSubscribed(client) || topic2.IsSubscribed(client) {
+		t.Error("Expected the client to be unsubscribed from all group topics")
+	}
+	if topic3 := group.NewTopic("topic3"); topic3.IsSubscribed(client) {
+		t.Error("Expected the client not to be subscribed after UnsubGroup")
+	}
+
+	// Leaving the group ends the group subscription
+	if err := client.SubGroup("group"); err != nil {
+		t.Fatal(err)
+	}
+	group.RemoveClient(client)
+	group.AddClient(client)
+	if topic4 := group.NewTopic("topic4"); topic4.IsSubscribed(client) {
+		t.Error("Expected the group subscription to end when the client leaves the group")
+	}
+}
+
+// TestClient_Disconnect tests that Disconnect ends the event stream and the client can reconnect
+func TestClient_Disconnect(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	// Not receiving
+	if err := client.Disconnect(); err == nil {
+		t.Error("Expected error without event stream")
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/event?client_id=" + client.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := bufio.NewReader(resp.Body)
+		readFrames(t, reader, 1, time.Second) // init message
+
+		// Queued messages are delivered before the server_disconnect message
+		if err := topic.Pub("hello"); err != nil {
+			t.Error(err)
+		}
+		if err := client.Disconnect(); err != nil {
+			t.Error(err)
+		}
+		frames := readFrames(t, reader, 2, time.Second)
+		if len(frames[0].Updates) != 1 || decodeData(frames[0].Updates[0].Data) != "hello" {
+			t.Errorf("Expected the update, got %+v", frames[0])
+		}
+		if len(frames[1].Sys) != 1 || frames[1].Sys[0].Type != "server_disconnect" {
+			t.Errorf("Expected the server_disconnect message, got %+v", frames[1])
+		}
+
+		// The response body ends
+		if _, err := io.ReadAll(reader); err != nil {
+			t.Error(err)
+		}
+		resp.Body.Close()
+		if client.GetStatus() != Waiting {
+			t.Error("Expected status Waiting after Disconnect")
+		}
+	}
+
+	if err := client.SendSysEvent("server_disconnect", nil); err == nil {
+		t.Error("Expected server_disconnect to be reserved")
+	}
+}
+
+// TestClient_DisconnectConnections tests that Disconnect ends every event stream of the client, e.g. connections opened with the same client ID
+func TestClient_DisconnectConnections(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	readers := []*bufio.Reader{}
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/event?client_id=" + client.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		reader := bufio.NewReader(resp.Body)
+		readFrames(t, reader, 1, time.Second) // init message
+		readers = append(readers, reader)
+	}
+
+	if err := client.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	for i, reader := range readers {
+		frames := readFrames(t, reader, 1, time.Second)
+		if len(frames[0].Sys) != 1 || frames[0].Sys[0].Type != "server_disconnect" {
+			t.Errorf("Connection %d: expected the server_disconnect message, got %+v", i, frames[0])
+		}
+		if _, err := io.ReadAll(reader); err != nil {
+			t.Errorf("Connection %d: %s", i, err)
+		}
+	}
+}
+
+// TestClient_DisconnectTab tests that Disconnect of a tab keeps the event stream of the primary tab open
+func TestClient_DisconnectTab(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	primary := ssePubSub.NewClient()
+	tab := ssePubSub.NewClient()
+	ssePubSub.TabManager().SetPrimary("browser", primary)
+	ssePubSub.TabManager().AddTab("browser", tab)
+
+	events, stop := startClient(t, primary)
+	if err := tab.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	if primary.GetStatus() != Receving {
+		t.Error("Expected the primary tab to keep receiving")
+	}
+	if err := tab.send(&eventData{}); err == nil {
+		t.Error("Expected the tab not to be routed after Disconnect")
+	}
+	stop()
+
+	found := false
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			if sys.Type == "tab" && sys.TabID == tab.GetID() && strings.Contains(string(sys.Data), "server_disconnect") {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected the server_disconnect message of the tab over the primary tab")
+	}
+
+	// A tab is disconnected even if its primary tab is not receiving
+	ssePubSub.TabManager().AddTab("browser", tab)
+	if err := tab.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	if ssePubSub.TabManager().isTab(tab) {
+		t.Error("Expected the tab to be removed after Disconnect without a receiving primary tab")
+	}
+}
+
+// TestClient_DisconnectAll tests that DisconnectAll ends the event stream of the client and disconnects its tabs
+func TestClient_DisconnectAll(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	primary := ssePubSub.NewClient()
+	tab := ssePubSub.NewClient()
+	other := ssePubSub.NewClient()
+	ssePubSub.TabManager().SetPrimary("browser", primary)
+	ssePubSub.TabManager().AddTab("browser", tab)
+	ssePubSub.TabManager().SetPrimary("other", other)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/event?client_id=" + primary.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	readFrames(t, reader, 1, time.Second) // init message
+	events, stop := startClient(t, other)
+	defer stop()
+
+	if err := primary.DisconnectAll(); err != nil {
+		t.Error(err)
+	}
+
+	// The tab is disconnected over the stream of the primary tab before the stream ends
+	frames := readFrames(t, reader, 2, time.Second)
+	if len(frames[0].Sys) != 1 || frames[0].Sys[0].Type != "tab" || frames[0].Sys[0].TabID != tab.GetID() ||
+		!strings.Contains(string(frames[0].Sys[0].Data), "server_disconnect") {
+		t.Errorf("Expected the server_disconnect message of the tab, got %+v", frames[0])
+	}
+	if len(frames[1].Sys) != 1 || frames[1].Sys[0].Type != "server_disconnect" {
+		t.Errorf("Expected the server_disconnect message, got %+v", frames[1])
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Error(err)
+	}
+	if err := tab.send(&eventData{}); err == nil {
+		t.Error("Expected the tab not to be routed after DisconnectAll")
+	}
+
+	// Clients of other browsers keep their streams
+	if other.GetStatus() != Receving {
+		t.Error("Expected the client of the other browser to keep receiving")
+	}
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			if sys.Type == "server_disconnect" {
+				t.Error("Expected no server_disconnect message for the client of the other browser")
+			}
+		}
+	}
+}
+
+// TestClient_SendError tests that an error sent before Disconnect is received before the event stream ends
+func TestClient_SendError(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	// Not receiving
+	if err := client.SendError(ErrorCodeForbidden, "kicked"); err == nil {
+		t.Error("Expected error without event stream")
+	}
+
+	resp, err := http.Get(srv.URL + "/event?client_id=" + client.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	readFrames(t, reader, 1, time.Second) // init message
+
+	if err := client.SendError(ErrorCodeForbidden, "You were kicked"); err != nil {
+		t.Error(err)
+	}
+	if err := client.Disconnect(); err != nil {
+		t.Error(err)
+	}
+	frames := readFrames(t, reader, 2, time.Second)
+	sys := frames[0].Sys
+	if len(sys) != 1 || sys[0].Type != "error" || len(sys[0].List) != 1 || sys[0].List[0].Name != "You were kicked" || sys[0].List[0].Type != "403" {
+		t.Errorf("Expected the error message, got %+v", frames[0])
+	}
+	if len(frames[1].Sys) != 1 || frames[1].Sys[0].Type != "server_disconnect" {
+		t.Errorf("Expected the server_disconnect message, got %+v", frames[1])
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Error(err)
+	}
+
+	if err := client.SendSysEvent("error", nil); err == nil {
+		t.Error("Expected error to be reserved")
+	}
+}
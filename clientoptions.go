@@ -0,0 +1,165 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// DropPolicy controls which message is dropped when a stream of a client is full.
+type DropPolicy int
+
+const (
+	// DropNewest waits up to the write timeout for space in the stream and drops the new message if there is none.
+	DropNewest DropPolicy = iota
+	// DropOldest drops the oldest queued message to make space for the new one.
+	DropOldest
+)
+
+// ClientOptions configures a client created with NewClientWithOptions.
+type ClientOptions struct {
+	// Tags of the client, e.g. for routing in a middleware. See Client.GetTag.
+	Tags map[string]string
+	// Size of the stream buffers of every event stream. Defaults to 100.
+	MaxBuffer int
+	// What to do when a stream buffer is full. Defaults to DropNewest.
+	DropPolicy DropPolicy
+	// Names of public topics the client is subscribed to before it is added.
+	InitialTopics []string
+}
+
+// Create a new client with an ID and options
+// If id is empty, a new ID is generated.
+// 0. Check the options and resolve the initial topics
+// 1. Create the client and subscribe it to the initial topics
+// 2. Add the client to the sSEPubSubService
+func (s *SSEPubSubService) NewClientWithOptions(id string, opts ClientOptions) (*Client, error) {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	if _, ok := s.GetClientByID(id); ok {
+		return nil, fmt.Errorf("client %s already exists", id)
+	}
+
+	// Check the options and resolve the initial topics
+	if opts.MaxBuffer < 0 {
+		return nil, fmt.Errorf("max buffer must not be negative")
+	}
+	topics := make([]*Topic, 0, len(opts.InitialTopics))
+	for _, name := range opts.InitialTopics {
+		t, ok := s.GetPublicTopicByName(name)
+		if !ok {
+			return nil, fmt.Errorf("topic %s does not exist", name)
+		}
+		if t.IsWriteOnly() {
+			return nil, ErrWriteOnlyTopic
+		}
+		topics = append(topics, t)
+	}
+
+	// Create the client and subscribe it to the initial topics
+	c := newClient(s)
+	c.id = id
+	c.dropPolicy = opts.DropPolicy
+	if opts.MaxBuffer > 0 {
+		c.maxBuffer = opts.MaxBuffer
+	}
+	for k, v := range opts.Tags {
+		c.tags[k] = v
+	}
+	undo := func() {
+		for _, t := range topics {
+			t.removeClient(c)
+		}
+	}
+	for _, t := range topics {
+		if err := c.Sub(t); err != nil {
+			undo()
+			return nil, err
+		}
+	}
+
+	// Add the client to the sSEPubSubService
+	if _, ok := s.addClient(c); !ok {
+		undo()
+		return nil, fmt.Errorf("client %s already exists", id)
+	}
+
+	return c, nil
+}
+
+// Get a tag of the client, see ClientOptions
+func (c *Client) GetTag(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	v, ok := c.tags[key]
+	return v, ok
+}
+
+// Set a tag of the client
+// The client can be found with GetClientsByTag of the sSEPubSubService.
+func (c *Client) SetTag(key, value string) {
+	s := c.sSEPubSubService
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if old, ok := c.tags[key]; ok {
+		s.unindexTag(key, old, c)
+	}
+	c.tags[key] = value
+
+	// Removed clients are not indexed
+	if s.clients[c.id] == c {
+		s.indexTag(key, value, c)
+	}
+}
+
+// Get all clients with a tag, sorted by ID
+func (s *SSEPubSubService) GetClientsByTag(key, value string) []*Client {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clients := make([]*Client, 0, len(s.tagIndex[key][value]))
+	for _, c := range s.tagIndex[key][value] {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].id < clients[j].id })
+	return clients
+}
+
+// Add a client to the tag index. The lock of the sSEPubSubService must be held.
+func (s *SSEPubSubService) indexTag(key, value string, c *Client) {
+	values, ok := s.tagIndex[key]
+	if !ok {
+		values = make(map[string]map[string]*Client)
+		s.tagIndex[key] = values
+	}
+	clients, ok := values[value]
+	if !ok {
+		clients = make(map[string]*Client)
+		values[value] = clients
+	}
+	clients[c.id] = c
+}
+
+// Remove a client from the tag index. The lock of the sSEPubSubService must be held.
+func (s *SSEPubSubService) unindexTag(key, value string, c *Client) {
+	clients := s.tagIndex[key][value]
+	if clients[c.id] != c {
+		return
+	}
+	delete(clients, c.id)
+
+	// Remove empty entries
+	if len(clients) == 0 {
+		delete(s.tagIndex[key], value)
+		if len(s.tagIndex[key]) == 0 {
+			delete(s.tagIndex, key)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package pubsubsse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +NewClientWithOptions(id string, opts ClientOptions): *Client, error
+// +GetClientsByTag(key, value string): []*Client
+// Client:
+// +GetTag(key string): string, bool
+// +SetTag(key, value string)
+// -sendToConnection() with DropOldest
+
+// TestNewClientWithOptions tests SSEPubSubService.NewClientWithOptions()
+func TestNewClientWithOptions(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.NewPublicTopic("a")
+	ssePubSub.NewPublicTopic("b")
+	ssePubSub.NewPublicTopic("c")
+
+	client, err := ssePubSub.NewClientWithOptions("client1", ClientOptions{
+		Tags:          map[string]string{"room": "42"},
+		MaxBuffer:     10,
+		InitialTopics: []string{"b", "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetID() != "client1" {
+		t.Errorf("Expected ID client1, got %s", client.GetID())
+	}
+	if c, ok := ssePubSub.GetClientByID("client1"); !ok || c != client {
+		t.Error("Expected the client to be added")
+	}
+	if room, ok := client.GetTag("room"); !ok || room != "42" {
+		t.Errorf("Expected tag room=42, got %q", room)
+	}
+	if _, ok := client.GetTag("missing"); ok {
+		t.Error("Expected missing tag not to exist")
+	}
+
+	// The initial subscriptions are part of the first init message
+	events, stop := startClient(t, client)
+	stop()
+	subscribed := []string{}
+	for _, sys := range events()[0].Sys {
+		if sys.Type == "subscribed" {
+			for _, l := range sys.List {
+				subscribed = append(subscribed, l.Name)
+			}
+		}
+	}
+	if len(subscribed) != 2 || subscribed[0] != "a" || subscribed[1] != "b" {
+		t.Errorf("Expected subscribed topics a and b in the init message, got %v", subscribed)
+	}
+
+	// Duplicate IDs and unknown topics are rejected
+	if _, err := ssePubSub.NewClientWithOptions("client1", ClientOptions{}); err == nil {
+		t.Error("Expected error for duplicate ID")
+	}
+	if _, err := ssePubSub.NewClientWithOptions("client2", ClientOptions{InitialTopics: []string{"a", "missing"}}); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	if _, ok := ssePubSub.GetClientByID("client2"); ok {
+		t.Error("Expected client2 not to be added")
+	}
+	topicA, _ := ssePubSub.GetPublicTopicByName("a")
+	if len(topicA.GetClients()) != 1 {
+		t.Errorf("Expected 1 subscriber of topic a, got %d", len(topicA.GetClients()))
+	}
+
+	// An empty ID is generated
+	client3, err := ssePubSub.NewClientWithOptions("", ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client3.GetID() == "" {
+		t.Error("Expected a generated ID")
+	}
+}
+
+// TestGetClientsByTag tests that the tag index follows SetTag and RemoveClient
+func TestGetClientsByTag(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	a, err := ssePubSub.NewClientWithOptions("a", ClientOptions{Tags: map[string]string{"room": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ssePubSub.NewClientWithOptions("b", ClientOptions{Tags: map[string]string{"room": "1", "role": "moderator"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ssePubSub.NewClient()
+	c.SetTag("room", "2")
+
+	// Check the IDs of the clients with a tag
+	expect := func(key, value string, ids ...string) {
+		t.Helper()
+		clients := ssePubSub.GetClientsByTag(key, value)
+		got := make([]string, 0, len(clients))
+		for _, c := range clients {
+			got = append(got, c.GetID())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(ids) {
+			t.Errorf("Expected clients %v with %s=%s, got %v", ids, key, value, got)
+		}
+	}
+	expect("room", "1", "a", "b")
+	expect("room", "2", c.GetID())
+	expect("role", "moderator", "b")
+	expect("room", "3")
+
+	// Changing a tag moves the client
+	a.SetTag("room", "2")
+	if v, _ := a.GetTag("room"); v != "2" {
+		t.Errorf("Expected tag room=2, got %s", v)
+	}
+	expect("room", "1", "b")
+
+	// Removed clients are removed from the index and not added again
+	ssePubSub.RemoveClient(b)
+	expect("room", "1")
+	expect("role", "moderator")
+	b.SetTag("role", "viewer")
+	expect("role", "viewer")
+	if len(ssePubSub.tagIndex) != 1 || len(ssePubSub.tagIndex["room"]) != 1 {
+		t.Errorf("Expected empty entries to be removed, got %v", ssePubSub.tagIndex)
+	}
+}
+
+// TestClientOptions_DropPolicy tests the drop policies of a full stream
+func TestClientOptions_DropPolicy(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	for _, policy := range []DropPolicy{DropNewest, DropOldest} {
+		client, err := ssePubSub.NewClientWithOptions("", ClientOptions{MaxBuffer: 2, DropPolicy: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn := newConnection(client.maxBuffer)
+
+		errs := 0
+		for _, msg := range []string{"1", "2", "3"} {
+			if err := client.sendToConnection(context.Background(), conn, msg, PriorityNormal, 10*time.Millisecond); err != nil {
+				errs++
+			}
+		}
+
+		expected := []string{"1", "2"}
+		expectedErrs := 1
+		if policy == DropOldest {
+			expected = []string{"2", "3"}
+			expectedErrs = 0
+		}
+		if errs != expectedErrs {
+			t.Errorf("Policy %d: expected %d errors, got %d", policy, expectedErrs, errs)
+		}
+		for _, want := range expected {
+			if got := <-conn.stream; got != want {
+				t.Errorf("Policy %d: expected %s, got %s", policy, want, got)
+			}
+		}
+	}
+}
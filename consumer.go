@@ -0,0 +1,167 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Size of the queue of a consumer pool per worker
+const consumerQueueSize = 100
+
+// errConsumerPoolClosed is returned by enqueue if the pool does not accept messages anymore.
+var errConsumerPoolClosed = errors.New("consumer pool is closed")
+
+// consumerJob is a message queued for the workers of a consumer pool.
+type consumerJob struct {
+	update   eventDataUpdates
+	priority Priority
+}
+
+// ConsumerPool sends the messages of a topic to its subscribers off the publish path, see NewPublicTopicWithConsumers.
+// Pub only queues the message, so errors of the send are reported to the OnPubError hooks instead.
+// With more than one worker, messages can be delivered out of order.
+type ConsumerPool struct {
+	topic   *Topic
+	workers int
+
+	queue chan consumerJob
+
+	// Held for reading while a message is queued, and for writing while the pool is closed
+	lock   sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	// Closed when the sSEPubSubService is closed
+	done <-chan struct{}
+}
+
+// Create a new public topic whose messages are sent by a pool of workers
+// Pub queues the message and returns, the workers send it to the subscribers. The queue holds 100 messages per worker,
+// Pub waits if it is full. The workers stop when the pool or the sSEPubSubService is closed, then Pub sends directly again.
+// 0. Check the number of workers
+// 1. Create the topic with the pool and add it to the sSEPubSubService, return error if it already exists
+// 2. Start the workers
+func (s *SSEPubSubService) NewPublicTopicWithConsumers(name string, workers int) (*Topic, *ConsumerPool, error) {
+	// Check the number of workers
+	if workers < 1 {
+		return nil, nil, fmt.Errorf("workers must be at least 1")
+	}
+	if s.IsClosed() {
+		return nil, nil, ErrServiceClosed
+	}
+
+	// Create the topic with the pool
+	t := newTopic(name, TPublic, s.logger)
+	pool := &ConsumerPool{
+		topic:   t,
+		workers: workers,
+		queue:   make(chan consumerJob, consumerQueueSize*workers),
+		stop:    make(chan struct{}),
+		done:    s.done,
+	}
+	t.consumers = pool
+	if _, ok := s.addPublicTopic(t); !ok {
+		return nil, nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	// Start the workers
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			pool.run()
+		}()
+	}
+
+	return t, pool, nil
+}
+
+// Get the number of workers
+func (cp *ConsumerPool) Workers() int {
+	return cp.workers
+}
+
+// Get the number of queued messages
+func (cp *ConsumerPool) Queued() int {
+	return len(cp.queue)
+}
+
+// Close the pool
+// The queued messages are sent before Close returns. Later messages are sent directly by Pub.
+func (cp *ConsumerPool) Close() {
+	cp.lock.Lock()
+	if !cp.closed {
+		cp.closed = true
+		close(cp.stop)
+	}
+	cp.lock.Unlock()
+
+	cp.wg.Wait()
+}
+
+// Queue a message for the workers
+// Returns errConsumerPoolClosed if the pool or the sSEPubSubService is closed.
+func (cp *ConsumerPool) enqueue(ctx context.Context, job consumerJob) error {
+	cp.lock.RLock()
+	defer cp.lock.RUnlock()
+
+	if cp.closed {
+		return errConsumerPoolClosed
+	}
+	select {
+	case <-cp.done:
+		return errConsumerPoolClosed
+	default:
+	}
+
+	select {
+	case cp.queue <- job:
+		return nil
+	case <-cp.done:
+		return errConsumerPoolClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run sends the queued messages until the pool or the sSEPubSubService is closed, then sends the rest of the queue
+func (cp *ConsumerPool) run() {
+	defer cp.wg.Done()
+
+	for {
+		select {
+		case job := <-cp.queue:
+			cp.send(job)
+		case <-cp.stop:
+			cp.drain()
+			return
+		case <-cp.done:
+			cp.drain()
+			return
+		}
+	}
+}
+
+// Send the queued messages until the queue is empty
+func (cp *ConsumerPool) drain() {
+	for {
+		select {
+		case job := <-cp.queue:
+			cp.send(job)
+		default:
+			return
+		}
+	}
+}
+
+// Send a message to the subscribers of the topic
+// Errors are reported to the OnPubError hooks by publish.
+func (cp *ConsumerPool) send(job consumerJob) {
+	if err := cp.topic.publish(context.Background(), []eventDataUpdates{job.update}, job.priority, nil, nil); err != nil {
+		cp.topic.logger.Errorf("[T:%s]: Error publishing queued message: %s", cp.topic.GetName(), err)
+	}
+}
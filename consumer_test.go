@@ -0,0 +1,134 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +NewPublicTopicWithConsumers(name string, workers int): *Topic, *ConsumerPool, error
+// ConsumerPool:
+// +Workers(): int
+// +Queued(): int
+// +Close()
+
+// TestNewPublicTopicWithConsumers tests that the workers deliver all messages and Pub does not wait for the send
+func TestNewPublicTopicWithConsumers(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if _, _, err := ssePubSub.NewPublicTopicWithConsumers("test", 0); err == nil {
+		t.Error("Expected error for 0 workers")
+	}
+
+	topic, pool, err := ssePubSub.NewPublicTopicWithConsumers("test", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.Workers() != 4 {
+		t.Errorf("Expected 4 workers, got %d", pool.Workers())
+	}
+	if _, _, err := ssePubSub.NewPublicTopicWithConsumers("test", 1); err == nil {
+		t.Error("Expected error for an existing topic")
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := startClient(t, client)
+
+	// Pub returns while the workers are busy
+	release := make(chan struct{})
+	id := topic.OnPub(func(interface{}) { <-release })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if err := topic.Pub(i); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Pub not to wait for the workers")
+	}
+	if pool.Queued() == 0 {
+		t.Error("Expected queued messages")
+	}
+	close(release)
+	topic.RemoveOnPub(id)
+
+	// Close sends the queued messages
+	pool.Close()
+	if pool.Queued() != 0 {
+		t.Errorf("Expected an empty queue after Close, got %d", pool.Queued())
+	}
+
+	// After Close, Pub sends directly
+	published := false
+	topic.OnPub(func(interface{}) { published = true })
+	if err := topic.Pub(20); err != nil {
+		t.Error(err)
+	}
+	if !published {
+		t.Error("Expected Pub to send directly after Close")
+	}
+	stop()
+
+	// All messages are delivered, with 4 workers in any order
+	data := []int{}
+	for _, v := range updatesData(events()) {
+		data = append(data, int(v.(float64)))
+	}
+	sort.Ints(data)
+	if len(data) != 21 {
+		t.Fatalf("Expected 21 messages, got %d", len(data))
+	}
+	for i, v := range data {
+		if v != i {
+			t.Errorf("Expected message %d, got %d", i, v)
+		}
+	}
+}
+
+// TestConsumerPool_PubError tests that errors of the workers are reported to the OnPubError hooks
+func TestConsumerPool_PubError(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic, pool, err := ssePubSub.NewPublicTopicWithConsumers("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic.SetMaxPublishRateNonBlocking(0.001, 1)
+
+	errs := make(chan error, 2)
+	ssePubSub.OnPubError(func(topicName string, err error) { errs <- err })
+	topic.Pub("first")
+	if err := topic.Pub("second"); err != nil {
+		t.Errorf("Expected Pub to queue the message, got %s", err)
+	}
+	pool.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("Expected ErrRateLimited, got %s", err)
+		}
+	default:
+		t.Error("Expected the error of the worker")
+	}
+
+	// Removing the topic stops the pool
+	_, pool, err = ssePubSub.NewPublicTopicWithConsumers("other", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, _ = ssePubSub.GetPublicTopicByName("other")
+	ssePubSub.RemovePublicTopic(topic)
+	if err := pool.enqueue(context.Background(), consumerJob{}); !errors.Is(err, errConsumerPoolClosed) {
+		t.Errorf("Expected the pool to be closed, got %v", err)
+	}
+}
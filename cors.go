@@ -0,0 +1,167 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS headers of ServeHTTP and the Event handler, see SetCORS.
+type CORSConfig struct {
+	// Origins that can access the endpoints, e.g. "https://example.com". "*" allows every origin.
+	AllowedOrigins []string
+	// Allow requests with cookies, e.g. the BrowserIDCookie of an EventSource created withCredentials.
+	AllowCredentials bool
+	// How long browsers cache the result of a preflight request. 0 sends no Access-Control-Max-Age.
+	MaxAge time.Duration
+	// Response headers the browser can read
+	ExposeHeaders []string
+}
+
+// WithCORS allows cross-origin browsers to use the endpoints of ServeHTTP and the Event handler.
+// "*" allows every origin. It can not be combined with allowCredentials, which is needed to send cookies,
+// e.g. the BrowserIDCookie. See SetCORS for more settings.
+func WithCORS(origins []string, allowCredentials bool) Option {
+	return func(s *SSEPubSubService) {
+		s.cors = &CORSConfig{
+			AllowedOrigins:   append([]string{}, origins...),
+			AllowCredentials: allowCredentials,
+		}
+	}
+}
+
+// Check the CORS configuration of WithCORS and SetCORS
+func (c *CORSConfig) validate() error {
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("%w: no allowed CORS origins", ErrInvalidOption)
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" && c.AllowCredentials {
+			return fmt.Errorf("%w: CORS origin * can not be combined with credentials", ErrInvalidOption)
+		}
+	}
+	return nil
+}
+
+// Set the CORS configuration of ServeHTTP and the Event handler
+// The CORS headers are added to the responses of all endpoints and preflight requests are answered.
+// A config without allowed origins disables CORS. Returns ErrInvalidOption if the origin "*" is combined with
+// AllowCredentials, because every origin could then make credentialed requests.
+func (s *SSEPubSubService) SetCORS(config CORSConfig) error {
+	if len(config.AllowedOrigins) == 0 {
+		s.lock.Lock()
+		s.cors = nil
+		s.lock.Unlock()
+		return nil
+	}
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	// Copy the slices, so the caller can not change them
+	config.AllowedOrigins = append([]string{}, config.AllowedOrigins...)
+	config.ExposeHeaders = append([]string{}, config.ExposeHeaders...)
+	s.lock.Lock()
+	s.cors = &config
+	s.lock.Unlock()
+	return nil
+}
+
+// Get the CORS configuration
+func (s *SSEPubSubService) getCORS() *CORSConfig {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.cors
+}
+
+// Check if the origin is allowed
+func (c *CORSConfig) allows(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Add the CORS headers to the response
+// Returns false if CORS is disabled or the origin is not allowed. Headers that are already set, e.g. by ServeHTTP
+// before the Event handler, are not added again.
+// 0. Vary on the origin, so caches do not mix up the responses of different origins
+// 1. Allow the origin if it is allowed
+func (s *SSEPubSubService) addCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	cors := s.getCORS()
+	if cors == nil {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		return true
+	}
+
+	// Vary on the origin
+	if !headerContains(w.Header(), "Vary", "Origin") {
+		w.Header().Add("Vary", "Origin")
+	}
+	if origin == "" || !cors.allows(origin) {
+		return false
+	}
+
+	// Allow the origin. With credentials the origin has to be sent instead of "*".
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if cors.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+	}
+	return true
+}
+
+// Check if a header has the value
+func headerContains(h http.Header, key, value string) bool {
+	for _, v := range h.Values(key) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Add the CORS headers of a route to the response and answer preflight requests
+// Returns true if the request was a preflight request and has been answered.
+// 0. Add the CORS headers
+// 1. Answer preflight requests with the allowed methods and headers, 403 if the origin is not allowed
+func (s *SSEPubSubService) handleCORS(w http.ResponseWriter, r *http.Request, rt route) bool {
+	cors := s.getCORS()
+	if cors == nil {
+		return false
+	}
+
+	// Add the CORS headers
+	allowed := s.addCORSHeaders(w, r)
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !preflight || r.Header.Get("Origin") == "" {
+		return false
+	}
+	if !allowed {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	// Answer preflight requests
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(rt.methods, ", "))
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	if cors.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
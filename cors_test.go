@@ -0,0 +1,161 @@
+package pubsubsse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +SetCORS(config CORSConfig): error
+// +WithCORS(origins []string, allowCredentials bool): Option
+
+// TestSetCORS tests the CORS headers of ServeHTTP
+func TestSetCORS(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	// Send a request with an origin to ServeHTTP
+	request := func(method, path, origin string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		ssePubSub.ServeHTTP(w, r)
+		return w
+	}
+
+	// No CORS headers without config
+	if w := request(http.MethodPost, DefaultAddClientPath, "https://example.com", nil); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected no CORS headers")
+	}
+
+	if err := ssePubSub.SetCORS(CORSConfig{
+		AllowedOrigins:   []string{"https://example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+		ExposeHeaders:    []string{"X-Request-ID"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Allowed origin
+	w := request(http.MethodPost, DefaultAddClientPath, "https://example.com", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	h := w.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+		h.Get("Access-Control-Expose-Headers") != "X-Request-ID" || h.Get("Vary") != "Origin" {
+		t.Errorf("Unexpected CORS headers %v", h)
+	}
+
+	// Preflight request
+	w = request(http.MethodOptions, DefaultSubscribePath, "https://example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "Content-Type",
+	})
+	h = w.Header()
+	if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Methods") != "POST" ||
+		h.Get("Access-Control-Allow-Headers") != "Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Unexpected preflight response %d %v", w.Code, h)
+	}
+
+	// Other origins
+	w = request(http.MethodPost, DefaultAddClientPath, "https://evil.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected no allowed origin, got %v", w.Header())
+	}
+	w = request(http.MethodOptions, DefaultAddClientPath, "https://evil.com", map[string]string{"Access-Control-Request-Method": "POST"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for the preflight of another origin, got %d", w.Code)
+	}
+
+	// The wildcard origin with credentials is rejected and the config is kept
+	if err := ssePubSub.SetCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Expected ErrInvalidOption for * with credentials, got %v", err)
+	}
+	w = request(http.MethodPost, DefaultAddClientPath, "https://evil.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected no credentialed CORS headers for another origin, got %v", w.Header())
+	}
+
+	// Wildcard origin
+	if err := ssePubSub.SetCORS(CORSConfig{AllowedOrigins: []string{"*"}}); err != nil {
+		t.Fatal(err)
+	}
+	w = request(http.MethodPost, DefaultAddClientPath, "https://other.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://other.com" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Unexpected CORS headers %v", w.Header())
+	}
+
+	// An OPTIONS request without preflight headers is not allowed
+	w = request(http.MethodOptions, DefaultAddClientPath, "https://other.com", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+
+	// Disable CORS
+	if err := ssePubSub.SetCORS(CORSConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if w := request(http.MethodPost, DefaultAddClientPath, "https://example.com", nil); w.Header().Get("Vary") != "" {
+		t.Error("Expected no CORS headers")
+	}
+}
+
+// TestWithCORS tests that cross-origin EventSources are allowed and invalid configurations are rejected
+func TestWithCORS(t *testing.T) {
+	for _, opt := range []Option{WithCORS(nil, false), WithCORS([]string{"*"}, true)} {
+		if _, err := NewSSEPubSubService(opt); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("Expected ErrInvalidOption, got %v", err)
+		}
+	}
+
+	ssePubSub := MustNewSSEPubSubService(WithCORS([]string{"https://bbb.example.com"}, true))
+	client := ssePubSub.NewClient()
+	srv := httptest.NewServer(ssePubSub)
+	defer srv.Close()
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer direct.Close()
+
+	// The Event handler sets the CORS headers, mounted directly or with ServeHTTP
+	for _, url := range []string{srv.URL + DefaultEventPath, direct.URL} {
+		req, err := http.NewRequest(http.MethodGet, url+"?client_id="+client.GetID(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", "https://bbb.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		h := resp.Header
+		if h.Get("Access-Control-Allow-Origin") != "https://bbb.example.com" || h.Get("Access-Control-Allow-Credentials") != "true" ||
+			len(h.Values("Vary")) != 1 {
+			t.Errorf("Unexpected CORS headers of %s: %v", url, h)
+		}
+	}
+
+	// Preflight
+	req, err := http.NewRequest(http.MethodOptions, srv.URL+DefaultEventPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://bbb.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Methods") != "GET" {
+		t.Errorf("Unexpected preflight response %d %v", resp.StatusCode, resp.Header)
+	}
+}
@@ -0,0 +1,62 @@
+package pubsubsse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidJSON is returned if already encoded data is not valid JSON.
+var ErrInvalidJSON = errors.New("data is not valid JSON")
+
+// MarshalData encodes a message as JSON for the data field of an update or sys event
+// Already encoded JSON, as json.RawMessage or []byte, is embedded verbatim instead of being encoded again.
+// All other values are encoded with json.Marshal.
+//
+// Migration: before, []byte was encoded as base64 string, e.g. {"data":"eyJhIjoxfQ=="}, and now it must be valid JSON.
+// To keep sending bytes as base64 string, convert them with base64.StdEncoding.EncodeToString first.
+// A JSON string, e.g. `{"a":1}` as Go string, is still sent as JSON string. Convert it to json.RawMessage to embed it.
+func MarshalData(v interface{}) (json.RawMessage, error) {
+	switch d := v.(type) {
+	case json.RawMessage:
+		return validJSON(d)
+	case []byte:
+		return validJSON(d)
+	}
+	return json.Marshal(v)
+}
+
+// Check already encoded JSON. Empty data is sent as null.
+func validJSON(d []byte) (json.RawMessage, error) {
+	if len(d) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	if !json.Valid(d) {
+		return nil, fmt.Errorf("%w: %.50s", ErrInvalidJSON, d)
+	}
+	return json.RawMessage(d), nil
+}
+
+// Build an update of a topic with the JSON encoded message
+func newUpdate(topicName string, msg interface{}) (eventDataUpdates, error) {
+	data, err := MarshalData(msg)
+	if err != nil {
+		return eventDataUpdates{}, err
+	}
+	return eventDataUpdates{Topic: topicName, Data: data, msg: msg}, nil
+}
+
+// encodedEvent is an event marshaled once, so it can be sent to many clients without encoding it again.
+type encodedEvent struct {
+	json  json.RawMessage // the event, e.g. for the tab frames
+	frame string          // the SSE frame of the event
+}
+
+// Marshal an event and build its SSE frame
+func encodeEvent(d *eventData) (*encodedEvent, error) {
+	jsonData, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return &encodedEvent{json: jsonData, frame: "data: " + string(jsonData) + "\n\n"}, nil
+}
@@ -0,0 +1,90 @@
+package pubsubsse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +MarshalData(v interface{}): json.RawMessage, error
+
+// TestMarshalData tests MarshalData()
+func TestMarshalData(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		v        interface{}
+		expected string
+	}{
+		{"raw message", json.RawMessage(`{"a":1}`), `{"a":1}`},
+		{"bytes", []byte(`[1,2]`), `[1,2]`},
+		{"empty bytes", []byte{}, `null`},
+		{"string", `{"a":1}`, `"{\"a\":1}"`},
+		{"struct", struct {
+			A int `json:"a"`
+		}{1}, `{"a":1}`},
+		{"nil", nil, `null`},
+	} {
+		data, err := MarshalData(tc.v)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if string(data) != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, data)
+		}
+	}
+
+	if _, err := MarshalData([]byte(`{"a":`)); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+}
+
+// TestPub_RawJSON tests that already encoded JSON is embedded verbatim in the SSE frame
+func TestPub_RawJSON(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(msg string) { frames <- msg })
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-frames // init message
+
+	if err := topic.Pub([]byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := topic.Pub(json.RawMessage(`{"b":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := topic.Pub([]byte(`{"a":`)); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+
+	for _, expected := range []string{
+		`data: {"sys":null,"updates":[{"topic":"test","data":{"a":1}}]}` + "\n\n",
+		`data: {"sys":null,"updates":[{"topic":"test","data":{"b":2}}]}` + "\n\n",
+	} {
+		select {
+		case frame := <-frames:
+			if frame != expected {
+				t.Errorf("Expected %q, got %q", expected, frame)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected a frame")
+		}
+	}
+}
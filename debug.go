@@ -0,0 +1,174 @@
+package pubsubsse
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time between two frames of the DebugHandler
+var debugInterval = time.Second
+
+// debugMetrics is a frame of the DebugHandler.
+type debugMetrics struct {
+	Clients         int               `json:"clients"`
+	Groups          int               `json:"groups"`
+	Connections     int               `json:"connections"`
+	MessagesSent    int64             `json:"messages_sent"`
+	MessagesDropped int64             `json:"messages_dropped"`
+	Topics          []debugTopicStats `json:"topics"`
+}
+
+// debugTopicStats are the subscriber counts of a public or group topic.
+type debugTopicStats struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Group       string `json:"group,omitempty"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// Collect the current metrics
+func (s *SSEPubSubService) debugMetrics() debugMetrics {
+	m := debugMetrics{
+		Groups:          s.GroupCount(),
+		MessagesSent:    s.messagesSent.Load(),
+		MessagesDropped: s.messagesDropped.Load(),
+		Topics:          []debugTopicStats{},
+	}
+
+	// Count clients and open event streams
+	for _, c := range s.GetClients() {
+		m.Clients++
+		c.lock.Lock()
+		m.Connections += len(c.connections)
+		c.lock.Unlock()
+	}
+
+	// Count the subscribers of public and group topics
+	s.ForEachPublicTopic(func(t *Topic) bool {
+		m.Topics = append(m.Topics, debugTopicStats{Name: t.GetName(), Type: string(t.GetTopicType()), Subscribers: len(t.GetClients())})
+		return true
+	})
+	s.ForEachGroup(func(g *Group) bool {
+		for _, t := range g.GetTopics() {
+			m.Topics = append(m.Topics, debugTopicStats{Name: t.GetName(), Type: string(t.GetTopicType()), Group: g.GetName(), Subscribers: len(t.GetClients())})
+		}
+		return true
+	})
+	sort.Slice(m.Topics, func(i, j int) bool {
+		if m.Topics[i].Group != m.Topics[j].Group {
+			return m.Topics[i].Group < m.Topics[j].Group
+		}
+		return m.Topics[i].Name < m.Topics[j].Name
+	})
+
+	return m
+}
+
+// Check the debug token of a request, either as bearer token or as token query parameter
+// Writes 401 and returns false if the token is missing or wrong, or if no debug token is set.
+func (s *SSEPubSubService) checkDebugToken(w http.ResponseWriter, r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		token = r.URL.Query().Get("token")
+	}
+	if s.debugToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.debugToken)) != 1 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "unauthorized"})
+		return false
+	}
+	return true
+}
+
+// DebugHandler streams the metrics of the sSEPubSubService as SSE to operator browsers
+// A metrics frame is sent every second. The token of WithDebugToken is required,
+// either as bearer token or, for the browser's EventSource, as token query parameter.
+// ServeHTTP serves it at DefaultDebugPath.
+// 0. Check the debug token
+// 1. Send a metrics frame every second until the connection is closed
+func (s *SSEPubSubService) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check the debug token
+		if !s.checkDebugToken(w, r) {
+			return
+		}
+
+		// SSE-specific headers
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		ticker := time.NewTicker(debugInterval)
+		defer ticker.Stop()
+
+		// Send a metrics frame every second until the connection is closed
+		for {
+			jsonData, err := json.Marshal(s.debugMetrics())
+			if err != nil {
+				s.logger.Errorf("Error marshalling debug metrics: %s", err)
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", jsonData)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Default number of entries returned by the HistoryHandler
+const defaultHistoryLimit = 50
+
+// HistoryHandler returns the history of a public or group topic as JSON array, newest first
+// ServeHTTP serves it at GET /topic/history?topic=<name>&limit=<n>, see DefaultTopicHistoryPath. limit defaults to 50.
+// The token of WithDebugToken is required, like for the DebugHandler.
+// 0. Check the debug token
+// 1. Get the topic and the limit
+// 2. Write the newest entries of the history
+func (s *SSEPubSubService) HistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check the debug token
+		if !s.checkDebugToken(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		// Get the topic and the limit
+		t, ok := s.getTopicByName(r.URL.Query().Get("topic"))
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "topic not found"})
+			return
+		}
+		limit := defaultHistoryLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "invalid limit"})
+				return
+			}
+			limit = n
+		}
+
+		// Write the newest entries of the history
+		entries := t.GetHistory()
+		if len(entries) > limit {
+			entries = entries[:limit]
+		}
+		json.NewEncoder(w).Encode(entries)
+	})
+}
@@ -0,0 +1,188 @@
+package pubsubsse
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +DebugHandler(): http.Handler
+// +HistoryHandler(): http.Handler
+// -routes of DebugHandler and HistoryHandler in ServeHTTP
+
+// Read the next metrics frame of the debug stream
+func readDebugFrame(t *testing.T, reader *bufio.Reader) debugMetrics {
+	t.Helper()
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader.ReadString('\n')
+
+	var m debugMetrics
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// TestDebugHandler tests the metrics stream of DebugHandler()
+func TestDebugHandler(t *testing.T) {
+	interval := debugInterval
+	debugInterval = 10 * time.Millisecond
+	defer func() { debugInterval = interval }()
+
+	ssePubSub := MustNewSSEPubSubService(WithDebugToken("secret"))
+	topic := ssePubSub.NewPublicTopic("test")
+
+	srv := httptest.NewServer(ssePubSub.DebugHandler())
+	defer srv.Close()
+
+	// The debug token is required
+	for _, token := range []string{"", "wrong"} {
+		resp := requestWithToken(t, srv.URL, token)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for token %q, got %d", token, resp.StatusCode)
+		}
+	}
+
+	resp := requestWithToken(t, srv.URL, "secret")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	m := readDebugFrame(t, reader)
+	if m.Clients != 0 || m.Groups != 0 || m.Connections != 0 {
+		t.Errorf("Expected no clients and groups, got %+v", m)
+	}
+
+	// A new group and a new client connection increment the counters
+	ssePubSub.NewGroup("group")
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	_, stop := startClient(t, client)
+	defer stop()
+	if err := topic.Pub("testdata"); err != nil {
+		t.Error(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for m.Connections != 1 || m.MessagesSent == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the new connection in the debug stream, got %+v", m)
+		}
+		m = readDebugFrame(t, reader)
+	}
+	if m.Clients != 1 {
+		t.Errorf("Expected 1 client, got %d", m.Clients)
+	}
+	if m.Groups != 1 {
+		t.Errorf("Expected 1 group, got %d", m.Groups)
+	}
+	if len(m.Topics) != 1 || m.Topics[0].Name != "test" || m.Topics[0].Subscribers != 1 {
+		t.Errorf("Expected 1 subscriber of topic test, got %+v", m.Topics)
+	}
+}
+
+// TestDebugHandler_Disabled tests that DebugHandler() rejects all requests without debug token
+func TestDebugHandler_Disabled(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	rec := httptest.NewRecorder()
+	ssePubSub.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug?token=", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+}
+
+// TestHistoryHandler tests the history endpoint of HistoryHandler()
+func TestHistoryHandler(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithDebugToken("secret"))
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(100, 100*time.Millisecond))
+
+	getHistory := func(query string) (*httptest.ResponseRecorder, []HistoryEntry) {
+		rec := httptest.NewRecorder()
+		ssePubSub.HistoryHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/topic/history?"+query, nil))
+		var entries []HistoryEntry
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, entries
+	}
+
+	// The debug token is required
+	if rec, _ := getHistory("topic=test&token=wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+	if rec, _ := getHistory("topic=missing&token=secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+	if rec, _ := getHistory("topic=test&limit=x&token=secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Code)
+	}
+
+	if err := topic.Pub("old"); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 60; i++ {
+		if err := topic.Pub(float64(i)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The default limit is 50, newest first, without messages older than the ttl
+	_, entries := getHistory("topic=test&token=secret")
+	if len(entries) != 50 || entries[0].Data != float64(59) || entries[49].Data != float64(10) {
+		t.Errorf("Expected the 50 newest messages, got %d", len(entries))
+	}
+
+	_, entries = getHistory("topic=test&limit=5&token=secret")
+	if len(entries) != 5 || entries[0].Data != float64(59) || entries[4].Data != float64(55) {
+		t.Errorf("Expected the 5 newest messages, got %+v", entries)
+	}
+
+	_, entries = getHistory("topic=test&limit=100&token=secret")
+	if len(entries) != 60 {
+		t.Errorf("Expected 60 messages without the expired one, got %d", len(entries))
+	}
+}
+
+// TestServeHTTP_DebugRoutes tests that ServeHTTP serves the DebugHandler and the HistoryHandler
+func TestServeHTTP_DebugRoutes(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithDebugToken("secret"), WithPath(EndpointDebug, "/ops/debug"))
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(10, time.Minute))
+	if err := topic.Pub("testdata"); err != nil {
+		t.Error(err)
+	}
+
+	rec := httptest.NewRecorder()
+	ssePubSub.ServeHTTP(rec, httptest.NewRequest("GET", DefaultTopicHistoryPath+"?topic=test&token=secret", nil))
+	var entries []HistoryEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); rec.Code != http.StatusOK || err != nil || len(entries) != 1 {
+		t.Errorf("Expected the history, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ssePubSub.ServeHTTP(rec, httptest.NewRequest("GET", "/ops/debug?token=wrong", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 from the DebugHandler, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	ssePubSub.ServeHTTP(rec, httptest.NewRequest("POST", DefaultTopicHistoryPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
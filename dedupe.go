@@ -0,0 +1,47 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithDedupeWindow enables PubDeduplicated on the topic
+// A message with the same key is delivered only once within d.
+func WithDedupeWindow(d time.Duration) TopicOption {
+	return func(t *Topic) {
+		if d <= 0 {
+			t.logger.Errorf("[T:%s]: Invalid dedupe window %s", t.name, d)
+			return
+		}
+		t.dedupeWindow = d
+	}
+}
+
+// Publish a message once per key within the dedupe window of the topic
+// Returns nil without delivering if a message with the same key was published within the window, see WithDedupeWindow.
+// After the window passed, a message with the key is delivered again. If the publish fails, the key is forgotten.
+func (t *Topic) PubDeduplicated(key string, msg interface{}) error {
+	if key == "" {
+		return fmt.Errorf("dedupe key must not be empty")
+	}
+
+	t.lock.Lock()
+	window := t.dedupeWindow
+	t.lock.Unlock()
+	if window <= 0 {
+		return fmt.Errorf("topic %s has no dedupe window", t.GetName())
+	}
+
+	// Remember the key, skip the message if it is already known
+	now := time.Now()
+	if !t.dedupeKeys.claim(key, now, window) {
+		return nil
+	}
+
+	// Publish the message
+	if err := t.Pub(msg); err != nil {
+		t.dedupeKeys.release(key, now)
+		return err
+	}
+	return nil
+}
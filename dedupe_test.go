@@ -0,0 +1,68 @@
+package pubsubsse
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +WithDedupeWindow(d time.Duration): TopicOption
+// Topic:
+// +PubDeduplicated(key string, msg interface{}): error
+
+// TestPubDeduplicated tests that a key is delivered once within the dedupe window of the topic
+func TestPubDeduplicated(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	plain := ssePubSub.NewPublicTopic("plain")
+	if err := plain.PubDeduplicated("key", "testdata"); err == nil {
+		t.Error("Expected error for a topic without dedupe window")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test", WithDedupeWindow(50*time.Millisecond))
+	r := newTopicRecorder()
+	topic.OnPub(r.record)
+	if err := topic.PubDeduplicated("", "testdata"); err == nil {
+		t.Error("Expected error for an empty key")
+	}
+
+	// Concurrent duplicates are delivered once, the first occurrence
+	if err := topic.PubDeduplicated("a", "first"); err != nil {
+		t.Error(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := topic.PubDeduplicated("a", "duplicate"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := topic.PubDeduplicated("b", "second"); err != nil {
+		t.Error(err)
+	}
+	msgs := r.Messages()
+	if len(msgs) != 2 || msgs[0].Data != "first" || msgs[1].Data != "second" {
+		t.Errorf("Expected the first and the second message, got %v", msgs)
+	}
+
+	// After the window the key is delivered again
+	time.Sleep(60 * time.Millisecond)
+	if err := topic.PubDeduplicated("a", "again"); err != nil {
+		t.Error(err)
+	}
+	if msgs := r.Messages(); len(msgs) != 3 || msgs[2].Data != "again" {
+		t.Errorf("Expected the message after the window, got %v", msgs)
+	}
+	if _, ok := topic.dedupeKeys.Load("b"); ok {
+		t.Error("Expected the expired key to be evicted")
+	}
+
+	// Keys of PubIdempotent are separate
+	if ok, err := ssePubSub.PubIdempotent("test", "a", "idempotent"); !ok || err != nil {
+		t.Errorf("Expected the idempotent publish, got %t, %v", ok, err)
+	}
+}
@@ -5,6 +5,8 @@ go 1.20
 require (
 	github.com/apex/log v1.9.0
 	github.com/google/uuid v1.4.0
+	go.uber.org/goleak v1.3.0
+	golang.org/x/time v0.8.0
 )
 
 require github.com/pkg/errors v0.8.1 // indirect
@@ -3,7 +3,6 @@ package pubsubsse
 import (
 	"sync"
 
-	"github.com/apex/log"
 	"github.com/google/uuid"
 )
 
@@ -13,16 +12,27 @@ type Group struct {
 	name string
 	id   string
 
-	lock *sync.Mutex
+	lock *sync.Mutex // see the lock ordering of SSEPubSubService
 
 	// Topics is a map of topic names to topics.
 	topics map[string]*Topic
 
 	// Clients is a map of client IDs to clients.
 	clients map[string]*Client
+
+	logger Logger
+
+	// OnPubError hooks of the sSEPubSubService, passed on to the topics of the group
+	pubErrors *hooks[PublishError]
+
+	// Events:
+	onNewClient        hooks[*Client]
+	onRemoveClient     hooks[*Client]
+	onNewGroupTopic    hooks[*Topic]
+	onRemoveGroupTopic hooks[*Topic]
 }
 
-func newGroup(name string) *Group {
+func newGroup(name string, logger Logger) *Group {
 	return &Group{
 		name: name,
 		id:   uuid.New().String(),
@@ -31,6 +41,8 @@ func newGroup(name string) *Group {
 
 		topics:  map[string]*Topic{},
 		clients: map[string]*Client{},
+
+		logger: logger,
 	}
 }
 
@@ -99,41 +111,67 @@ func (g *Group) GetClientByID(id string) (*Client, bool) {
 }
 
 // AddTopic adds a topic to the group.
+// The options are ignored if the topic already exists.
 // 1. Check if topic already exists, return it if it does
 // 2. Add the topic to the group
 // 3. Inform all clients about the new topic
-func (g *Group) NewTopic(name string) *Topic {
+func (g *Group) NewTopic(name string, opts ...TopicOption) *Topic {
 	// Check if the topic already exists and return it if it does
 	if t, ok := g.GetTopicByName(name); ok {
 		return t
 	}
 
-	// Create the topic
-	t := newTopic(name, TGroup)
+	// Create the topic. If it was created concurrently, return the existing one.
+	t, _ := g.addTopic(newTopic(name, TGroup, g.logger, opts...))
+
+	return t
+}
+
+// Add a topic to the group and inform all clients about the new topic
+// If a topic with the same name already exists, it is returned with false.
+func (g *Group) addTopic(t *Topic) (*Topic, bool) {
 	g.lock.Lock()
-	g.topics[name] = t
+	if existing, ok := g.topics[t.GetName()]; ok {
+		g.lock.Unlock()
+		return existing, false
+	}
+	t.pubErrors = g.pubErrors
+	g.topics[t.GetName()] = t
 	g.lock.Unlock()
 
+	// Emit event
+	g.onNewGroupTopic.emit(t)
+
 	// Inform all clients about the new topic
+	// Clients with a group subscription are subscribed to the new topic, see Client.SubGroup.
+	name := g.GetName()
 	for _, c := range g.GetClients() {
 		if err := c.sendTopicList(); err != nil {
-			log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+			g.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+		}
+		c.onNewTopic.emit(t)
+		c.onNewGroupTopic.emit(t)
+
+		if c.hasGroupSub(name) && !t.IsWriteOnly() {
+			if err := c.Sub(t); err != nil {
+				g.logger.Errorf("[C:%s]: Error subscribing client to new group topic: %s", c.id, err)
+			}
 		}
 	}
 
-	return t
+	return t, true
 }
 
 // RemoveTopic removes a topic from the group.
 // 0. Check if topic is a group topic
 // 1. Check if topic exists in the group
-// 2. Unsuscribe all clients from the topic
-// 3. Remove topic from the group
+// 2. Emit the remove event and unsuscribe all clients from the topic
+// 3. Remove topic from the group and cancel its scheduled publishes
 // 4. Inform all clients about the removed topic
 func (g *Group) RemoveTopic(t *Topic) {
 	// Check if topic is a group topic
-	if t.GetType() != string(TGroup) {
-		log.Errorf("topic is not a group topic")
+	if t.GetTopicType() != TopicTypeGroup {
+		g.logger.Errorf("topic is not a group topic")
 	}
 
 	// Check if topic exists in sSEPubSubService
@@ -146,14 +184,20 @@ func (g *Group) RemoveTopic(t *Topic) {
 		return false
 	}
 	if !checkIfExist() {
-		log.Errorf("Topic %s does not exist in group", t.GetName())
+		g.logger.Errorf("Topic %s does not exist in group", t.GetName())
 		return
 	}
 
+	// Emit event while the topic still has all its clients
+	g.onRemoveGroupTopic.emit(t)
+
+	// The topic is removed anyway, so it must not remove itself when the last client leaves
+	t.stopAutoDelete()
+
 	// Unsuscribe all clients from the topic
 	for _, c := range t.GetClients() {
 		if err := c.Unsub(t); err != nil {
-			log.Errorf("[C:%s]: Error unsuscribing client from topic: %s", c.id, err)
+			g.logger.Errorf("[C:%s]: Error unsuscribing client from topic: %s", c.id, err)
 		}
 	}
 
@@ -162,11 +206,15 @@ func (g *Group) RemoveTopic(t *Topic) {
 	delete(g.topics, t.GetName())
 	g.lock.Unlock()
 
+	// Cancel all pending scheduled publishes
+	t.CancelAllScheduled()
+
 	// Inform all clients about the removed topic
 	for _, c := range g.GetClients() {
 		if err := c.sendTopicList(); err != nil {
-			log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+			g.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
 		}
+		c.onRemoveTopic.emit(t)
 	}
 }
 
@@ -177,7 +225,7 @@ func (g *Group) RemoveTopic(t *Topic) {
 func (g *Group) AddClient(c *Client) {
 	// Check if client already exists in the group
 	if _, ok := g.GetClientByID(c.GetID()); ok {
-		log.Errorf("Client already exists in group")
+		g.logger.Errorf("Client already exists in group")
 		return
 	}
 
@@ -191,27 +239,40 @@ func (g *Group) AddClient(c *Client) {
 
 	// Inform client about the new topic
 	if err := c.sendTopicList(); err != nil {
-		log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+		g.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+	}
+
+	// Emit events
+	g.onNewClient.emit(c)
+	c.onNewGroup.emit(g)
+	for _, t := range g.GetTopics() {
+		c.onNewTopic.emit(t)
+		c.onNewGroupTopic.emit(t)
 	}
 }
 
 // RemoveClient removes a client from the group.
 // 0. Check if client exists in the group
-// 1. Unsubscribe client from all group topics
-// 2. Remove client from the group
-// 3. Remove group from client
-// 4. Inform client about the removed topic
+// 1. Emit the remove events
+// 2. Unsubscribe client from all group topics
+// 3. Remove client from the group
+// 4. Remove group from client
+// 5. Inform client about the removed topic
 func (g *Group) RemoveClient(c *Client) {
 	// Check if client exists in the group
 	if _, ok := g.GetClientByID(c.GetID()); !ok {
-		log.Errorf("Client does not exist in group")
+		g.logger.Errorf("Client does not exist in group")
 		return
 	}
 
+	// Emit events while the client is still in the group
+	g.onRemoveClient.emit(c)
+	c.onRemoveGroup.emit(g)
+
 	// Unsubscribe client from all group topics
 	for _, t := range g.GetTopics() {
 		if err := c.Unsub(t); err != nil {
-			log.Errorf("[C:%s]: Error unsuscribing client from topic: %s", c.id, err)
+			g.logger.Errorf("[C:%s]: Error unsuscribing client from topic: %s", c.id, err)
 		}
 	}
 
@@ -225,6 +286,53 @@ func (g *Group) RemoveClient(c *Client) {
 
 	// Inform client about the removed topic
 	if err := c.sendTopicList(); err != nil {
-		log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+		g.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
 	}
+
+	// Emit events
+	for _, t := range g.GetTopics() {
+		c.onRemoveTopic.emit(t)
+	}
+}
+
+// Event: When client is added to the group
+func (g *Group) OnNewClient(f funcClient) string {
+	return g.onNewClient.add(f)
+}
+
+// Remove Event: When client is added to the group
+func (g *Group) RemoveOnNewClient(id string) {
+	g.onNewClient.remove(id)
+}
+
+// Event: When client is removed from the group
+// Called before the client is removed, so it is still in the group.
+func (g *Group) OnRemoveClient(f funcClient) string {
+	return g.onRemoveClient.add(f)
+}
+
+// Remove Event: When client is removed from the group
+func (g *Group) RemoveOnRemoveClient(id string) {
+	g.onRemoveClient.remove(id)
+}
+
+// Event: When a topic is added to the group
+func (g *Group) OnNewGroupTopic(f funcTopic) string {
+	return g.onNewGroupTopic.add(f)
+}
+
+// Remove Event: When a topic is added to the group
+func (g *Group) RemoveOnNewGroupTopic(id string) {
+	g.onNewGroupTopic.remove(id)
+}
+
+// Event: When a topic is removed from the group
+// Called before the clients are unsubscribed, so the topic still has all its clients.
+func (g *Group) OnRemoveGroupTopic(f funcTopic) string {
+	return g.onRemoveGroupTopic.add(f)
+}
+
+// Remove Event: When a topic is removed from the group
+func (g *Group) RemoveOnRemoveGroupTopic(id string) {
+	g.onRemoveGroupTopic.remove(id)
 }
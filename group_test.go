@@ -18,7 +18,7 @@ import (
 
 // TestGroup_NewGroup tests the NewGroup function
 func TestGroup_NewGroup(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if g == nil {
 		t.Error("NewGroup returned nil")
@@ -27,7 +27,7 @@ func TestGroup_NewGroup(t *testing.T) {
 
 // TestGroup_GetName tests the GetName function
 func TestGroup_GetName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if g.GetName() != "test" {
 		t.Error("GetName returned the wrong name")
@@ -36,7 +36,7 @@ func TestGroup_GetName(t *testing.T) {
 
 // TestGroup_GetID tests the GetID function
 func TestGroup_GetID(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if g.GetID() == "" {
 		t.Error("GetID returned an empty string")
@@ -45,7 +45,7 @@ func TestGroup_GetID(t *testing.T) {
 
 // TestGroup_GetTopics tests the GetTopics function
 func TestGroup_GetTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if len(g.GetTopics()) > 0 {
 		t.Error("GetTopics returned a non-empty map")
@@ -63,7 +63,7 @@ func TestGroup_GetTopics(t *testing.T) {
 
 // TestGroup_GetTopicByName tests the GetTopicByName function
 func TestGroup_GetTopicByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if _, ok := g.GetTopicByName("test"); ok {
 		t.Error("GetTopicByName returned true for a non-existent topic")
@@ -81,7 +81,7 @@ func TestGroup_GetTopicByName(t *testing.T) {
 
 // TestGroup_GetClients tests the GetClients function
 func TestGroup_GetClients(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if len(g.GetClients()) > 0 {
 		t.Error("GetClients returned a non-empty map")
@@ -104,7 +104,7 @@ func TestGroup_GetClients(t *testing.T) {
 
 // TestGroup_GetClientByID tests the GetClientByID function
 func TestGroup_GetClientByID(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if _, ok := g.GetClientByID("test"); ok {
 		t.Error("GetClientByID returned true for a non-existent client")
@@ -123,7 +123,7 @@ func TestGroup_GetClientByID(t *testing.T) {
 
 // TestGroup_NewTopic tests the NewTopic function
 func TestGroup_NewTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	topic := g.NewTopic("test")
 	if topic == nil {
@@ -136,7 +136,7 @@ func TestGroup_NewTopic(t *testing.T) {
 
 // TestGroup_RemoveTopic tests the RemoveTopic function
 func TestGroup_RemoveTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	topic := g.NewTopic("test")
 	if len(g.GetTopics()) != 1 {
@@ -151,7 +151,7 @@ func TestGroup_RemoveTopic(t *testing.T) {
 
 // TestGroup_AddClient tests the AddClient function
 func TestGroup_AddClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	if len(g.GetClients()) > 0 {
 		t.Error("GetClients returned a non-empty map")
@@ -166,7 +166,7 @@ func TestGroup_AddClient(t *testing.T) {
 
 // TestGroup_RemoveClient tests the RemoveClient function
 func TestGroup_RemoveClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	g := ssePubSub.NewGroup("test")
 	client := ssePubSub.NewClient()
 	g.AddClient(client)
@@ -1,19 +1,67 @@
 package pubsubsse
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-
-	"github.com/apex/log"
+	"strings"
 )
 
+// authenticate validates the bearer token of the request with the auth validator of the sSEPubSubService.
+// ok is false if no auth validator is set. If one is set, a request without bearer token is an error.
+func authenticate(s *SSEPubSubService, r *http.Request) (clientID, token string, ok bool, err error) {
+	validator := s.getAuthValidator()
+	if validator == nil {
+		return "", "", false, nil
+	}
+
+	// Get the bearer token
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return "", "", false, errors.New("missing bearer token")
+	}
+
+	clientID, err = validator(token)
+	if err != nil {
+		return "", "", false, err
+	}
+	return clientID, token, true, nil
+}
+
 // AddClient handles HTTP requests for adding a new client.
+// If the request has a valid bearer token of an existing client, that client is returned.
 func AddClient(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Create a new client
-	c := s.NewClient()
+	// Validate the bearer token
+	clientID, token, authenticated, err := authenticate(s, r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "unauthorized"})
+		return
+	}
+
+	// Get the client of the token or create a new client. The client of a token gets the ID of the token,
+	// so Event finds it with the same token.
+	c, ok := s.GetClientByID(clientID)
+	if !authenticated {
+		c = s.NewClient()
+	} else if !ok {
+		if c, err = s.NewClientWithOptions(clientID, ClientOptions{}); err != nil {
+			// Created concurrently by another request with the same token
+			if c, ok = s.GetClientByID(clientID); !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": err.Error()})
+				return
+			}
+		}
+	}
+	if authenticated {
+		c.SetAuthToken(token)
+	}
 
 	// Send the client ID
 
@@ -34,6 +82,33 @@ func AddPublicTopic(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"ok": "true", "topic_name": t.GetName()})
 }
 
+// topicListItem is an entry of the ListTopics response.
+type topicListItem struct {
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// ListTopics handles HTTP requests for listing the public topics, sorted by name.
+func ListTopics(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Build the list of public topics
+	list := []topicListItem{}
+	for _, t := range s.ListPublicTopics() {
+		list = append(list, topicListItem{
+			Name:        t.GetName(),
+			ID:          t.GetID(),
+			Type:        string(t.GetTopicType()),
+			Subscribers: len(t.GetClients()),
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
 // AddPrivateTopic handles HTTP requests for adding a new private topic.
 func AddPrivateTopic(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -85,7 +160,12 @@ func Subscribe(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 
 	// Subscribe to the topic
 	if err := client.Sub(t); err != nil {
-		log.Errorf("Error subscribing to topic %s: %s", topic, err)
+		if errors.Is(err, ErrTopicFull) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "topic is full"})
+			return
+		}
+		s.logger.Errorf("Error subscribing to topic %s: %s", topic, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "internal server error"})
 		return
@@ -121,7 +201,7 @@ func Unsubscribe(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 
 	// Unsubscribe from the topic
 	if err := client.Unsub(t); err != nil {
-		log.Errorf("Error unsubscribing from topic %s: %s", topic, err)
+		s.logger.Errorf("Error unsubscribing from topic %s: %s", topic, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "internal server error"})
 		return
@@ -131,13 +211,84 @@ func Unsubscribe(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"ok": "true"})
 }
 
+// Maximum size of the request body of PubToClient
+const maxPubBodySize = 1 << 20
+
+// PubToClient handles HTTP requests for publishing a message to one client.
+// The JSON request body is sent as data of an update of the topic query parameter.
+func PubToClient(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// GET clientID and topic from request
+	clientID := r.URL.Query().Get("client_id")
+	topic := r.URL.Query().Get("topic")
+	if topic == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "topic is missing"})
+		return
+	}
+
+	// Read the data from the request body
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPubBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "invalid body"})
+		return
+	}
+
+	// Publish the data to the client
+	if err := s.PubToClient(clientID, topic, json.RawMessage(body)); err != nil {
+		switch {
+		case errors.Is(err, ErrClientNotFound):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "client not found"})
+		case errors.Is(err, ErrInvalidJSON):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "invalid json"})
+		default:
+			s.logger.Errorf("Error publishing to client %s: %s", clientID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "internal server error"})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"ok": "true"})
+}
+
+// acceptsGzip reports if the request accepts a gzip encoded response.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(enc) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
 // Event
 func Event(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	// Allow cross-origin EventSources, see WithCORS
+	s.addCORSHeaders(w, r)
+
 	// GET clientID and topic from request body
 	clientID := r.URL.Query().Get("client_id")
 
+	// Validate the bearer token. The client ID of the token overrides the query parameter.
+	tokenClientID, token, authenticated, err := authenticate(s, r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "unauthorized"})
+		return
+	}
+	if authenticated {
+		clientID = tokenClientID
+	}
+
 	// Get the client
 	client, ok := s.GetClientByID(clientID)
 	if !ok {
@@ -145,30 +296,200 @@ func Event(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "client not found"})
 		return
 	}
+	if authenticated {
+		client.SetAuthToken(token)
+	}
 
-	// Test if client is already receiving
-	if client.GetStatus() == Receving {
-		w.WriteHeader(http.StatusBadRequest)
-
-		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "client is already receiving"})
+	// No new event streams after Close
+	if s.IsClosed() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "server closing"})
 		return
 	}
 
+	// Only the primary tab of a browser keeps an event stream, see TabManager
+	if cookie, err := r.Cookie(BrowserIDCookie); err == nil && cookie.Value != "" {
+		browserID := cookie.Value
+		if primary, ok := s.tabs.GetPrimary(browserID); ok && primary != client && primary.GetStatus() == Receving {
+			s.tabs.AddTab(browserID, client)
+
+			// The tab receives its init message over the primary tab. 204 stops the EventSource of the tab from reconnecting.
+			if err := client.send(client.initData()); err != nil {
+				s.logger.Errorf("[C:%s]: Error sending init message to tab: %s", client.GetID(), err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.tabs.SetPrimary(browserID, client)
+	}
+
 	// SSE-specific headers
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
+	// Compress the event stream if enabled and accepted by the browser
+	var out io.Writer = w
+	var gz *gzip.Writer
+	if s.compression && acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Vary", "Accept-Encoding")
+		gz = gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+
+	// Write a frame and flush it to the browser
+	// The gzip writer has to be flushed first, otherwise the frame stays in its buffer.
+	write := func(frame string) {
+		fmt.Fprintf(out, "%s", frame)
+		if gz != nil {
+			gz.Flush()
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	// Tell the browser how long to wait before reconnecting
+	if s.retryHint > 0 {
+		write(fmt.Sprintf("retry: %d\n\n", s.retryHint.Milliseconds()))
+	}
+
 	// Get the request's context. If the connection closes, the context will be canceled.
 	ctx := r.Context()
 
-	// Keep the connection open until it's closed by the client or client is removed
+	// Keep the connection open until it's closed by the client or client is removed.
+	// Every connection of the same client, e.g. browser tabs, receives all messages.
 	// OnEvent: Send message to client if new data is published
-	client.Start(ctx, func(msg string) {
-		fmt.Fprintf(w, "%s", msg)
-		if f, ok := w.(http.Flusher); ok {
-			f.Flush()
+	client.Start(ctx, write)
+}
+
+// Endpoint identifies an endpoint of ServeHTTP.
+type Endpoint string
+
+// Endpoints of ServeHTTP
+const (
+	EndpointAddClient       Endpoint = "add_client"
+	EndpointAddPublicTopic  Endpoint = "add_public_topic"
+	EndpointAddPrivateTopic Endpoint = "add_private_topic"
+	EndpointListTopics      Endpoint = "list_topics"
+	EndpointSubscribe       Endpoint = "subscribe"
+	EndpointUnsubscribe     Endpoint = "unsubscribe"
+	EndpointEvent           Endpoint = "event"
+	EndpointPubToClient     Endpoint = "pub_to_client"
+	EndpointTopicHistory    Endpoint = "topic_history"
+	EndpointDebug           Endpoint = "debug"
+)
+
+// Default paths of the ServeHTTP endpoints. They can be changed with WithPath.
+const (
+	DefaultAddClientPath       = "/client"
+	DefaultAddPublicTopicPath  = "/topic/public"
+	DefaultAddPrivateTopicPath = "/topic/private"
+	DefaultListTopicsPath      = "/topics"
+	DefaultSubscribePath       = "/sub"
+	DefaultUnsubscribePath     = "/unsub"
+	DefaultEventPath           = "/event"
+	DefaultPubToClientPath     = "/pub/client"
+	DefaultTopicHistoryPath    = "/topic/history"
+	DefaultDebugPath           = "/debug"
+)
+
+// route is an endpoint of ServeHTTP.
+type route struct {
+	endpoint Endpoint
+	path     string
+	methods  []string
+	handle   func(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+}
+
+// Check if the method is allowed for the route
+func (rt route) allows(method string) bool {
+	for _, m := range rt.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// All endpoints of ServeHTTP. A new endpoint only has to be added here.
+var routes = []route{
+	{EndpointAddClient, DefaultAddClientPath, []string{http.MethodGet, http.MethodPost}, AddClient},
+	{EndpointAddPublicTopic, DefaultAddPublicTopicPath, []string{http.MethodPost}, AddPublicTopic},
+	{EndpointAddPrivateTopic, DefaultAddPrivateTopicPath, []string{http.MethodPost}, AddPrivateTopic},
+	{EndpointListTopics, DefaultListTopicsPath, []string{http.MethodGet}, ListTopics},
+	{EndpointSubscribe, DefaultSubscribePath, []string{http.MethodPost}, Subscribe},
+	{EndpointUnsubscribe, DefaultUnsubscribePath, []string{http.MethodPost}, Unsubscribe},
+	{EndpointEvent, DefaultEventPath, []string{http.MethodGet}, Event},
+	{EndpointPubToClient, DefaultPubToClientPath, []string{http.MethodPost}, PubToClient},
+	{EndpointTopicHistory, DefaultTopicHistoryPath, []string{http.MethodGet}, func(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
+		s.HistoryHandler().ServeHTTP(w, r)
+	}},
+	{EndpointDebug, DefaultDebugPath, []string{http.MethodGet}, func(s *SSEPubSubService, w http.ResponseWriter, r *http.Request) {
+		s.DebugHandler().ServeHTTP(w, r)
+	}},
+}
+
+// WithPath changes the path of an endpoint of ServeHTTP.
+// The path has to start with / and must not be used by another endpoint.
+func WithPath(endpoint Endpoint, path string) Option {
+	return func(s *SSEPubSubService) {
+		s.paths[endpoint] = path
+	}
+}
+
+// Check if the endpoint is served by ServeHTTP
+func knownEndpoint(endpoint Endpoint) bool {
+	for _, rt := range routes {
+		if rt.endpoint == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the path of a route, either changed with WithPath or the default path
+func (s *SSEPubSubService) routePath(rt route) string {
+	if path, ok := s.paths[rt.endpoint]; ok {
+		return path
+	}
+	return rt.path
+}
+
+// ServeHTTP routes the request to the endpoint of its path, so the sSEPubSubService can be mounted as http.Handler.
+// To mount it below a prefix, strip the prefix, e.g. mux.Handle("/sse/", http.StripPrefix("/sse", ssePubSub)).
+// 0. Find the route of the path, write 404 if there is none
+// 1. Add the CORS headers and answer preflight requests, see SetCORS
+// 2. Check the method, write 405 if it is not allowed
+// 3. Call the handler of the route
+func (s *SSEPubSubService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rt := range routes {
+		if s.routePath(rt) != r.URL.Path {
+			continue
 		}
-	})
+
+		// Add the CORS headers and answer preflight requests
+		if s.handleCORS(w, r, rt) {
+			return
+		}
+
+		// Check the method
+		if !rt.allows(r.Method) {
+			w.Header().Set("Allow", strings.Join(rt.methods, ", "))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "method not allowed"})
+			return
+		}
+
+		rt.handle(s, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"ok": "false", "error": "not found"})
 }
@@ -0,0 +1,621 @@
+package pubsubsse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +AddClient(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+// +ListTopics(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+// +Subscribe(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+// +Unsubscribe(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+// +Event(s *SSEPubSubService, w http.ResponseWriter, r *http.Request)
+// +WithPath(endpoint Endpoint, path string): Option
+// SSEPubSubService:
+// +ServeHTTP(w http.ResponseWriter, r *http.Request)
+
+// Make a request with an optional bearer token
+func requestWithToken(t *testing.T, url, token string) *http.Response {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// TestEvent_RetryHint tests that the retry field is the first frame of the event stream.
+func TestEvent_RetryHint(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithRetryHint(3 * time.Second))
+	client := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/event?client_id=" + client.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "retry: 3000\n" {
+		t.Errorf("Expected retry line, got %q", line)
+	}
+}
+
+// TestAuthValidator tests the bearer token authentication of the AddClient and Event handlers.
+func TestAuthValidator(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	ssePubSub.SetAuthValidator(func(token string) (string, error) {
+		switch token {
+		case "valid":
+			return client.GetID(), nil
+		case "new":
+			return "unknown", nil
+		}
+		return "", errors.New("token expired")
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/add/user", func(w http.ResponseWriter, r *http.Request) { AddClient(ssePubSub, w, r) })
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Expired token
+	resp := requestWithToken(t, srv.URL+"/add/user", "expired")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for expired token, got %d", resp.StatusCode)
+	}
+	resp = requestWithToken(t, srv.URL+"/event?client_id="+client.GetID(), "expired")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for expired token, got %d", resp.StatusCode)
+	}
+
+	// Missing header: the client_id query parameter does not skip the authentication
+	clients := len(ssePubSub.GetClients())
+	resp = requestWithToken(t, srv.URL+"/add/user", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+	if len(ssePubSub.GetClients()) != clients {
+		t.Error("Expected no new client without token")
+	}
+	resp = requestWithToken(t, srv.URL+"/event?client_id="+client.GetID(), "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+	if client.GetStatus() != Waiting {
+		t.Error("Expected no event stream without token")
+	}
+
+	// Valid token of an unknown client: a new client is created with the token
+	resp = requestWithToken(t, srv.URL+"/add/user", "new")
+	body := map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	newClient, ok := ssePubSub.GetClientByID(body["client_id"])
+	if !ok || newClient.GetAuthToken() != "new" {
+		t.Error("Expected a new client with the token")
+	}
+
+	// Valid token: the existing client is returned
+	resp = requestWithToken(t, srv.URL+"/add/user", "valid")
+	body = map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if body["client_id"] != client.GetID() {
+		t.Error("Expected the client of the token")
+	}
+	if client.GetAuthToken() != "valid" {
+		t.Error("Expected the token to be stored on the client")
+	}
+
+	// Valid token: the client ID of the token overrides the query parameter
+	client.SetAuthToken("")
+	resp = requestWithToken(t, srv.URL+"/event?client_id=wrong", "valid")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for valid token, got %d", resp.StatusCode)
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Error(err)
+	}
+	if client.GetAuthToken() != "valid" {
+		t.Error("Expected the token to be stored on the client")
+	}
+}
+
+// TestAuthValidator_NewClient tests that the client created by AddClient for a token opens its stream with the same token.
+func TestAuthValidator_NewClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.SetAuthValidator(func(token string) (string, error) {
+		if token == "new" {
+			return "user42", nil
+		}
+		return "", errors.New("token expired")
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/add/user", func(w http.ResponseWriter, r *http.Request) { AddClient(ssePubSub, w, r) })
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// The client gets the ID of the token
+	resp := requestWithToken(t, srv.URL+"/add/user", "new")
+	body := map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if body["client_id"] != "user42" {
+		t.Errorf("Expected the client ID of the token, got %q", body["client_id"])
+	}
+
+	// The stream opens with the same token
+	resp = requestWithToken(t, srv.URL+"/event", "new")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for the new client, got %d", resp.StatusCode)
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Error(err)
+	}
+	client, ok := ssePubSub.GetClientByID("user42")
+	if !ok || client.GetStatus() != Receving {
+		t.Error("Expected the client of the token to receive")
+	}
+}
+
+// TestEvent_MultipleConnections tests that every connection of a client receives all messages.
+func TestEvent_MultipleConnections(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	client.Sub(topic)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	// Open two connections, e.g. two browser tabs
+	readers := []*bufio.Reader{}
+	for i := 0; i < 2; i++ {
+		resp := requestWithToken(t, srv.URL+"/event?client_id="+client.GetID(), "")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 for connection %d, got %d", i, resp.StatusCode)
+		}
+		reader := bufio.NewReader(resp.Body)
+
+		// Read the init message
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		reader.ReadString('\n')
+		readers = append(readers, reader)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := topic.Pub(float64(i)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for n, reader := range readers {
+		for i := 0; i < 3; i++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader.ReadString('\n')
+
+			var d eventData
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &d); err != nil {
+				t.Fatal(err)
+			}
+			if len(d.Updates) != 1 || decodeData(d.Updates[0].Data) != float64(i) {
+				t.Errorf("Connection %d: expected message %d, got %s", n, i, line)
+			}
+		}
+	}
+}
+
+// TestEvent_Compression tests the gzip compression of the event stream.
+func TestEvent_Compression(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithCompression(true))
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	client.Sub(topic)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	// Without Accept-Encoding the stream is not compressed
+	hclient := http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := hclient.Get(srv.URL + "/event?client_id=" + client.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Error("Expected no compression without Accept-Encoding")
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Error(err)
+	}
+	resp.Body.Close()
+
+	// With Accept-Encoding: gzip every frame can be decoded as soon as it is sent
+	req, err := http.NewRequest("GET", srv.URL+"/event?client_id="+client.GetID(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("Expected gzip compression")
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(gz)
+
+	// Read the init message
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	reader.ReadString('\n')
+
+	// Publish a large payload
+	payload := strings.Repeat("slide data ", 1000)
+	if err := topic.Pub(payload); err != nil {
+		t.Error(err)
+	}
+	u, err := newUpdate("test", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(&eventData{Updates: []eventDataUpdates{u}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "data: "+string(expected)+"\n" {
+		t.Errorf("Expected the decoded frame to match the JSON, got %s", line)
+	}
+}
+
+// TestPubToClient tests the PubToClient handler
+func TestPubToClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	events, stop := startClient(t, client)
+
+	for _, tc := range []struct {
+		url      string
+		body     string
+		expected int
+	}{
+		{"/pub/client?client_id=" + client.GetID() + "&topic=test", `{"a":1}`, http.StatusOK},
+		{"/pub/client?client_id=unknown&topic=test", `{"a":1}`, http.StatusBadRequest},
+		{"/pub/client?client_id=" + client.GetID(), `{"a":1}`, http.StatusBadRequest},
+		{"/pub/client?client_id=" + client.GetID() + "&topic=test", `{"a":`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		PubToClient(ssePubSub, rec, httptest.NewRequest("POST", tc.url, strings.NewReader(tc.body)))
+		if rec.Code != tc.expected {
+			t.Errorf("%s %s: expected %d, got %d", tc.url, tc.body, tc.expected, rec.Code)
+		}
+	}
+	stop()
+
+	data := updatesData(events())
+	if len(data) != 1 || data[0].(map[string]interface{})["a"] != float64(1) {
+		t.Errorf("Expected the body as data, got %v", data)
+	}
+}
+
+// TestSubscribe_TopicFull tests that the Subscribe handler rejects subscribers of a full topic with 429
+func TestSubscribe_TopicFull(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("room")
+	topic.SetMaxSubscribers(1)
+
+	for i, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		client := ssePubSub.NewClient()
+		rec := httptest.NewRecorder()
+		Subscribe(ssePubSub, rec, httptest.NewRequest("POST", "/sub?topic=room&client_id="+client.GetID(), nil))
+		if rec.Code != expected {
+			t.Errorf("Subscriber %d: expected %d, got %d", i+1, expected, rec.Code)
+		}
+	}
+}
+
+// TestListTopics tests the ListTopics handler
+func TestListTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topicB := ssePubSub.NewPublicTopic("b")
+	ssePubSub.NewPublicTopic("a")
+	ssePubSub.NewGroup("group").NewTopic("grouptopic") // not public
+	if err := ssePubSub.NewClient().Sub(topicB); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	ListTopics(ssePubSub, rec, httptest.NewRequest("GET", "/topics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var list []topicListItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	expected := []topicListItem{
+		{Name: "a", Type: "public", Subscribers: 0},
+		{Name: "b", ID: topicB.GetID(), Type: "public", Subscribers: 1},
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 topics, got %+v", list)
+	}
+	list[0].ID = ""
+	for i := range expected {
+		if list[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], list[i])
+		}
+	}
+}
+
+// TestServeHTTP tests all endpoints of ServeHTTP with the handler mounted at /sse/
+func TestServeHTTP(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithPath(EndpointListTopics, "/topics/public"))
+
+	mux := http.NewServeMux()
+	mux.Handle("/sse/", http.StripPrefix("/sse", ssePubSub))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Make a request and decode the JSON response
+	do := func(method, path string, expectedStatus int, body interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+"/sse"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("%s %s: expected %d, got %d", method, path, expectedStatus, resp.StatusCode)
+		}
+		if body != nil {
+			if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+				t.Errorf("%s %s: %s", method, path, err)
+			}
+		}
+	}
+
+	// Add client
+	added := map[string]string{}
+	do("POST", DefaultAddClientPath, http.StatusOK, &added)
+	client, ok := ssePubSub.GetClientByID(added["client_id"])
+	if !ok {
+		t.Fatalf("Expected the client to be created, got %v", added)
+	}
+
+	// Add topics
+	do("POST", DefaultAddPublicTopicPath+"?topic=public", http.StatusOK, nil)
+	if _, ok := ssePubSub.GetPublicTopicByName("public"); !ok {
+		t.Error("Expected the public topic to be created")
+	}
+	do("POST", DefaultAddPrivateTopicPath+"?topic=private&client_id="+client.GetID(), http.StatusOK, nil)
+	if _, ok := client.GetPrivateTopicByName("private"); !ok {
+		t.Error("Expected the private topic to be created")
+	}
+
+	// List topics at the changed path
+	list := []topicListItem{}
+	do("GET", "/topics/public", http.StatusOK, &list)
+	if len(list) != 1 || list[0].Name != "public" {
+		t.Errorf("Expected the public topic, got %+v", list)
+	}
+	do("GET", DefaultListTopicsPath, http.StatusNotFound, nil)
+
+	// Subscribe and unsubscribe
+	topic, _ := ssePubSub.GetPublicTopicByName("public")
+	do("POST", DefaultSubscribePath+"?topic=public&client_id="+client.GetID(), http.StatusOK, nil)
+	if !topic.IsSubscribed(client) {
+		t.Error("Expected the client to be subscribed")
+	}
+	do("POST", DefaultUnsubscribePath+"?topic=public&client_id="+client.GetID(), http.StatusOK, nil)
+	if topic.IsSubscribed(client) {
+		t.Error("Expected the client to be unsubscribed")
+	}
+
+	// Publish to an unknown client
+	do("POST", DefaultPubToClientPath+"?topic=public&client_id=unknown", http.StatusBadRequest, nil)
+
+	// Wrong method and unknown path
+	do("GET", DefaultSubscribePath, http.StatusMethodNotAllowed, nil)
+	do("GET", "/unknown", http.StatusNotFound, nil)
+
+	// Event stream
+	resp := requestWithToken(t, srv.URL+"/sse"+DefaultEventPath+"?client_id="+client.GetID(), "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for the event stream, got %d", resp.StatusCode)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "data: ") {
+		t.Errorf("Expected the init message, got %q", line)
+	}
+}
+
+// Read exactly n SSE frames from the event stream, fails the test after timeout
+func readFrames(t *testing.T, reader *bufio.Reader, n int, timeout time.Duration) []eventData {
+	t.Helper()
+
+	type result struct {
+		frames []eventData
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		frames := []eventData{}
+		for len(frames) < n {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				done <- result{frames, err}
+				return
+			}
+			// Frames are separated by an empty line. Other fields, e.g. retry, are skipped.
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+			if !ok {
+				continue
+			}
+			var d eventData
+			if err := json.Unmarshal([]byte(data), &d); err != nil {
+				done <- result{frames, err}
+				return
+			}
+			frames = append(frames, d)
+		}
+		done <- result{frames, nil}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Expected %d frames, got %d: %s", n, len(r.frames), r.err)
+		}
+		return r.frames
+	case <-time.After(timeout):
+		t.Fatalf("Expected %d frames within %s", n, timeout)
+	}
+	return nil
+}
+
+// TestLifecycle tests the full SSE lifecycle of a client over HTTP
+func TestLifecycle(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("news")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/add/user", func(w http.ResponseWriter, r *http.Request) { AddClient(ssePubSub, w, r) })
+	mux.HandleFunc("/sub", func(w http.ResponseWriter, r *http.Request) { Subscribe(ssePubSub, w, r) })
+	mux.HandleFunc("/unsub", func(w http.ResponseWriter, r *http.Request) { Unsubscribe(ssePubSub, w, r) })
+	mux.HandleFunc("/event", func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Add a client
+	resp := requestWithToken(t, srv.URL+"/add/user", "")
+	body := map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	clientID := body["client_id"]
+	client, ok := ssePubSub.GetClientByID(clientID)
+	if !ok {
+		t.Fatalf("Expected the client to be created, got %v", body)
+	}
+
+	// Open the event stream and read the init message
+	stream := requestWithToken(t, srv.URL+"/event?client_id="+clientID, "")
+	defer stream.Body.Close()
+	reader := bufio.NewReader(stream.Body)
+	init := readFrames(t, reader, 1, time.Second)[0]
+	if len(init.Sys) != 1 || init.Sys[0].Type != "topics" || init.Sys[0].List[0].Name != "news" {
+		t.Errorf("Unexpected init message %+v", init)
+	}
+
+	// Subscribe
+	resp = requestWithToken(t, srv.URL+"/sub?client_id="+clientID+"&topic=news", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for subscribe, got %d", resp.StatusCode)
+	}
+	if sub := readFrames(t, reader, 1, time.Second)[0]; len(sub.Sys) != 1 || sub.Sys[0].Type != "subscribed" {
+		t.Errorf("Expected the subscribed frame, got %+v", sub)
+	}
+
+	// Publish
+	if err := topic.Pub("breaking"); err != nil {
+		t.Error(err)
+	}
+	if u := readFrames(t, reader, 1, time.Second)[0]; len(u.Updates) != 1 || u.Updates[0].Topic != "news" || decodeData(u.Updates[0].Data) != "breaking" {
+		t.Errorf("Expected the update, got %+v", u)
+	}
+
+	// Unsubscribe, later publishes are not delivered
+	resp = requestWithToken(t, srv.URL+"/unsub?client_id="+clientID+"&topic=news", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for unsubscribe, got %d", resp.StatusCode)
+	}
+	if unsub := readFrames(t, reader, 1, time.Second)[0]; len(unsub.Sys) != 1 || unsub.Sys[0].Type != "unsubscribed" {
+		t.Errorf("Expected the unsubscribed frame, got %+v", unsub)
+	}
+	if err := topic.Pub("ignored"); err != nil {
+		t.Error(err)
+	}
+
+	// Remove the client: its stream channel is closed and the event stream ends
+	client.lock.Lock()
+	conns := []*connection{}
+	for _, conn := range client.connections {
+		conns = append(conns, conn)
+	}
+	client.lock.Unlock()
+	if len(conns) != 1 {
+		t.Fatalf("Expected 1 connection, got %d", len(conns))
+	}
+	ssePubSub.RemoveClient(client)
+	select {
+	case <-conns[0].stopchan:
+	case <-time.After(time.Second):
+		t.Error("Expected the stream channel of the removed client to be closed")
+	}
+	if rest, err := io.ReadAll(reader); err != nil || strings.Contains(string(rest), "ignored") {
+		t.Errorf("Expected the stream to end without further updates, got %q, %v", rest, err)
+	}
+	if _, ok := ssePubSub.GetClientByID(clientID); ok {
+		t.Error("Expected the client to be removed")
+	}
+}
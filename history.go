@@ -0,0 +1,232 @@
+package pubsubsse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TopicOption configures a topic when it is created.
+type TopicOption func(*Topic)
+
+// WithHistory keeps the last size messages of a topic for ttl, so clients can replay them.
+func WithHistory(size int, ttl time.Duration) TopicOption {
+	return func(t *Topic) {
+		if size <= 0 || ttl <= 0 {
+			t.logger.Errorf("[T:%s]: Invalid history size %d or ttl %s", t.name, size, ttl)
+			return
+		}
+		t.history = newHistory(size, ttl)
+	}
+}
+
+// historyEntry is a published message with its publish time.
+type historyEntry struct {
+	at        time.Time
+	update    eventDataUpdates
+	delivered int
+}
+
+// HistoryEntry is a message in the history of a topic, see Topic.GetHistory.
+type HistoryEntry struct {
+	PublishedAt time.Time   `json:"published_at"`
+	Data        interface{} `json:"data"`
+	DeliveredTo int         `json:"delivered_to"` // number of clients the message was queued for
+}
+
+// history is a time-indexed ring buffer of the published messages of a topic.
+type history struct {
+	ttl time.Duration
+
+	lock sync.Mutex
+
+	entries []historyEntry
+	start   int // index of the oldest entry
+	count   int
+}
+
+// Create a new history
+func newHistory(size int, ttl time.Duration) *history {
+	return &history{
+		ttl:     ttl,
+		entries: make([]historyEntry, size),
+	}
+}
+
+// Add a message to the history. The oldest message is overwritten if the history is full.
+func (h *history) add(u eventDataUpdates, delivered int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	h.evict(now)
+
+	if h.count == len(h.entries) {
+		h.start = (h.start + 1) % len(h.entries)
+		h.count--
+	}
+	h.entries[(h.start+h.count)%len(h.entries)] = historyEntry{at: now, update: u, delivered: delivered}
+	h.count++
+}
+
+// Get all messages published at or after since, oldest first
+// Messages with an expired TTL are skipped.
+func (h *history) since(since time.Time) []eventDataUpdates {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	h.evict(now)
+
+	updates := []eventDataUpdates{}
+	for i := 0; i < h.count; i++ {
+		e := h.entries[(h.start+i)%len(h.entries)]
+		if e.at.Before(since) {
+			continue
+		}
+		if e.update.ExpiresAt != "" {
+			if expiresAt, err := time.Parse(time.RFC3339, e.update.ExpiresAt); err == nil && !now.Before(expiresAt) {
+				continue
+			}
+		}
+		updates = append(updates, e.update)
+	}
+	return updates
+}
+
+// Get all messages within the ttl, newest first
+func (h *history) list() []HistoryEntry {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.evict(time.Now())
+
+	entries := make([]HistoryEntry, 0, h.count)
+	for i := h.count - 1; i >= 0; i-- {
+		e := h.entries[(h.start+i)%len(h.entries)]
+		entries = append(entries, HistoryEntry{PublishedAt: e.at, Data: e.update.msg, DeliveredTo: e.delivered})
+	}
+	return entries
+}
+
+// Remove all messages older than the ttl. The lock must be held.
+func (h *history) evict(now time.Time) {
+	for h.count > 0 && now.Sub(h.entries[h.start].at) >= h.ttl {
+		h.entries[h.start] = historyEntry{}
+		h.start = (h.start + 1) % len(h.entries)
+		h.count--
+	}
+}
+
+// Resend the messages of a topic published at or after since to the client
+// The topic needs a history or a message store, see WithHistory and SetTopicPersistence. The store is used if both are set.
+// 0. Get the topic by name
+// 1. Check if the client is subscribed to the topic
+// 2. Load the messages from the message store or the history
+// 3. Send the messages in order
+func (c *Client) Replay(topicName string, since time.Time) error {
+	// Get the topic by name
+	t, ok := c.GetTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("[C:%s]: topic %s does not exist", c.GetID(), topicName)
+	}
+
+	// Check if the client is subscribed to the topic
+	if !t.IsSubscribed(c) {
+		return fmt.Errorf("[C:%s]: client is not subscribed to topic %s", c.GetID(), topicName)
+	}
+
+	// Load the messages from the message store or the history
+	name := t.GetName()
+	t.lock.Lock()
+	h := t.history
+	store := t.store
+	t.lock.Unlock()
+	var updates []eventDataUpdates
+	switch {
+	case store != nil:
+		msgs, err := loadStoredMessages(store, name, since)
+		if err != nil {
+			return fmt.Errorf("[C:%s]: loading messages of topic %s: %w", c.GetID(), topicName, err)
+		}
+		// Messages with an expired TTL are skipped, like in the history
+		now := time.Now()
+		for _, msg := range msgs {
+			if msg.expired(now) {
+				continue
+			}
+			u, err := newUpdate(name, msg.Data)
+			if err != nil {
+				return err
+			}
+			if !msg.ExpiresAt.IsZero() {
+				u.ExpiresAt = msg.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			updates = append(updates, u)
+		}
+	case h != nil:
+		updates = h.since(since)
+	default:
+		return fmt.Errorf("topic %s has no history", topicName)
+	}
+
+	// Send the messages in order, with the current name of the topic
+	for _, u := range updates {
+		u.Topic = name
+		if err := c.sendCtx(context.Background(), &eventData{Updates: []eventDataUpdates{u}}, PriorityNormal, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the messages in the history of the topic, newest first
+// Messages older than the ttl of the history are excluded. Empty if the topic has no history, see WithHistory.
+func (t *Topic) GetHistory() []HistoryEntry {
+	t.lock.Lock()
+	h := t.history
+	t.lock.Unlock()
+	if h == nil {
+		return []HistoryEntry{}
+	}
+
+	return h.list()
+}
+
+// Get the messages in the history of a topic that were published after the client subscribed, oldest first
+// Useful to debug messages a client missed. Messages with an expired TTL are excluded, like for Replay.
+// 0. Get the client by ID, return ErrClientNotFound if it does not exist
+// 1. Get the topic by name and the subscription time of the client
+// 2. Collect the messages of the history published at or after the subscription
+func (s *SSEPubSubService) TopicClientHistory(topicName string, clientID string) ([]interface{}, error) {
+	// Get the client by ID
+	c, ok := s.GetClientByID(clientID)
+	if !ok {
+		return nil, fmt.Errorf("client %s: %w", clientID, ErrClientNotFound)
+	}
+
+	// Get the topic by name and the subscription time of the client
+	t, ok := c.GetTopicByName(topicName)
+	if !ok {
+		return nil, fmt.Errorf("[C:%s]: topic %s does not exist", c.GetID(), topicName)
+	}
+	at, ok := t.GetSubscribedAt(c)
+	if !ok {
+		return nil, fmt.Errorf("[C:%s]: client is not subscribed to topic %s", c.GetID(), topicName)
+	}
+
+	t.lock.Lock()
+	h := t.history
+	t.lock.Unlock()
+	if h == nil {
+		return nil, fmt.Errorf("topic %s has no history", topicName)
+	}
+
+	// Collect the messages of the history published at or after the subscription
+	msgs := []interface{}{}
+	for _, u := range h.since(at) {
+		msgs = append(msgs, u.msg)
+	}
+	return msgs, nil
+}
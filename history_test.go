@@ -0,0 +1,190 @@
+package pubsubsse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +WithHistory(size int, ttl time.Duration): TopicOption
+// Client:
+// +Replay(topicName string, since time.Time): error
+// Topic:
+// +GetHistory(): []HistoryEntry
+// sSEPubSubService:
+// +TopicClientHistory(topicName string, clientID string): []interface{}, error
+
+// Collect the data of all updates
+func updatesData(events []eventData) []interface{} {
+	data := []interface{}{}
+	for _, d := range events {
+		for _, u := range d.Updates {
+			data = append(data, decodeData(u.Data))
+		}
+	}
+	return data
+}
+
+// TestReplay tests Client.Replay()
+func TestReplay(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	// Replay needs an existing, subscribed topic with a history
+	if err := client.Replay("test", time.Time{}); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	noHistory := ssePubSub.NewPublicTopic("nohistory")
+	if err := client.Sub(noHistory); err != nil {
+		t.Error(err)
+	}
+	if err := client.Replay("nohistory", time.Time{}); err == nil {
+		t.Error("Expected error for topic without history")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(10, 50*time.Millisecond))
+	if err := client.Replay("test", time.Time{}); err == nil {
+		t.Error("Expected error for unsubscribed topic")
+	}
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+
+	// Published before the client connects
+	topic.Pub("expired")
+	time.Sleep(60 * time.Millisecond)
+	topic.Pub("before")
+	since := time.Now()
+	topic.Pub("first")
+	topic.Pub("second")
+
+	events, stop := startClient(t, client)
+	if err := client.Replay("test", since); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	data := updatesData(events())
+	if len(data) != 2 || data[0] != "first" || data[1] != "second" {
+		t.Errorf("Expected the messages of the window in order, got %v", data)
+	}
+
+	// Expired messages are evicted
+	time.Sleep(60 * time.Millisecond)
+	events, stop = startClient(t, client)
+	if err := client.Replay("test", time.Time{}); err != nil {
+		t.Error(err)
+	}
+	stop()
+	if data := updatesData(events()); len(data) != 0 {
+		t.Errorf("Expected no messages after the ttl, got %v", data)
+	}
+}
+
+// TestReplay_Size tests that the history keeps only the newest messages
+func TestReplay_Size(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test", WithHistory(3, time.Minute))
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+
+	for _, msg := range []string{"1", "2", "3", "4", "5"} {
+		topic.Pub(msg)
+	}
+
+	events, stop := startClient(t, client)
+	if err := client.Replay("test", time.Time{}); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	data := updatesData(events())
+	if len(data) != 3 || data[0] != "3" || data[1] != "4" || data[2] != "5" {
+		t.Errorf("Expected the 3 newest messages, got %v", data)
+	}
+}
+
+// TestTopic_GetHistory tests Topic.GetHistory()
+func TestTopic_GetHistory(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(10, 100*time.Millisecond))
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	_, stop := startClient(t, client)
+	defer stop()
+
+	if len(ssePubSub.NewPublicTopic("nohistory").GetHistory()) != 0 {
+		t.Error("Expected no history without WithHistory")
+	}
+
+	if err := topic.Pub("old"); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	for _, msg := range []string{"first", "second"} {
+		if err := topic.Pub(msg); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Newest first, without the message older than the ttl
+	entries := topic.GetHistory()
+	if len(entries) != 2 || entries[0].Data != "second" || entries[1].Data != "first" {
+		t.Fatalf("Expected second and first, got %+v", entries)
+	}
+	if entries[0].DeliveredTo != 1 {
+		t.Errorf("Expected the message to be delivered to 1 client, got %d", entries[0].DeliveredTo)
+	}
+	if entries[0].PublishedAt.Before(entries[1].PublishedAt) {
+		t.Error("Expected the newest message first")
+	}
+}
+
+// TestTopicClientHistory tests that only the messages published after the subscription are returned
+func TestTopicClientHistory(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(10, time.Minute))
+	ssePubSub.NewPublicTopic("nohistory")
+
+	if _, err := ssePubSub.TopicClientHistory("test", "unknown"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+	if _, err := ssePubSub.TopicClientHistory("test", client.GetID()); err == nil {
+		t.Error("Expected error for a client that is not subscribed")
+	}
+
+	// Published before the subscription
+	topic.Pub("before")
+	time.Sleep(time.Millisecond)
+
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	topic.Pub("first")
+	topic.Pub("second")
+
+	msgs, err := ssePubSub.TopicClientHistory("test", client.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0] != "first" || msgs[1] != "second" {
+		t.Errorf("Expected the messages after the subscription, got %v", msgs)
+	}
+
+	// Errors
+	if _, err := ssePubSub.TopicClientHistory("unknown", client.GetID()); err == nil {
+		t.Error("Expected error for an unknown topic")
+	}
+	if err := client.Sub(ssePubSub.NewPublicTopic("nohistory")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ssePubSub.TopicClientHistory("nohistory", client.GetID()); err == nil {
+		t.Error("Expected error for a topic without history")
+	}
+}
@@ -0,0 +1,56 @@
+package pubsubsse
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// hook is a registered callback with its ID.
+type hook[T any] struct {
+	id string
+	f  func(T)
+}
+
+// hooks stores the callbacks of one lifecycle event.
+// Every callback has an ID, so it can be removed again. Callbacks are called in the order they were added.
+type hooks[T any] struct {
+	lock  sync.Mutex
+	funcs []hook[T]
+}
+
+// Add a callback and return its ID
+func (h *hooks[T]) add(f func(T)) string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	id := uuid.New().String()
+	h.funcs = append(h.funcs, hook[T]{id: id, f: f})
+	return id
+}
+
+// Remove a callback by its ID
+func (h *hooks[T]) remove(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, hk := range h.funcs {
+		if hk.id == id {
+			// Copy, so a running emit keeps its snapshot
+			h.funcs = append(append([]hook[T]{}, h.funcs[:i]...), h.funcs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Call all callbacks synchronously
+// The callbacks are called without holding the lock, so they can add or remove callbacks.
+func (h *hooks[T]) emit(v T) {
+	h.lock.Lock()
+	funcs := h.funcs
+	h.lock.Unlock()
+
+	for _, hk := range funcs {
+		hk.f(v)
+	}
+}
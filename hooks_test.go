@@ -0,0 +1,733 @@
+package pubsubsse
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +OnNewClient(f funcClient): string
+// +OnRemoveClient(f funcClient): string
+// +OnNewPublicTopic(f funcTopic): string
+// +OnRemovePublicTopic(f funcTopic): string
+// +OnNewGroup(f funcGroup): string
+// +OnRemoveGroup(f funcGroup): string
+// +OnPubError(f func(topicName string, err error)): string
+// Client:
+// +OnConnected(f funcClient): string
+// +OnDisconnected(f funcClient): string
+// +OnNewTopic(f funcTopic): string
+// +OnRemoveTopic(f funcTopic): string
+// +OnNewPublicTopic(f funcTopic): string
+// +OnRemovePublicTopic(f funcTopic): string
+// +OnNewPrivateTopic(f funcTopic): string
+// +OnRemovePrivateTopic(f funcTopic): string
+// +OnNewGroupTopic(f funcTopic): string
+// +OnNewGroup(f funcGroup): string
+// +OnRemoveGroup(f funcGroup): string
+// +OnNewSubToTopic(f funcTopic): string
+// +OnUnsubToTopic(f funcTopic): string
+// Group:
+// +OnNewClient(f funcClient): string
+// +OnRemoveClient(f funcClient): string
+// +OnNewGroupTopic(f funcTopic): string
+// +OnRemoveGroupTopic(f funcTopic): string
+// Topic:
+// +OnNewClient(f funcClient): string
+// +OnRemoveClient(f funcClient): string
+// +OnNewSubOfClient(f funcClient): string
+// +OnUnsubOfClient(f funcClient): string
+// +OnPub(f func(msg interface{})): string
+
+// hookRecorder counts how often a hook was called and with which values.
+type hookRecorder[T any] struct {
+	lock   sync.Mutex
+	values []T
+}
+
+func (r *hookRecorder[T]) record(v T) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.values = append(r.values, v)
+}
+
+// Check that the hook was called exactly once with want
+func (r *hookRecorder[T]) expectOnce(t *testing.T, name string, want T, equal func(a, b T) bool) {
+	t.Helper()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.values) != 1 {
+		t.Errorf("%s: expected 1 call, got %d", name, len(r.values))
+		return
+	}
+	if !equal(r.values[0], want) {
+		t.Errorf("%s: called with unexpected value %v", name, r.values[0])
+	}
+}
+
+func samePtr[T comparable](a, b T) bool { return a == b }
+
+// TestHooks_Service tests the lifecycle hooks of SSEPubSubService
+func TestHooks_Service(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	newClient := &hookRecorder[*Client]{}
+	removeClient := &hookRecorder[*Client]{}
+	newTopic := &hookRecorder[*Topic]{}
+	removeTopic := &hookRecorder[*Topic]{}
+	newGroup := &hookRecorder[*Group]{}
+	removeGroup := &hookRecorder[*Group]{}
+
+	ssePubSub.OnNewClient(newClient.record)
+	ssePubSub.OnRemoveClient(removeClient.record)
+	ssePubSub.OnNewPublicTopic(newTopic.record)
+	ssePubSub.OnRemovePublicTopic(removeTopic.record)
+	ssePubSub.OnNewGroup(newGroup.record)
+	ssePubSub.OnRemoveGroup(removeGroup.record)
+
+	client := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("test")
+	ssePubSub.NewPublicTopic("test") // already exists, no event
+	group := ssePubSub.NewGroup("group")
+
+	ssePubSub.RemoveGroup(group)
+	ssePubSub.RemovePublicTopic(topic)
+	ssePubSub.RemoveClient(client)
+	ssePubSub.RemoveClient(client) // already removed, no event
+
+	newClient.expectOnce(t, "OnNewClient", client, samePtr[*Client])
+	removeClient.expectOnce(t, "OnRemoveClient", client, samePtr[*Client])
+	newTopic.expectOnce(t, "OnNewPublicTopic", topic, samePtr[*Topic])
+	removeTopic.expectOnce(t, "OnRemovePublicTopic", topic, samePtr[*Topic])
+	newGroup.expectOnce(t, "OnNewGroup", group, samePtr[*Group])
+	removeGroup.expectOnce(t, "OnRemoveGroup", group, samePtr[*Group])
+
+	// A removed hook is not called anymore
+	removed := &hookRecorder[*Client]{}
+	id := ssePubSub.OnNewClient(removed.record)
+	ssePubSub.RemoveOnNewClient(id)
+	ssePubSub.NewClient()
+	if len(removed.values) != 0 {
+		t.Error("Expected removed hook not to be called")
+	}
+}
+
+// TestHooks_ClientAndTopic tests the lifecycle hooks of Client and Topic
+func TestHooks_ClientAndTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	connected := &hookRecorder[*Client]{}
+	disconnected := &hookRecorder[*Client]{}
+	clientNewTopic := &hookRecorder[*Topic]{}
+	subToTopic := &hookRecorder[*Topic]{}
+	unsubToTopic := &hookRecorder[*Topic]{}
+
+	client.OnConnected(connected.record)
+	client.OnDisconnected(disconnected.record)
+	client.OnNewTopic(clientNewTopic.record)
+	client.OnNewSubToTopic(subToTopic.record)
+	client.OnUnsubToTopic(unsubToTopic.record)
+
+	_, stop := startClient(t, client)
+
+	topic := ssePubSub.NewPublicTopic("test")
+
+	topicNewClient := &hookRecorder[*Client]{}
+	topicRemoveClient := &hookRecorder[*Client]{}
+	subOfClient := &hookRecorder[*Client]{}
+	unsubOfClient := &hookRecorder[*Client]{}
+	pub := &hookRecorder[interface{}]{}
+
+	topic.OnNewClient(topicNewClient.record)
+	topic.OnRemoveClient(topicRemoveClient.record)
+	topic.OnNewSubOfClient(subOfClient.record)
+	topic.OnUnsubOfClient(unsubOfClient.record)
+	topic.OnPub(pub.record)
+
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	if err := client.Sub(topic); err != nil { // already subscribed, no event
+		t.Error(err)
+	}
+	if err := topic.Pub("hello"); err != nil {
+		t.Error(err)
+	}
+	if err := client.Unsub(topic); err != nil {
+		t.Error(err)
+	}
+
+	stop()
+
+	connected.expectOnce(t, "Client.OnConnected", client, samePtr[*Client])
+	disconnected.expectOnce(t, "Client.OnDisconnected", client, samePtr[*Client])
+	clientNewTopic.expectOnce(t, "Client.OnNewTopic", topic, samePtr[*Topic])
+	subToTopic.expectOnce(t, "Client.OnNewSubToTopic", topic, samePtr[*Topic])
+	unsubToTopic.expectOnce(t, "Client.OnUnsubToTopic", topic, samePtr[*Topic])
+
+	topicNewClient.expectOnce(t, "Topic.OnNewClient", client, samePtr[*Client])
+	topicRemoveClient.expectOnce(t, "Topic.OnRemoveClient", client, samePtr[*Client])
+	subOfClient.expectOnce(t, "Topic.OnNewSubOfClient", client, samePtr[*Client])
+	unsubOfClient.expectOnce(t, "Topic.OnUnsubOfClient", client, samePtr[*Client])
+	pub.expectOnce(t, "Topic.OnPub", "hello", func(a, b interface{}) bool { return a == b })
+}
+
+// TestHooks_Group tests the lifecycle hooks of Group
+func TestHooks_Group(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	topic := group.NewTopic("test")
+
+	newClient := &hookRecorder[*Client]{}
+	removeClient := &hookRecorder[*Client]{}
+	clientNewTopic := &hookRecorder[*Topic]{}
+
+	group.OnNewClient(func(c *Client) {
+		if _, ok := group.GetClientByID(c.GetID()); !ok {
+			t.Error("Expected the client to be added before Group.OnNewClient")
+		}
+		newClient.record(c)
+	})
+	group.OnRemoveClient(func(c *Client) {
+		if _, ok := group.GetClientByID(c.GetID()); !ok {
+			t.Error("Expected the client to be removed after Group.OnRemoveClient")
+		}
+		removeClient.record(c)
+	})
+	client.OnNewTopic(clientNewTopic.record)
+
+	group.AddClient(client)
+	group.RemoveClient(client)
+
+	newClient.expectOnce(t, "Group.OnNewClient", client, samePtr[*Client])
+	removeClient.expectOnce(t, "Group.OnRemoveClient", client, samePtr[*Client])
+	clientNewTopic.expectOnce(t, "Client.OnNewTopic", topic, samePtr[*Topic])
+}
+
+// TestHooks_Order tests that hooks stack and are called in the order they were added
+func TestHooks_Order(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	calls := []string{}
+	ssePubSub.OnNewPublicTopic(func(topic *Topic) { calls = append(calls, "first:"+topic.GetName()) })
+	id := ssePubSub.OnNewPublicTopic(func(topic *Topic) { calls = append(calls, "removed:"+topic.GetName()) })
+	ssePubSub.OnNewPublicTopic(func(topic *Topic) {
+		// The topic is already inserted when the hook is called
+		if _, ok := ssePubSub.GetPublicTopicByName(topic.GetName()); !ok {
+			t.Error("Expected the topic to exist")
+		}
+		calls = append(calls, "second:"+topic.GetName())
+	})
+	ssePubSub.RemoveOnNewPublicTopic(id)
+
+	// Every way to create a public topic fires the hook
+	ssePubSub.NewPublicTopic("a")
+	if _, err := ssePubSub.NewReadOnlyTopic("b"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ssePubSub.PubOrCreate("c", "testdata"); err != nil {
+		t.Error(err)
+	}
+
+	expected := []string{"first:a", "second:a", "first:b", "second:b", "first:c", "second:c"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+// TestHooks_RemovePublicTopic tests that OnRemovePublicTopic is called before the clients are unsubscribed
+func TestHooks_RemovePublicTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	for i := 0; i < 3; i++ {
+		if err := ssePubSub.NewClient().Sub(topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := []int{}
+	for i := 1; i <= 2; i++ {
+		i := i
+		ssePubSub.OnRemovePublicTopic(func(topic *Topic) {
+			if len(topic.GetClients()) != 3 {
+				t.Errorf("Expected 3 subscribers, got %d", len(topic.GetClients()))
+			}
+			if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+				t.Error("Expected the topic to still exist")
+			}
+			calls = append(calls, i)
+		})
+	}
+
+	ssePubSub.RemovePublicTopic(topic)
+
+	if len(calls) != 2 || calls[0] != 1 || calls[1] != 2 {
+		t.Errorf("Expected both hooks in order, got %v", calls)
+	}
+	if len(topic.GetClients()) != 0 {
+		t.Error("Expected all clients to be unsubscribed")
+	}
+}
+
+// TestHooks_RemoveClient tests that OnRemoveClient is called before the client is cleaned up
+func TestHooks_RemoveClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	client.NewPrivateTopic("private")
+
+	calls := []int{}
+	for i := 1; i <= 2; i++ {
+		i := i
+		ssePubSub.OnRemoveClient(func(c *Client) {
+			if _, ok := ssePubSub.GetClientByID(c.GetID()); !ok {
+				t.Error("Expected the client to still exist")
+			}
+			if !topic.IsSubscribed(c) || len(c.GetPrivateTopics()) != 1 {
+				t.Error("Expected the client to still have its subscription and private topic")
+			}
+			calls = append(calls, i)
+		})
+	}
+
+	ssePubSub.RemoveClient(client)
+	ssePubSub.RemoveClient(client) // already removed, no event
+
+	if len(calls) != 2 || calls[0] != 1 || calls[1] != 2 {
+		t.Errorf("Expected both hooks once in order, got %v", calls)
+	}
+	if _, ok := ssePubSub.GetClientByID(client.GetID()); ok {
+		t.Error("Expected the client to be removed")
+	}
+}
+
+// TestHooks_RemoveGroup tests that OnRemoveGroup is called before the group is emptied
+func TestHooks_RemoveGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	group := ssePubSub.NewGroup("group")
+	group.NewTopic("test")
+	group.AddClient(ssePubSub.NewClient())
+
+	calls := []int{}
+	for i := 1; i <= 2; i++ {
+		i := i
+		ssePubSub.OnRemoveGroup(func(g *Group) {
+			if len(g.GetTopics()) != 1 || len(g.GetClients()) != 1 {
+				t.Error("Expected the group to still have its topic and client")
+			}
+			calls = append(calls, i)
+		})
+	}
+
+	ssePubSub.RemoveGroup(group)
+	ssePubSub.RemoveGroup(group) // already removed, no event
+
+	if len(calls) != 2 || calls[0] != 1 || calls[1] != 2 {
+		t.Errorf("Expected both hooks once in order, got %v", calls)
+	}
+	if ssePubSub.GroupCount() != 0 {
+		t.Error("Expected the group to be removed")
+	}
+}
+
+// TestHooks_ClientConnected tests that all Client.OnConnected hooks are called after the init message
+func TestHooks_ClientConnected(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	calls := &hookRecorder[*Client]{}
+	client.OnConnected(func(c *Client) {
+		if c.GetStatus() != Receving {
+			t.Error("Expected the client to be receiving")
+		}
+		calls.record(c)
+	})
+	client.OnConnected(func(c *Client) {
+		// Messages of the hook are delivered to the new stream
+		if err := c.SendSysEvent("welcome", nil); err != nil {
+			t.Error(err)
+		}
+		calls.record(c)
+	})
+
+	resp := requestWithToken(t, srv.URL+"/event?client_id="+client.GetID(), "")
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// The init message comes first, then the message of the hook
+	readFrame := func() string {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.ReadString('\n')
+		return line
+	}
+	if line := readFrame(); strings.Contains(line, "welcome") {
+		t.Errorf("Expected the init message first, got %s", line)
+	}
+	if line := readFrame(); !strings.Contains(line, `"type":"welcome"`) {
+		t.Errorf("Expected the message of the hook, got %s", line)
+	}
+
+	// Wait for the second hook to return
+	for i := 0; i < 100; i++ {
+		calls.lock.Lock()
+		n := len(calls.values)
+		calls.lock.Unlock()
+		if n == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected both hooks to be called")
+}
+
+// TestHooks_ClientDisconnected tests that all Client.OnDisconnected hooks are called when the browser closes the event stream
+func TestHooks_ClientDisconnected(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	first := make(chan *Client, 1)
+	second := make(chan *Client, 1)
+	client.OnDisconnected(func(c *Client) { first <- c })
+	client.OnDisconnected(func(c *Client) { second <- c })
+
+	resp := requestWithToken(t, srv.URL+"/event?client_id="+client.GetID(), "")
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-first:
+		t.Fatal("Expected no disconnect while the stream is open")
+	default:
+	}
+
+	// The browser closes the stream
+	resp.Body.Close()
+	for _, ch := range []chan *Client{first, second} {
+		select {
+		case c := <-ch:
+			if c != client {
+				t.Error("Expected the hook to be called with the client")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected all OnDisconnected hooks to be called")
+		}
+	}
+
+	// The client still exists
+	if _, ok := ssePubSub.GetClientByID(client.GetID()); !ok {
+		t.Error("Expected the client not to be removed")
+	}
+}
+
+// TestHooks_ClientTopicVisibility tests Client.OnNewTopic and Client.OnRemoveTopic for all topic types
+func TestHooks_ClientTopicVisibility(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client)
+
+	added := []string{}
+	removed := []string{}
+	client.OnNewTopic(func(topic *Topic) { added = append(added, topic.GetName()) })
+	client.OnRemoveTopic(func(topic *Topic) { removed = append(removed, topic.GetName()) })
+
+	public := ssePubSub.NewPublicTopic("public")
+	groupTopic := group.NewTopic("grouptopic")
+	private := client.NewPrivateTopic("private")
+	group.NewTopic("grouptopic2")
+
+	ssePubSub.RemovePublicTopic(public)
+	group.RemoveTopic(groupTopic)
+	client.RemovePrivateTopic(private)
+	group.RemoveClient(client) // grouptopic2 is no longer visible
+
+	if strings.Join(added, ",") != "public,grouptopic,private,grouptopic2" {
+		t.Errorf("Unexpected OnNewTopic calls: %v", added)
+	}
+	if strings.Join(removed, ",") != "public,grouptopic,private,grouptopic2" {
+		t.Errorf("Unexpected OnRemoveTopic calls: %v", removed)
+	}
+}
+
+// TestHooks_ClientPublicTopics tests Client.OnNewPublicTopic and Client.OnRemovePublicTopic
+func TestHooks_ClientPublicTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client)
+
+	newPublic := &hookRecorder[*Topic]{}
+	removePublic := &hookRecorder[*Topic]{}
+	client.OnNewPublicTopic(func(topic *Topic) {
+		if _, ok := client.GetPublicTopicByName(topic.GetName()); !ok {
+			t.Error("Expected the topic to be available to the client")
+		}
+		newPublic.record(topic)
+	})
+	client.OnRemovePublicTopic(removePublic.record)
+
+	// Private and group topics do not fire the public topic hooks
+	client.RemovePrivateTopic(client.NewPrivateTopic("private"))
+	group.RemoveTopic(group.NewTopic("grouptopic"))
+
+	public := ssePubSub.NewPublicTopic("public")
+	ssePubSub.NewPublicTopic("public") // already exists, no event
+	ssePubSub.RemovePublicTopic(public)
+	ssePubSub.RemovePublicTopic(public) // does not exist anymore, no event
+
+	newPublic.expectOnce(t, "Client.OnNewPublicTopic", public, samePtr[*Topic])
+	removePublic.expectOnce(t, "Client.OnRemovePublicTopic", public, samePtr[*Topic])
+}
+
+// TestHooks_ClientPrivateTopics tests Client.OnNewPrivateTopic and Client.OnRemovePrivateTopic
+func TestHooks_ClientPrivateTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client)
+
+	newPrivate := &hookRecorder[*Topic]{}
+	removePrivate := &hookRecorder[*Topic]{}
+	client.OnNewPrivateTopic(newPrivate.record)
+	client.OnRemovePrivateTopic(func(topic *Topic) {
+		if !topic.IsSubscribed(client) {
+			t.Error("Expected the client to still be subscribed to the private topic")
+		}
+		removePrivate.record(topic)
+	})
+
+	// Public and group topics do not fire the private topic hooks
+	ssePubSub.NewPublicTopic("public")
+	group.NewTopic("grouptopic")
+
+	private := client.NewPrivateTopic("private")
+	client.NewPrivateTopic("private") // already exists, no event
+	if err := client.Sub(private); err != nil {
+		t.Error(err)
+	}
+	client.RemovePrivateTopic(private)
+	client.RemovePrivateTopic(private) // does not exist anymore, no event
+
+	newPrivate.expectOnce(t, "Client.OnNewPrivateTopic", private, samePtr[*Topic])
+	removePrivate.expectOnce(t, "Client.OnRemovePrivateTopic", private, samePtr[*Topic])
+}
+
+// TestHooks_ClientGroupTopics tests Client.OnNewGroupTopic
+func TestHooks_ClientGroupTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.NewTopic("a")
+	group.NewTopic("b")
+
+	added := []string{}
+	client.OnNewGroupTopic(func(topic *Topic) {
+		if _, ok := client.GetTopicByName(topic.GetName()); !ok {
+			t.Error("Expected the topic to be available to the client")
+		}
+		added = append(added, topic.GetName())
+	})
+
+	// Public and private topics do not fire the hook
+	ssePubSub.NewPublicTopic("public")
+	client.NewPrivateTopic("private")
+
+	group.AddClient(client) // a and b become visible
+	group.NewTopic("c")
+	group.NewTopic("c")                       // already exists, no event
+	ssePubSub.NewGroup("other").NewTopic("d") // client is not in the group
+
+	sort.Strings(added[:2])
+	if strings.Join(added, ",") != "a,b,c" {
+		t.Errorf("Unexpected OnNewGroupTopic calls: %v", added)
+	}
+}
+
+// TestHooks_ClientGroups tests Client.OnNewGroup and Client.OnRemoveGroup
+func TestHooks_ClientGroups(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	topic := group.NewTopic("test")
+
+	newGroup := &hookRecorder[*Group]{}
+	removeGroup := &hookRecorder[*Group]{}
+	client.OnNewGroup(func(g *Group) {
+		// React to the new group by subscribing to its topics
+		if _, ok := g.GetClientByID(client.GetID()); !ok {
+			t.Error("Expected the client to be in the group")
+		}
+		for _, gt := range g.GetTopics() {
+			if err := client.Sub(gt); err != nil {
+				t.Error(err)
+			}
+		}
+		newGroup.record(g)
+	})
+	client.OnRemoveGroup(func(g *Group) {
+		if !topic.IsSubscribed(client) {
+			t.Error("Expected the client to still be subscribed to the group topic")
+		}
+		removeGroup.record(g)
+	})
+
+	group.AddClient(client)
+	group.AddClient(client) // already in the group, no event
+	if !topic.IsSubscribed(client) {
+		t.Error("Expected the client to be subscribed by the hook")
+	}
+	group.RemoveClient(client)
+	group.RemoveClient(client) // not in the group anymore, no event
+
+	newGroup.expectOnce(t, "Client.OnNewGroup", group, samePtr[*Group])
+	removeGroup.expectOnce(t, "Client.OnRemoveGroup", group, samePtr[*Group])
+}
+
+// TestHooks_GroupTopics tests Group.OnNewGroupTopic and Group.OnRemoveGroupTopic
+func TestHooks_GroupTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	group := ssePubSub.NewGroup("group")
+	client := ssePubSub.NewClient()
+	group.AddClient(client)
+
+	newTopic := &hookRecorder[*Topic]{}
+	removeTopic := &hookRecorder[*Topic]{}
+	group.OnNewGroupTopic(func(topic *Topic) {
+		if _, ok := group.GetTopicByName(topic.GetName()); !ok {
+			t.Error("Expected the topic to be added before Group.OnNewGroupTopic")
+		}
+		newTopic.record(topic)
+	})
+	group.OnRemoveGroupTopic(func(topic *Topic) {
+		if len(topic.GetClients()) != 1 {
+			t.Error("Expected the client to be subscribed during Group.OnRemoveGroupTopic")
+		}
+		removeTopic.record(topic)
+	})
+
+	topic := group.NewTopic("test")
+	group.NewTopic("test") // already exists, no event
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	group.RemoveTopic(topic)
+	group.RemoveTopic(topic) // already removed, no event
+
+	newTopic.expectOnce(t, "Group.OnNewGroupTopic", topic, samePtr[*Topic])
+	removeTopic.expectOnce(t, "Group.OnRemoveGroupTopic", topic, samePtr[*Topic])
+}
+
+// TestHooks_TopicClientPaths tests that Topic.OnNewClient and Topic.OnRemoveClient fire for every way a client is added or removed
+func TestHooks_TopicClientPaths(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	public := ssePubSub.NewPublicTopic("public")
+	group := ssePubSub.NewGroup("group")
+	groupTopic := group.NewTopic("grouptopic")
+
+	added := 0
+	removed := 0
+	for _, topic := range []*Topic{public, groupTopic} {
+		topic.OnNewClient(func(*Client) { added++ })
+		topic.OnRemoveClient(func(*Client) { removed++ })
+	}
+
+	// Subscribe 4 clients
+	clients := make([]*Client, 4)
+	for i := range clients {
+		clients[i] = ssePubSub.NewClient()
+		group.AddClient(clients[i])
+		for _, topic := range []*Topic{public, groupTopic} {
+			if err := clients[i].Sub(topic); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if added != 8 {
+		t.Errorf("Expected 8 added clients, got %d", added)
+	}
+
+	if err := clients[0].Unsub(public); err != nil { // 1 removal
+		t.Error(err)
+	}
+	group.RemoveClient(clients[1])      // 1 removal of the group topic
+	ssePubSub.RemoveClient(clients[2])  // 2 removals
+	ssePubSub.RemovePublicTopic(public) // 2 removals, clients 1 and 3
+	ssePubSub.RemoveGroup(group)        // 2 removals, clients 0 and 3
+
+	if removed != 8 {
+		t.Errorf("Expected 8 removed clients, got %d", removed)
+	}
+}
+
+// TestHooks_PubError tests OnPubError for public, private and group topics
+func TestHooks_PubError(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	errInvalid := errors.New("invalid")
+	reject := WithValidator(func(interface{}) error { return errInvalid })
+
+	public := ssePubSub.NewPublicTopic("public", reject)
+	private := client.NewPrivateTopic("private", reject)
+	groupTopic := ssePubSub.NewGroup("group").NewTopic("group", reject)
+	valid := ssePubSub.NewPublicTopic("valid")
+
+	recorder := &hookRecorder[PublishError]{}
+	id := ssePubSub.OnPubError(func(topicName string, err error) {
+		recorder.record(PublishError{TopicName: topicName, Err: err})
+	})
+
+	for _, topic := range []*Topic{public, private, groupTopic} {
+		if err := topic.Pub("test"); !errors.Is(err, errInvalid) {
+			t.Errorf("Expected validator error, got %v", err)
+		}
+	}
+	if err := valid.Pub("test"); err != nil { // no error, no event
+		t.Error(err)
+	}
+	if err := valid.Pub(json.RawMessage("{")); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+
+	recorder.lock.Lock()
+	names := []string{}
+	for _, pe := range recorder.values {
+		names = append(names, pe.TopicName)
+	}
+	recorder.lock.Unlock()
+	if strings.Join(names, ",") != "public,private,group,valid" {
+		t.Errorf("Unexpected OnPubError calls: %v", names)
+	}
+
+	// No events after the hook is removed
+	ssePubSub.RemoveOnPubError(id)
+	public.Pub("test")
+	recorder.lock.Lock()
+	if len(recorder.values) != 4 {
+		t.Errorf("Expected no event after RemoveOnPubError, got %d events", len(recorder.values))
+	}
+	recorder.lock.Unlock()
+}
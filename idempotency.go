@@ -0,0 +1,99 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default time PubIdempotent remembers a key
+const defaultIdempotencyWindow = 5 * time.Minute
+
+// WithIdempotencyWindow sets how long PubIdempotent remembers the key of a published message.
+// A message with the same key is not published again within d. 0 keeps the default of 5 minutes, a negative duration is invalid.
+func WithIdempotencyWindow(d time.Duration) Option {
+	return func(s *SSEPubSubService) {
+		if d != 0 {
+			s.idempotencyWindow = d
+		}
+	}
+}
+
+// Publish a message to a topic once per key
+// Callers that retry a publish, e.g. after a timeout, can use the same key, so the message is not delivered twice.
+// Returns false without publishing if the key was published within the idempotency window, see WithIdempotencyWindow.
+// If the publish fails, the key is forgotten, so the publish can be retried.
+// 0. Get the topic by name
+// 1. Forget the keys older than the idempotency window
+// 2. Remember the key, return false if it is already known
+// 3. Publish the message
+func (s *SSEPubSubService) PubIdempotent(topicName, key string, msg interface{}) (bool, error) {
+	if key == "" {
+		return false, fmt.Errorf("idempotency key must not be empty")
+	}
+
+	// Get the topic by name
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return false, fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	// Forget the keys older than the idempotency window and remember the key
+	now := time.Now()
+	if !t.idempotencyKeys.claim(key, now, s.idempotencyWindow) {
+		return false, nil
+	}
+
+	// Publish the message
+	if err := t.Pub(msg); err != nil {
+		t.idempotencyKeys.release(key, now)
+		return false, err
+	}
+	return true, nil
+}
+
+// keyWindow remembers keys for a time window. Maps a key to the time.Time it was claimed.
+type keyWindow struct {
+	sync.Map
+	swept atomic.Int64 // unix nano of the last check for old keys
+}
+
+// Claim a key at now
+// Returns false if the key was claimed within window. Keys older than window are forgotten.
+func (w *keyWindow) claim(key string, now time.Time, window time.Duration) bool {
+	w.forget(now, window)
+
+	if v, loaded := w.LoadOrStore(key, now); loaded {
+		at := v.(time.Time)
+		if now.Sub(at) < window {
+			return false
+		}
+		// The key expired. If it was replaced concurrently, the other claim wins.
+		if !w.CompareAndSwap(key, at, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Release a key claimed at at, e.g. if the publish failed, so it can be claimed again
+func (w *keyWindow) release(key string, at time.Time) {
+	w.CompareAndDelete(key, at)
+}
+
+// Forget the keys older than window
+// The keys are checked at most once per window, so the memory is bounded by the keys of two windows.
+func (w *keyWindow) forget(now time.Time, window time.Duration) {
+	last := w.swept.Load()
+	if now.UnixNano()-last < int64(window) || !w.swept.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	w.Range(func(key, value interface{}) bool {
+		if now.Sub(value.(time.Time)) >= window {
+			w.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
@@ -0,0 +1,91 @@
+package pubsubsse
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +WithIdempotencyWindow(d time.Duration): Option
+// +PubIdempotent(topicName, key string, msg interface{}): bool, error
+
+// TestPubIdempotent tests that a key is published once within the idempotency window
+func TestPubIdempotent(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithIdempotencyWindow(50 * time.Millisecond))
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+
+	if _, err := ssePubSub.PubIdempotent("unknown", "key", "testdata"); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	if _, err := ssePubSub.PubIdempotent("test", "", "testdata"); err == nil {
+		t.Error("Expected error for an empty key")
+	}
+
+	// Concurrent retries publish once
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	published := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := ssePubSub.PubIdempotent("test", "a", "first")
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				lock.Lock()
+				published++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if published != 1 {
+		t.Errorf("Expected 1 publish, got %d", published)
+	}
+
+	// Other keys are published
+	if ok, err := ssePubSub.PubIdempotent("test", "b", "second"); !ok || err != nil {
+		t.Errorf("Expected the publish of another key, got %t, %v", ok, err)
+	}
+	if len(recorder.Messages()) != 2 {
+		t.Errorf("Expected 2 messages, got %d", len(recorder.Messages()))
+	}
+
+	// After the window the key is published again and old keys are forgotten
+	time.Sleep(60 * time.Millisecond)
+	if ok, err := ssePubSub.PubIdempotent("test", "a", "again"); !ok || err != nil {
+		t.Errorf("Expected the publish after the window, got %t, %v", ok, err)
+	}
+	if _, ok := topic.idempotencyKeys.Load("b"); ok {
+		t.Error("Expected the expired key to be forgotten")
+	}
+
+	// A failed publish can be retried
+	errInvalid := errors.New("invalid")
+	strict := ssePubSub.NewPublicTopic("strict", WithValidator(func(msg interface{}) error {
+		if msg == "bad" {
+			return errInvalid
+		}
+		return nil
+	}))
+	if ok, err := ssePubSub.PubIdempotent("strict", "c", "bad"); ok || !errors.Is(err, errInvalid) {
+		t.Errorf("Expected the error of the validator, got %t, %v", ok, err)
+	}
+	if ok, err := ssePubSub.PubIdempotent("strict", "c", "good"); !ok || err != nil {
+		t.Errorf("Expected the retry to be published, got %t, %v", ok, err)
+	}
+	strict.idempotencyKeys.Range(func(key, value interface{}) bool {
+		if key != "c" {
+			t.Errorf("Unexpected key %v", key)
+		}
+		return true
+	})
+
+	if _, err := NewSSEPubSubService(WithIdempotencyWindow(-time.Second)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Expected ErrInvalidOption, got %v", err)
+	}
+}
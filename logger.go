@@ -0,0 +1,45 @@
+package pubsubsse
+
+import (
+	"github.com/apex/log"
+)
+
+// Logger is used by the sSEPubSubService to log informations and errors.
+// It can be replaced with WithLogger to use slog, zap, logrus, etc.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// NoOpLogger discards all log messages.
+type NoOpLogger struct{}
+
+// Infof discards the message
+func (NoOpLogger) Infof(format string, args ...interface{}) {}
+
+// Errorf discards the message
+func (NoOpLogger) Errorf(format string, args ...interface{}) {}
+
+// ApexLogger logs all messages with github.com/apex/log. This is the default logger.
+type ApexLogger struct{}
+
+// Infof logs the message with log.Infof
+func (ApexLogger) Infof(format string, args ...interface{}) {
+	log.Infof(format, args...)
+}
+
+// Errorf logs the message with log.Errorf
+func (ApexLogger) Errorf(format string, args ...interface{}) {
+	log.Errorf(format, args...)
+}
+
+// WithLogger sets the logger of the sSEPubSubService.
+// A nil logger discards all log messages.
+func WithLogger(l Logger) Option {
+	return func(s *SSEPubSubService) {
+		if l == nil {
+			l = NoOpLogger{}
+		}
+		s.logger = l
+	}
+}
@@ -0,0 +1,65 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Tests for:
+// +WithLogger(l Logger): Option
+
+// testLogger records all log messages
+type testLogger struct {
+	lock   sync.Mutex
+	infos  []string
+	errors []string
+}
+
+func (l *testLogger) Infof(format string, args ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.infos = append(l.infos, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+// TestWithLogger tests WithLogger()
+func TestWithLogger(t *testing.T) {
+	if _, ok := MustNewSSEPubSubService().logger.(ApexLogger); !ok {
+		t.Error("Default logger is not ApexLogger")
+	}
+	if _, ok := MustNewSSEPubSubService(WithLogger(nil)).logger.(NoOpLogger); !ok {
+		t.Error("nil logger is not replaced with NoOpLogger")
+	}
+
+	logger := &testLogger{}
+	ssePubSub := MustNewSSEPubSubService(WithLogger(logger))
+
+	// Errors of the service, clients, groups and topics are logged to the logger
+	topic := ssePubSub.NewPublicTopic("test")
+	ssePubSub.RemovePublicTopic(topic)
+	ssePubSub.RemovePublicTopic(topic)
+
+	client := ssePubSub.NewClient()
+	privTopic := client.NewPrivateTopic("test")
+	if err := client.Sub(privTopic); err != nil {
+		t.Error(err)
+	}
+	privTopic.Pub("testdata")
+
+	group := ssePubSub.NewGroup("test")
+	group.RemoveClient(client)
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	if len(logger.errors) < 4 {
+		t.Errorf("Expected at least 4 logged errors, got %d", len(logger.errors))
+	}
+}
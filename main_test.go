@@ -2,11 +2,13 @@ package pubsubsse
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -17,6 +19,15 @@ import (
 // General
 // -----------------------------
 
+// Decode the JSON data of an update or sys event. Invalid JSON is returned as nil.
+func decodeData(data json.RawMessage) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
 // Start /event server
 func startEventServer(ssePubSub *SSEPubSubService, t *testing.T, port int) {
 	srv := http.NewServeMux()
@@ -114,3 +125,57 @@ func httpToEvent(t *testing.T, client *Client, port int, connected, done chan bo
 		}
 	}()
 }
+
+// Start the client without an http server and collect all received events.
+// Call stop to close the event stream and wait for the client to stop.
+func startClient(t *testing.T, client *Client) (events func() []eventData, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lock := sync.Mutex{}
+	data := []eventData{}
+
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(msg string) {
+			var rvalue eventData
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(msg, "data: "))), &rvalue); err != nil {
+				t.Error(err)
+				return
+			}
+			lock.Lock()
+			data = append(data, rvalue)
+			lock.Unlock()
+		})
+	}()
+
+	// Wait for the client to receive
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+
+	events = func() []eventData {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]eventData{}, data...)
+	}
+	stop = func() {
+		// Wait until all queued messages are delivered
+		for {
+			queued := 0
+			client.lock.Lock()
+			for _, conn := range client.connections {
+				queued += len(conn.stream) + len(conn.highStream)
+			}
+			client.lock.Unlock()
+			if queued == 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		<-done
+	}
+	return events, stop
+}
@@ -0,0 +1,106 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"time"
+)
+
+// MessageStore persists the published messages of topics, e.g. in a database.
+// It must be safe for concurrent use.
+// Messages with a TTL are only persisted by an ExpiringMessageStore, so they are never replayed after their TTL.
+type MessageStore interface {
+	// Append a published message of the topic
+	Append(topicName string, msg interface{}) error
+	// Load the messages of the topic published at or after since, oldest first
+	Load(topicName string, since time.Time) ([]interface{}, error)
+}
+
+// ExpiringMessageStore is a MessageStore that also persists the expiry of messages with a TTL, see PubWithTTL.
+// AppendWithExpiry and LoadWithExpiry are used instead of Append and Load.
+type ExpiringMessageStore interface {
+	MessageStore
+	// Append a published message of the topic. expiresAt is zero for messages without a TTL.
+	AppendWithExpiry(topicName string, msg interface{}, expiresAt time.Time) error
+	// Load the messages of the topic published at or after since with their expiry, oldest first
+	LoadWithExpiry(topicName string, since time.Time) ([]StoredMessage, error)
+}
+
+// StoredMessage is a published message in an ExpiringMessageStore.
+type StoredMessage struct {
+	Data      interface{} `json:"data"`
+	ExpiresAt time.Time   `json:"expires_at"` // zero for messages without a TTL, see PubWithTTL
+}
+
+// Check if the TTL of the message is over
+func (m StoredMessage) expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// Set the message store of a public or group topic
+// Every message published to the topic is appended to the store under the current name of the topic.
+// Client.Replay loads the messages from the store instead of the history. nil removes the store.
+func (s *SSEPubSubService) SetTopicPersistence(topicName string, store MessageStore) error {
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	t.lock.Lock()
+	t.store = store
+	t.lock.Unlock()
+	return nil
+}
+
+// Get the message store of the topic. nil if it has none.
+func (t *Topic) getStore() MessageStore {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.store
+}
+
+// Load the messages of a topic from a message store
+// The messages of a MessageStore without expiry never expire.
+func loadStoredMessages(store MessageStore, topicName string, since time.Time) ([]StoredMessage, error) {
+	if es, ok := store.(ExpiringMessageStore); ok {
+		return es.LoadWithExpiry(topicName, since)
+	}
+
+	msgs, err := store.Load(topicName, since)
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]StoredMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		stored = append(stored, StoredMessage{Data: msg})
+	}
+	return stored, nil
+}
+
+// Append the published messages to the message store of the topic
+// A failing store does not fail the publish, the messages are already sent. The error is logged.
+// Messages with a TTL are skipped if the store can not persist their expiry.
+func (t *Topic) persist(name string, us []eventDataUpdates) {
+	store := t.getStore()
+	if store == nil {
+		return
+	}
+	es, expiring := store.(ExpiringMessageStore)
+
+	for _, u := range us {
+		var err error
+		switch {
+		case expiring:
+			var expiresAt time.Time
+			if u.ExpiresAt != "" {
+				expiresAt, _ = time.Parse(time.RFC3339, u.ExpiresAt)
+			}
+			err = es.AppendWithExpiry(name, u.msg, expiresAt)
+		case u.ExpiresAt == "":
+			err = store.Append(name, u.msg)
+		}
+		if err != nil {
+			t.logger.Errorf("[T:%s]: Error persisting message: %s", name, err)
+		}
+	}
+}
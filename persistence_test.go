@@ -0,0 +1,187 @@
+package pubsubsse
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +SetTopicPersistence(topicName string, store MessageStore): error
+// Client:
+// +Replay(topicName string, since time.Time): error with a message store
+// +Replay(topicName string, since time.Time): error with expired messages in an ExpiringMessageStore
+
+// memoryStore is an ExpiringMessageStore that keeps the messages in memory
+type memoryStore struct {
+	lock     sync.Mutex
+	messages map[string][]storedMessage
+	err      error
+}
+
+type storedMessage struct {
+	at  time.Time
+	msg StoredMessage
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{messages: make(map[string][]storedMessage)}
+}
+
+func (m *memoryStore) Append(topicName string, msg interface{}) error {
+	return m.AppendWithExpiry(topicName, msg, time.Time{})
+}
+
+func (m *memoryStore) Load(topicName string, since time.Time) ([]interface{}, error) {
+	stored, err := m.LoadWithExpiry(topicName, since)
+	if err != nil {
+		return nil, err
+	}
+	msgs := []interface{}{}
+	for _, msg := range stored {
+		msgs = append(msgs, msg.Data)
+	}
+	return msgs, nil
+}
+
+func (m *memoryStore) AppendWithExpiry(topicName string, msg interface{}, expiresAt time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages[topicName] = append(m.messages[topicName], storedMessage{at: time.Now(), msg: StoredMessage{Data: msg, ExpiresAt: expiresAt}})
+	return nil
+}
+
+func (m *memoryStore) LoadWithExpiry(topicName string, since time.Time) ([]StoredMessage, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.err != nil {
+		return nil, m.err
+	}
+	msgs := []StoredMessage{}
+	for _, s := range m.messages[topicName] {
+		if !s.at.Before(since) {
+			msgs = append(msgs, s.msg)
+		}
+	}
+	return msgs, nil
+}
+
+// plainStore hides the expiry methods of a store, so only the MessageStore methods are used
+type plainStore struct {
+	MessageStore
+}
+
+// TestSetTopicPersistence tests that published messages are stored and replayed from the store
+func TestSetTopicPersistence(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	store := newMemoryStore()
+	if err := ssePubSub.SetTopicPersistence("test", plainStore{store}); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	// The store is used instead of the history
+	topic := ssePubSub.NewPublicTopic("test", WithHistory(1, time.Minute))
+	if err := ssePubSub.SetTopicPersistence("test", plainStore{store}); err != nil {
+		t.Fatal(err)
+	}
+	topic.Pub("before")
+	since := time.Now()
+	topic.Pub("first")
+	topic.Pub("second")
+	if got := len(store.messages["test"]); got != 3 {
+		t.Errorf("Expected 3 stored messages, got %d", got)
+	}
+
+	// A store without expiry does not get messages with a TTL
+	if err := ssePubSub.PubWithTTL("test", "ttl", time.Minute); err != nil {
+		t.Error(err)
+	}
+	if got := len(store.messages["test"]); got != 3 {
+		t.Errorf("Expected the message with a TTL not to be stored, got %d messages", got)
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := startClient(t, client)
+	if err := client.Replay("test", since); err != nil {
+		t.Error(err)
+	}
+
+	// A failing store does not fail the publish, but the replay
+	store.lock.Lock()
+	store.err = errors.New("store down")
+	store.lock.Unlock()
+	if err := topic.Pub("third"); err != nil {
+		t.Errorf("Expected the publish to succeed, got %v", err)
+	}
+	if err := client.Replay("test", since); err == nil {
+		t.Error("Expected error for a failing store")
+	}
+	stop()
+
+	data := updatesData(events())
+	if len(data) != 3 || data[0] != "first" || data[1] != "second" || data[2] != "third" {
+		t.Errorf("Expected the stored messages and the published message, got %v", data)
+	}
+
+	// Without the store the history is used again
+	if err := ssePubSub.SetTopicPersistence("test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if topic.getStore() != nil {
+		t.Error("Expected the store to be removed")
+	}
+}
+
+// TestSetTopicPersistence_TTL tests that messages with an expired TTL are not replayed from an ExpiringMessageStore
+func TestSetTopicPersistence_TTL(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	store := newMemoryStore()
+	if err := ssePubSub.SetTopicPersistence("test", store); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ssePubSub.PubWithTTL("test", "short", time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.PubWithTTL("test", "long", time.Minute); err != nil {
+		t.Error(err)
+	}
+	if err := topic.Pub("forever"); err != nil {
+		t.Error(err)
+	}
+	if expiresAt := store.messages["test"][0].msg.ExpiresAt; expiresAt.IsZero() {
+		t.Error("Expected the expiry to be stored")
+	}
+	time.Sleep(1100 * time.Millisecond) // the expiry has a precision of seconds
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := startClient(t, client)
+	if err := client.Replay("test", time.Time{}); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	var updates []eventDataUpdates
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 2 || decodeData(updates[0].Data) != "long" || decodeData(updates[1].Data) != "forever" {
+		t.Fatalf("Expected the messages without expired TTL, got %v", updatesData(events()))
+	}
+	if updates[0].ExpiresAt == "" || updates[1].ExpiresAt != "" {
+		t.Errorf("Expected the expiry of the replayed messages, got %q and %q", updates[0].ExpiresAt, updates[1].ExpiresAt)
+	}
+}
@@ -0,0 +1,73 @@
+package pubsubsse
+
+import (
+	"time"
+)
+
+// Name of the synthetic topic of the ping updates
+const pingTopicName = "__ping__"
+
+// WithPingInterval enables a ping update to every receiving client at the interval.
+// Browsers can use it to detect dead connections. The update is tagged with the synthetic topic "__ping__"
+// and contains {"ping": <unix ms>}. The pings stop when the sSEPubSubService is closed.
+func WithPingInterval(d time.Duration) Option {
+	return func(s *SSEPubSubService) {
+		s.pingInterval = d
+	}
+}
+
+// Stop sending ping updates to the client
+func (c *Client) DisablePing() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.pingDisabled = true
+}
+
+// Check if the client receives ping updates
+func (c *Client) isPingDisabled() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.pingDisabled
+}
+
+// runPing sends a ping update to every receiving client at the ping interval until the sSEPubSubService is closed
+func (s *SSEPubSubService) runPing() {
+	defer s.workers.Done()
+
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.sendPing(now)
+		}
+	}
+}
+
+// Send a ping update to every receiving client that did not disable pings
+func (s *SSEPubSubService) sendPing(now time.Time) {
+	// Build the JSON data
+	u, err := newUpdate(pingTopicName, map[string]int64{"ping": now.UnixMilli()})
+	if err != nil {
+		s.logger.Errorf("Error building ping: %s", err)
+		return
+	}
+	fulldata := &eventData{
+		Updates: []eventDataUpdates{u},
+	}
+
+	// Send the JSON data to all receiving clients
+	for _, c := range s.GetClients() {
+		if c.GetStatus() != Receving || c.isPingDisabled() {
+			continue
+		}
+		if err := c.send(fulldata); err != nil {
+			s.logger.Errorf("[C:%s]: Error sending ping to client: %s", c.GetID(), err)
+		}
+	}
+}
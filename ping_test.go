@@ -0,0 +1,61 @@
+package pubsubsse
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// Tests for:
+// +WithPingInterval(d time.Duration): Option
+// Client:
+// +DisablePing()
+
+// Count the ping updates of the events
+func countPings(events []eventData) int {
+	pings := 0
+	for _, e := range events {
+		for _, u := range e.Updates {
+			if u.Topic == pingTopicName {
+				pings++
+			}
+		}
+	}
+	return pings
+}
+
+// TestPing tests that every receiving client gets one ping per interval until the sSEPubSubService is closed
+// goleak checks that Close stops the ping goroutine.
+func TestPing(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	const interval = 100 * time.Millisecond
+	const pings = 5
+
+	ssePubSub := MustNewSSEPubSubService(WithPingInterval(interval))
+	client := ssePubSub.NewClient()
+	quietClient := ssePubSub.NewClient()
+	quietClient.DisablePing()
+
+	events, stop := startClient(t, client)
+	quietEvents, quietStop := startClient(t, quietClient)
+
+	// Half an interval of margin, so exactly N ticks happen
+	time.Sleep(pings*interval + interval/2)
+	if err := ssePubSub.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.Close(); err != nil { // already closed
+		t.Error(err)
+	}
+	stop()
+	quietStop()
+
+	if n := countPings(events()); n != pings {
+		t.Errorf("Expected %d pings, got %d", pings, n)
+	}
+	if n := countPings(quietEvents()); n != 0 {
+		t.Errorf("Expected no pings after DisablePing, got %d", n)
+	}
+}
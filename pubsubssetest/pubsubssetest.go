@@ -0,0 +1,69 @@
+// Package pubsubssetest provides helpers for tests of code that uses pubsubsse.
+// It is a separate package, so programs using pubsubsse do not link the testing package.
+package pubsubssetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	pubsubsse "github.com/bigbluebutton-bot/pubsub-sse"
+)
+
+// NewTestClient creates a client for a test and removes it when the test ends
+// The returned function removes the client earlier. It is safe to call it more than once.
+func NewTestClient(t testing.TB, s *pubsubsse.SSEPubSubService) (*pubsubsse.Client, func()) {
+	t.Helper()
+
+	c := s.NewClient()
+	remove := func() {
+		s.RemoveClient(c)
+	}
+	t.Cleanup(remove)
+
+	return c, remove
+}
+
+// NewReceivingTestClient creates a client for a test with an open event stream
+// The stream is drained into a list of the received SSE frames, which the returned function returns.
+// The stream is closed and the client removed when the test ends.
+// 0. Create the client, see NewTestClient
+// 1. Start the event stream and collect the received messages
+// 2. Wait until the client is receiving
+func NewReceivingTestClient(t testing.TB, s *pubsubsse.SSEPubSubService) (*pubsubsse.Client, func() []string) {
+	t.Helper()
+
+	// Create the client
+	c, _ := NewTestClient(t, s)
+
+	// Start the event stream and collect the received messages
+	var lock sync.Mutex
+	received := []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Start(ctx, func(msg string) {
+			lock.Lock()
+			received = append(received, msg)
+			lock.Unlock()
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Wait until the client is receiving
+	if err := c.WaitForStream(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return append([]string{}, received...)
+	}
+	return c, messages
+}
@@ -0,0 +1,71 @@
+package pubsubssetest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pubsubsse "github.com/bigbluebutton-bot/pubsub-sse"
+)
+
+// Tests for:
+// +NewTestClient(t testing.TB, s *SSEPubSubService): *client, func()
+// +NewReceivingTestClient(t testing.TB, s *SSEPubSubService): *client, func() []string
+
+// TestNewTestClient tests that NewTestClient removes the client at the end of the test
+func TestNewTestClient(t *testing.T) {
+	ssePubSub := pubsubsse.MustNewSSEPubSubService()
+
+	var client *pubsubsse.Client
+	t.Run("cleanup", func(t *testing.T) {
+		client, _ = NewTestClient(t, ssePubSub)
+		if _, ok := ssePubSub.GetClientByID(client.GetID()); !ok {
+			t.Error("Expected the client to exist during the test")
+		}
+	})
+	if _, ok := ssePubSub.GetClientByID(client.GetID()); ok {
+		t.Error("Expected the client to be removed after the test")
+	}
+
+	t.Run("remove", func(t *testing.T) {
+		client, remove := NewTestClient(t, ssePubSub)
+		remove()
+		if _, ok := ssePubSub.GetClientByID(client.GetID()); ok {
+			t.Error("Expected the client to be removed")
+		}
+	})
+}
+
+// TestNewReceivingTestClient tests that the published messages are collected and the stream is closed after the test
+func TestNewReceivingTestClient(t *testing.T) {
+	ssePubSub := pubsubsse.MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+
+	var client *pubsubsse.Client
+	t.Run("receive", func(t *testing.T) {
+		var messages func() []string
+		client, messages = NewReceivingTestClient(t, ssePubSub)
+		if err := client.Sub(topic); err != nil {
+			t.Fatal(err)
+		}
+		if err := topic.Pub("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		// The init message, the subscribed message and the update
+		deadline := time.Now().Add(time.Second)
+		for len(messages()) < 3 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		received := messages()
+		if len(received) != 3 || !strings.Contains(received[2], `"data":"testdata"`) {
+			t.Errorf("Expected the published message, got %v", received)
+		}
+	})
+	if client.GetStatus() != pubsubsse.Waiting {
+		t.Error("Expected the event stream to be closed after the test")
+	}
+	if _, ok := ssePubSub.GetClientByID(client.GetID()); ok {
+		t.Error("Expected the client to be removed after the test")
+	}
+}
@@ -0,0 +1,73 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned by Topic.Pub if the topic has a non-blocking publish rate and it is exceeded.
+var ErrRateLimited = errors.New("publish rate limit exceeded")
+
+// Set the maximum publish rate of the topic in messages per second
+// Pub waits until the rate allows the message. burst messages can be published at once.
+// A rate of 0 or less removes the limit.
+func (t *Topic) SetMaxPublishRate(limit float64, burst int) {
+	t.setPublishRate(limit, burst, true)
+}
+
+// Set the maximum publish rate of the topic in messages per second
+// Pub returns ErrRateLimited instead of waiting if the rate is exceeded.
+// A rate of 0 or less removes the limit.
+func (t *Topic) SetMaxPublishRateNonBlocking(limit float64, burst int) {
+	t.setPublishRate(limit, burst, false)
+}
+
+// Get the maximum publish rate of the topic
+// Returns 0, 0 if the topic has no limit.
+func (t *Topic) GetPublishRate() (float64, int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.limiter == nil {
+		return 0, 0
+	}
+	return float64(t.limiter.Limit()), t.limiter.Burst()
+}
+
+// Install or remove the rate limiter
+func (t *Topic) setPublishRate(limit float64, burst int, blocking bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if limit <= 0 {
+		t.limiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	t.limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	t.limiterBlocking = blocking
+}
+
+// Wait until the publish rate allows a message
+// Returns ErrRateLimited at once if the rate limit is non-blocking.
+func (t *Topic) waitPublishRate(ctx context.Context) error {
+	t.lock.Lock()
+	limiter := t.limiter
+	blocking := t.limiterBlocking
+	t.lock.Unlock()
+
+	if limiter == nil {
+		return nil
+	}
+	if !blocking {
+		if !limiter.Allow() {
+			return ErrRateLimited
+		}
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
@@ -0,0 +1,80 @@
+package pubsubsse
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests for:
+// Topic:
+// +SetMaxPublishRate(rate float64, burst int)
+// +SetMaxPublishRateNonBlocking(rate float64, burst int)
+// +GetPublishRate(): float64, int
+
+// TestSetMaxPublishRate tests that bursts pass and sustained publishes are blocked
+func TestSetMaxPublishRate(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+
+	if r, b := topic.GetPublishRate(); r != 0 || b != 0 {
+		t.Error("Expected no rate limit")
+	}
+	topic.SetMaxPublishRate(20, 3)
+	if r, b := topic.GetPublishRate(); r != 20 || b != 3 {
+		t.Errorf("Expected rate 20 and burst 3, got %f and %d", r, b)
+	}
+
+	// The burst passes at once
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := topic.Pub(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("Expected the burst to pass at once, took %s", d)
+	}
+
+	// Then one message every 50ms
+	for i := 3; i < 5; i++ {
+		if err := topic.Pub(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("Expected sustained publishes to be blocked, took %s", d)
+	}
+	if len(recorder.Messages()) != 5 {
+		t.Error("Expected all messages to be published")
+	}
+
+	// A rate of 0 removes the limit
+	topic.SetMaxPublishRate(0, 0)
+	if r, _ := topic.GetPublishRate(); r != 0 {
+		t.Error("Expected no rate limit")
+	}
+}
+
+// TestSetMaxPublishRateNonBlocking tests that sustained over-rate publishes are dropped
+func TestSetMaxPublishRateNonBlocking(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+	topic.SetMaxPublishRateNonBlocking(1, 2)
+
+	dropped := 0
+	for i := 0; i < 5; i++ {
+		switch err := topic.Pub(i); err {
+		case nil:
+		case ErrRateLimited:
+			dropped++
+		default:
+			t.Error(err)
+		}
+	}
+	if dropped != 3 {
+		t.Errorf("Expected 3 dropped messages, got %d", dropped)
+	}
+	if len(recorder.Messages()) != 2 {
+		t.Errorf("Expected the burst of 2 messages, got %d", len(recorder.Messages()))
+	}
+}
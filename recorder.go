@@ -0,0 +1,91 @@
+package pubsubsse
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RecordedMessage is a message published to a recorded topic.
+type RecordedMessage struct {
+	Time time.Time
+	Data interface{}
+}
+
+// TopicRecorder captures all messages published to a topic.
+type TopicRecorder struct {
+	lock     sync.Mutex
+	messages []RecordedMessage
+
+	// Closed and replaced when a message is recorded. Used by WaitFor.
+	recorded chan struct{}
+}
+
+// Create a new topic recorder
+func newTopicRecorder() *TopicRecorder {
+	return &TopicRecorder{
+		messages: []RecordedMessage{},
+		recorded: make(chan struct{}),
+	}
+}
+
+// Record a published message
+func (r *TopicRecorder) record(msg interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.messages = append(r.messages, RecordedMessage{Time: time.Now(), Data: msg})
+
+	// Wake up WaitFor
+	close(r.recorded)
+	r.recorded = make(chan struct{})
+}
+
+// Get all recorded messages
+func (r *TopicRecorder) Messages() []RecordedMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]RecordedMessage{}, r.messages...)
+}
+
+// Remove all recorded messages
+func (r *TopicRecorder) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.messages = []RecordedMessage{}
+}
+
+// Wait until at least n messages are recorded
+// Returns an error if timeout is reached first.
+func (r *TopicRecorder) WaitFor(n int, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		r.lock.Lock()
+		count := len(r.messages)
+		recorded := r.recorded
+		r.lock.Unlock()
+		if count >= n {
+			return nil
+		}
+
+		// Wait for the next message or the timeout
+		select {
+		case <-recorded:
+		case <-deadline:
+			return fmt.Errorf("timeout: %d of %d messages recorded", count, n)
+		}
+	}
+}
+
+// Create new public topic that records all published messages
+// Useful in tests to check what was published without a client.
+func (s *SSEPubSubService) NewRecordingTopic(name string) (*Topic, *TopicRecorder) {
+	t := s.NewPublicTopic(name)
+
+	r := newTopicRecorder()
+	t.OnPub(r.record)
+
+	return t, r
+}
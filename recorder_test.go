@@ -0,0 +1,56 @@
+package pubsubsse
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +NewRecordingTopic(name string): *topic, *TopicRecorder
+// TopicRecorder:
+// +Messages(): []RecordedMessage
+// +Clear()
+// +WaitFor(n int, timeout time.Duration): error
+
+// TestNewRecordingTopic tests the TopicRecorder of NewRecordingTopic()
+func TestNewRecordingTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+	if _, ok := ssePubSub.GetPublicTopicByName("test"); !ok {
+		t.Error("Expected a public topic")
+	}
+
+	if err := recorder.WaitFor(1, 10*time.Millisecond); err == nil {
+		t.Error("Expected timeout without messages")
+	}
+
+	// Publish asynchronously and wait for the messages
+	before := time.Now()
+	go func() {
+		for i := 0; i < 3; i++ {
+			topic.Pub(i)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if err := recorder.WaitFor(3, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := recorder.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	for i, m := range messages {
+		if m.Data != i {
+			t.Errorf("Expected message %d, got %v", i, m.Data)
+		}
+		if m.Time.Before(before) {
+			t.Error("Expected the time of the publish")
+		}
+	}
+
+	recorder.Clear()
+	if len(recorder.Messages()) != 0 {
+		t.Error("Expected no messages after Clear")
+	}
+}
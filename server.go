@@ -0,0 +1,82 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// WithHTTPServer sets the http.Server used by ListenAndServe, ListenAndServeTLS and Serve,
+// e.g. to configure timeouts or TLS. If its Handler is nil, the sSEPubSubService is used.
+// An http.Server can only be started once.
+func WithHTTPServer(srv *http.Server) Option {
+	return func(s *SSEPubSubService) {
+		s.httpServer = srv
+	}
+}
+
+// Get the http.Server of WithHTTPServer or a new one, serving the endpoints of ServeHTTP
+func (s *SSEPubSubService) newHTTPServer(addr string) *http.Server {
+	srv := s.httpServer
+	if srv == nil {
+		srv = &http.Server{}
+	}
+	if addr != "" {
+		srv.Addr = addr
+	}
+	if srv.Handler == nil {
+		srv.Handler = s
+	}
+	return srv
+}
+
+// Run an http.Server until serve returns
+// The server is shut down when the sSEPubSubService is closed, serve then returns nil.
+func (s *SSEPubSubService) runHTTPServer(srv *http.Server, serve func() error) error {
+	if s.IsClosed() {
+		return ErrServiceClosed
+	}
+
+	// Shut down the server when the sSEPubSubService is closed
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-s.done:
+			ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				s.logger.Errorf("Error shutting down http server: %s", err)
+			}
+		case <-stopped:
+		}
+	}()
+
+	err := serve()
+	if errors.Is(err, http.ErrServerClosed) && s.IsClosed() {
+		return nil
+	}
+	return err
+}
+
+// ListenAndServe serves the endpoints of ServeHTTP on addr until the sSEPubSubService is closed
+// The http.Server can be configured with WithHTTPServer.
+func (s *SSEPubSubService) ListenAndServe(addr string) error {
+	srv := s.newHTTPServer(addr)
+	return s.runHTTPServer(srv, srv.ListenAndServe)
+}
+
+// ListenAndServeTLS serves the endpoints of ServeHTTP with TLS on addr until the sSEPubSubService is closed
+// The http.Server can be configured with WithHTTPServer.
+func (s *SSEPubSubService) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	srv := s.newHTTPServer(addr)
+	return s.runHTTPServer(srv, func() error { return srv.ListenAndServeTLS(certFile, keyFile) })
+}
+
+// Serve serves the endpoints of ServeHTTP on l until the sSEPubSubService is closed
+// The http.Server can be configured with WithHTTPServer.
+func (s *SSEPubSubService) Serve(l net.Listener) error {
+	srv := s.newHTTPServer("")
+	return s.runHTTPServer(srv, func() error { return srv.Serve(l) })
+}
@@ -0,0 +1,73 @@
+package pubsubsse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +WithHTTPServer(srv *http.Server): Option
+// +ListenAndServe(addr string): error
+// +ListenAndServeTLS(addr, certFile, keyFile string): error
+// +Serve(l net.Listener): error
+
+// TestServe tests that Serve serves the endpoints until the sSEPubSubService is closed
+func TestServe(t *testing.T) {
+	srv := &http.Server{ReadHeaderTimeout: time.Second}
+	ssePubSub := MustNewSSEPubSubService(WithHTTPServer(srv))
+
+	// Use the listener of httptest instead of binding a fixed port
+	ts := httptest.NewUnstartedServer(nil)
+	l := ts.Listener
+	served := make(chan error, 1)
+	go func() { served <- ssePubSub.Serve(l) }()
+
+	resp, err := http.Post("http://"+l.Addr().String()+DefaultAddClientPath, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := map[string]string{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if _, ok := ssePubSub.GetClientByID(body["client_id"]); !ok {
+		t.Errorf("Expected a new client, got %v", body)
+	}
+	if srv.Handler != ssePubSub {
+		t.Error("Expected the server of WithHTTPServer to serve the sSEPubSubService")
+	}
+
+	// Close shuts the server down
+	if err := ssePubSub.Close(); err != nil {
+		t.Error(err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected no error after Close, got %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Serve to return after Close")
+	}
+
+	// No server after Close
+	if err := ssePubSub.ListenAndServe("127.0.0.1:0"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected ErrServiceClosed, got %v", err)
+	}
+}
+
+// TestListenAndServe_Errors tests that errors of the http.Server are returned
+func TestListenAndServe_Errors(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	defer ssePubSub.Close()
+
+	if err := ssePubSub.ListenAndServe("invalid address"); err == nil {
+		t.Error("Expected error for an invalid address")
+	}
+	if err := ssePubSub.ListenAndServeTLS("127.0.0.1:0", "missing.crt", "missing.key"); err == nil {
+		t.Error("Expected error for missing certificate files")
+	}
+}
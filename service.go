@@ -1,13 +1,42 @@
 package pubsubsse
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
-
-	"github.com/apex/log"
-	"github.com/google/uuid"
+	"sync/atomic"
+	"time"
 )
 
 type funcClient func(*Client)
+type funcTopic func(*Topic)
+type funcGroup func(*Group)
+
+// AuthValidatorFunc validates a bearer token and returns the ID of the client it belongs to.
+type AuthValidatorFunc func(token string) (clientID string, err error)
+
+// Default time a send waits for a full client stream
+const defaultWriteTimeout = 100 * time.Millisecond
+
+// Default name of the synthetic topic used by BroadcastAll
+const defaultBroadcastTopicName = "__broadcast__"
+
+var (
+	// ErrClientNotFound is returned if a client does not exist in the sSEPubSubService.
+	ErrClientNotFound = errors.New("client not found")
+	// ErrGroupNotFound is returned if a group does not exist in the sSEPubSubService.
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrInvalidOption is returned by NewSSEPubSubService if the options are invalid or conflict.
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// Option configures the sSEPubSubService.
+type Option func(*SSEPubSubService)
 
 // SSEPubSubService represents the SSE publisher and subscriber system.
 type SSEPubSubService struct {
@@ -15,86 +44,395 @@ type SSEPubSubService struct {
 	publicTopics map[string]*Topic
 	groups       map[string]*Group
 
+	// Clients by tag, see GetClientsByTag. Maps the key and value of a tag to the clients by ID.
+	tagIndex map[string]map[string]map[string]*Client
+
+	// Lock ordering: sSEPubSubService -> Group -> Topic -> Client.
+	// While a lock is held, only locks further right may be taken. Callbacks and sends run without any lock.
 	lock sync.Mutex
 
+	// Closed and replaced when a client is created. Used by WaitForClient.
+	clientAdded chan struct{}
+
+	// Number of groups. Can be read without locking the sSEPubSubService.
+	groupCount atomic.Int64
+
+	logger Logger
+
+	// Time a send waits for a full client stream
+	writeTimeout time.Duration
+
+	// Name of the synthetic topic used by BroadcastAll
+	broadcastTopicName string
+
+	// Reconnect delay sent to the browser as SSE retry field. 0 disables it.
+	retryHint time.Duration
+
+	// Compress the event stream with gzip if the browser accepts it
+	compression bool
+
+	// Routes the messages of browser tabs over the event stream of the primary tab
+	tabs *TabManager
+
+	// Server of ListenAndServe, see WithHTTPServer. nil creates a new one.
+	httpServer *http.Server
+
+	// Paths of the ServeHTTP endpoints that differ from the default paths
+	paths map[Endpoint]string
+
+	// Token required by the DebugHandler. Empty disables the DebugHandler.
+	debugToken string
+
+	// Metrics
+	messagesSent    atomic.Int64
+	messagesDropped atomic.Int64
+
+	// Validates bearer tokens of http requests. nil disables authentication.
+	authValidator AuthValidatorFunc
+
+	// CORS headers of ServeHTTP. nil disables CORS.
+	cors *CORSConfig
+
+	// Conflict resolution of ImportState
+	importMode ImportMode
+
+	// Interval of the ping updates. 0 disables pings.
+	pingInterval time.Duration
+
+	// Closed by Close to stop the background goroutines
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	closed    atomic.Bool
+	workers   sync.WaitGroup
+
+	// Running throttled publishers. Close stops them.
+	throttled map[*ThrottledPublisher]struct{}
+
+	// Time Close waits for the event streams to end
+	shutdownTimeout time.Duration
+
+	// Time PubIdempotent remembers a key
+	idempotencyWindow time.Duration
+
+	// Number of running event streams. Close waits until it is 0.
+	activeStreams atomic.Int64
+
 	// Events:
-	eventsOnNewClient map[string]funcClient
+	onNewClient         hooks[*Client]
+	onRemoveClient      hooks[*Client]
+	onNewPublicTopic    hooks[*Topic]
+	onRemovePublicTopic hooks[*Topic]
+	onNewGroup          hooks[*Group]
+	onRemoveGroup       hooks[*Group]
+	onPubError          hooks[PublishError]
 }
 
-// NewSSEPubSub creates a new sSEPubSubService instance.
-func NewSSEPubSubService() *SSEPubSubService {
-	return &SSEPubSubService{
+// NewSSEPubSubService creates a new sSEPubSubService instance.
+// Returns ErrInvalidOption if the options are invalid or conflict, e.g. a negative timeout or an empty path.
+// 0. Apply the options
+// 1. Validate the configuration
+// 2. Start the background goroutines
+func NewSSEPubSubService(opts ...Option) (*SSEPubSubService, error) {
+	s := &SSEPubSubService{
 		clients:      make(map[string]*Client),
 		publicTopics: make(map[string]*Topic),
 		groups:       make(map[string]*Group),
+		tagIndex:     make(map[string]map[string]map[string]*Client),
+		paths:        make(map[Endpoint]string),
+		tabs:         newTabManager(),
+		throttled:    make(map[*ThrottledPublisher]struct{}),
 
 		lock: sync.Mutex{},
 
-		eventsOnNewClient: make(map[string]funcClient),
+		clientAdded: make(chan struct{}),
+		done:        make(chan struct{}),
+
+		logger: ApexLogger{},
+
+		writeTimeout:       defaultWriteTimeout,
+		broadcastTopicName: defaultBroadcastTopicName,
+		shutdownTimeout:    defaultShutdownTimeout,
+		idempotencyWindow:  defaultIdempotencyWindow,
+	}
+
+	// Apply the options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Validate the configuration
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	// Start the background goroutines
+	if s.pingInterval > 0 {
+		s.workers.Add(1)
+		go s.runPing()
+	}
+
+	return s, nil
+}
+
+// MustNewSSEPubSubService is like NewSSEPubSubService but panics if the options are invalid.
+// Useful in main, where the options are fixed.
+func MustNewSSEPubSubService(opts ...Option) *SSEPubSubService {
+	s, err := NewSSEPubSubService(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Check the configuration set by the options
+// Returns all problems at once, each wrapping ErrInvalidOption.
+func (s *SSEPubSubService) validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidOption, fmt.Sprintf(format, args...)))
+	}
+
+	// Durations
+	if s.writeTimeout < 0 {
+		invalid("write timeout %s is negative", s.writeTimeout)
+	}
+	if s.shutdownTimeout < 0 {
+		invalid("shutdown timeout %s is negative", s.shutdownTimeout)
+	}
+	if s.retryHint < 0 {
+		invalid("retry hint %s is negative", s.retryHint)
+	}
+	if s.pingInterval < 0 {
+		invalid("ping interval %s is negative", s.pingInterval)
+	}
+	if s.idempotencyWindow < 0 {
+		invalid("idempotency window %s is negative", s.idempotencyWindow)
+	}
+
+	// Synthetic topics
+	if s.broadcastTopicName == "" {
+		invalid("broadcast topic name is empty")
+	} else if s.broadcastTopicName == pingTopicName {
+		invalid("broadcast topic name %q is used by the pings", s.broadcastTopicName)
+	}
+
+	if s.cors != nil {
+		if err := s.cors.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.importMode != ImportModeSkip && s.importMode != ImportModeReplace {
+		invalid("unknown import mode %d", s.importMode)
+	}
+
+	// Paths of ServeHTTP. Every endpoint needs its own path.
+	endpoints := make(map[string]Endpoint, len(routes))
+	for _, rt := range routes {
+		path := s.routePath(rt)
+		if !strings.HasPrefix(path, "/") {
+			invalid("path %q of endpoint %s does not start with /", path, rt.endpoint)
+		}
+		if other, ok := endpoints[path]; ok {
+			invalid("endpoints %s and %s have the same path %q", other, rt.endpoint, path)
+		}
+		endpoints[path] = rt.endpoint
+	}
+	for endpoint := range s.paths {
+		if !knownEndpoint(endpoint) {
+			invalid("unknown endpoint %s", endpoint)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithRetryHint sets the reconnect delay the browser's EventSource waits after a lost connection.
+// The Event handler sends it as SSE retry field before any other data.
+func WithRetryHint(d time.Duration) Option {
+	return func(s *SSEPubSubService) {
+		s.retryHint = d
+	}
+}
+
+// WithCompression enables gzip compression of the event stream for browsers that accept it.
+// Useful for large payloads like slide data or whiteboard events.
+func WithCompression(enabled bool) Option {
+	return func(s *SSEPubSubService) {
+		s.compression = enabled
+	}
+}
+
+// WithDebugToken sets the token operators need for the DebugHandler.
+func WithDebugToken(token string) Option {
+	return func(s *SSEPubSubService) {
+		s.debugToken = token
+	}
+}
+
+// WithWriteTimeout sets how long a send waits for a full client stream before it fails.
+// It can be overridden per topic with SetTopicPublishTimeout.
+// 0 keeps the default, a negative duration is invalid.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *SSEPubSubService) {
+		if d != 0 {
+			s.writeTimeout = d
+		}
+	}
+}
+
+// WithBroadcastTopicName sets the name of the synthetic topic used by BroadcastAll.
+func WithBroadcastTopicName(name string) Option {
+	return func(s *SSEPubSubService) {
+		s.broadcastTopicName = name
 	}
 }
 
 // Create new client
 func (s *SSEPubSubService) NewClient() *Client {
+	c, _ := s.addClient(newClient(s))
+	return c
+}
+
+// Add client to the sSEPubSubService
+// If a client with the same ID already exists, it is returned with false.
+func (s *SSEPubSubService) addClient(c *Client) (*Client, bool) {
 	// Lock the sSEPubSubService
 	s.lock.Lock()
+	if existing, ok := s.clients[c.id]; ok {
+		s.lock.Unlock()
+		return existing, false
+	}
+	s.clients[c.id] = c
+	c.lock.Lock()
+	for k, v := range c.tags {
+		s.indexTag(k, v, c)
+	}
+	c.lock.Unlock()
 
-	c := newClient(s)
-	s.clients[c.GetID()] = c
+	// Wake up WaitForClient
+	close(s.clientAdded)
+	s.clientAdded = make(chan struct{})
 	s.lock.Unlock()
 
 	// Emit event
-	s.emitOnNewClient(c)
+	s.onNewClient.emit(c)
 
-	return c
+	return c, true
 }
 
 // Event: When client is created
+// Called at the end of NewClient and NewClientWithOptions.
 func (s *SSEPubSubService) OnNewClient(f funcClient) string {
-	// Lock the sSEPubSubService
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	return s.onNewClient.add(f)
+}
 
-	id := uuid.New().String()
+// Remove Event: When client is created
+func (s *SSEPubSubService) RemoveOnNewClient(id string) {
+	s.onNewClient.remove(id)
+}
 
-	// Add f to eventsOnNewClient map
-	s.eventsOnNewClient[id] = f
-	return id
+// Event: When client is removed
+// Called at the start of RemoveClient, while the client still has its topics and subscriptions.
+func (s *SSEPubSubService) OnRemoveClient(f funcClient) string {
+	return s.onRemoveClient.add(f)
 }
 
-// Emit Event: When client is created
-func (s *SSEPubSubService) emitOnNewClient(c *Client) {
-	// Lock the sSEPubSubService
+// Remove Event: When client is removed
+func (s *SSEPubSubService) RemoveOnRemoveClient(id string) {
+	s.onRemoveClient.remove(id)
+}
+
+// Event: When public topic is created
+func (s *SSEPubSubService) OnNewPublicTopic(f funcTopic) string {
+	return s.onNewPublicTopic.add(f)
+}
+
+// Remove Event: When public topic is created
+func (s *SSEPubSubService) RemoveOnNewPublicTopic(id string) {
+	s.onNewPublicTopic.remove(id)
+}
+
+// Event: When public topic is removed
+// Called before the clients are unsubscribed, so the topic still has all its clients.
+func (s *SSEPubSubService) OnRemovePublicTopic(f funcTopic) string {
+	return s.onRemovePublicTopic.add(f)
+}
+
+// Remove Event: When public topic is removed
+func (s *SSEPubSubService) RemoveOnRemovePublicTopic(id string) {
+	s.onRemovePublicTopic.remove(id)
+}
+
+// Event: When group is created
+func (s *SSEPubSubService) OnNewGroup(f funcGroup) string {
+	return s.onNewGroup.add(f)
+}
+
+// Remove Event: When group is created
+func (s *SSEPubSubService) RemoveOnNewGroup(id string) {
+	s.onNewGroup.remove(id)
+}
+
+// Event: When group is removed
+// Called before the topics and clients are removed from the group.
+func (s *SSEPubSubService) OnRemoveGroup(f funcGroup) string {
+	return s.onRemoveGroup.add(f)
+}
+
+// Remove Event: When group is removed
+func (s *SSEPubSubService) RemoveOnRemoveGroup(id string) {
+	s.onRemoveGroup.remove(id)
+}
+
+// Event: When a publish to a topic of the sSEPubSubService fails
+// Called with the error the publish returns, e.g. of the rate limit, the circuit breaker or the validator of the topic.
+func (s *SSEPubSubService) OnPubError(f func(topicName string, err error)) string {
+	return s.onPubError.add(func(pe PublishError) { f(pe.TopicName, pe.Err) })
+}
+
+// Remove Event: When a publish to a topic of the sSEPubSubService fails
+func (s *SSEPubSubService) RemoveOnPubError(id string) {
+	s.onPubError.remove(id)
+}
+
+// Set the auth validator
+// The AddClient and Event handlers require an "Authorization: Bearer <token>" header and call it with the token.
+// Requests without the header or with an invalid token are rejected with 401.
+// The returned client ID overrides the client_id query parameter. nil disables authentication.
+func (s *SSEPubSubService) SetAuthValidator(fn AuthValidatorFunc) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	// Emit event
-	for _, f := range s.eventsOnNewClient {
-		go f(c)
-	}
+	s.authValidator = fn
 }
 
-// Remove Event: When client is created
-func (s *SSEPubSubService) RemoveOnNewClient(id string) {
-	// Lock the sSEPubSubService
+// Get the auth validator
+func (s *SSEPubSubService) getAuthValidator() AuthValidatorFunc {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	// Remove f from eventsOnNewClient map
-	delete(s.eventsOnNewClient, id)
+	return s.authValidator
 }
 
 // Remove client
+// 0. Emit the remove event if the client exists in sSEPubSubService
 // 1. Unsubscribe from all topics
 // 2. Remove all private topics
 // 3. Stop the client
-// 4. Remove client from sSEPubSubService
+// 4. Remove client from the tab manager and the sSEPubSubService
 func (s *SSEPubSubService) RemoveClient(c *Client) {
+	// Emit event while the client still has all its topics and subscriptions
+	if existing, ok := s.GetClientByID(c.GetID()); ok && existing == c {
+		s.onRemoveClient.emit(c)
+	}
+
 	// Unsubscribe from all topics
 	alltopics := c.GetAllTopics()
 	for _, t := range alltopics {
 		if err := c.Unsub(t); err != nil {
-			log.Errorf("[C:%s]: Error unsubscribing from topic %s: %s", c.GetID(), t.GetName(), err)
+			s.logger.Errorf("[C:%s]: Error unsubscribing from topic %s: %s", c.GetID(), t.GetName(), err)
 		}
 	}
 
@@ -106,12 +444,40 @@ func (s *SSEPubSubService) RemoveClient(c *Client) {
 	// stop the client
 	c.stop()
 
-	// Lock the sSEPubSubService
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	// Remove client from the tab manager
+	s.tabs.RemoveTab(c)
 
 	// Remove client from sSEPubSubService
-	delete(s.clients, c.GetID())
+	id := c.GetID()
+	s.lock.Lock()
+	delete(s.clients, id)
+	c.lock.Lock()
+	for k, v := range c.tags {
+		s.unindexTag(k, v, c)
+	}
+	c.lock.Unlock()
+	s.lock.Unlock()
+}
+
+// Set custom data of a client
+func (s *SSEPubSubService) SetClientCustomData(clientID string, data interface{}) error {
+	c, ok := s.GetClientByID(clientID)
+	if !ok {
+		return fmt.Errorf("client %s: %w", clientID, ErrClientNotFound)
+	}
+
+	c.SetCustomData(data)
+	return nil
+}
+
+// Get custom data of a client
+func (s *SSEPubSubService) GetClientCustomData(clientID string) (interface{}, error) {
+	c, ok := s.GetClientByID(clientID)
+	if !ok {
+		return nil, fmt.Errorf("client %s: %w", clientID, ErrClientNotFound)
+	}
+
+	return c.GetCustomData(), nil
 }
 
 // Add Group
@@ -124,22 +490,79 @@ func (s *SSEPubSubService) NewGroup(name string) *Group {
 		return g
 	}
 
-	// Create a new group
-	g := newGroup(name)
+	// Create a new group and add it to the sSEPubSubService. If it was created concurrently, return the existing one.
+	g, _ := s.addGroup(newGroup(name, s.logger))
+
+	return g
+}
+
+// Create a new group together with its topics
+// The group is added with all topics at once, so no client can see the group without its topics.
+// Returns the topics in the order of names. Returns an error if the group already exists or a name is empty or duplicated.
+// 0. Check the group name and the topic names
+// 1. Create the group with its topics
+// 2. Add the group to the sSEPubSubService
+func (s *SSEPubSubService) NewPublicTopicGroup(groupName string, names []string) ([]*Topic, *Group, error) {
+	// Check the group name and the topic names
+	if groupName == "" {
+		return nil, nil, fmt.Errorf("group name must not be empty")
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, nil, fmt.Errorf("topic name must not be empty")
+		}
+		if seen[name] {
+			return nil, nil, fmt.Errorf("topic %s is duplicated", name)
+		}
+		seen[name] = true
+	}
+
+	// Create the group with its topics. It has no clients yet, so no client has to be informed.
+	g := newGroup(groupName, s.logger)
+	topics := make([]*Topic, 0, len(names))
+	for _, name := range names {
+		t := newTopic(name, TGroup, s.logger)
+		t.pubErrors = &s.onPubError
+		g.topics[name] = t
+		topics = append(topics, t)
+	}
 
 	// Add the group to the sSEPubSubService
+	if _, ok := s.addGroup(g); !ok {
+		return nil, nil, fmt.Errorf("group %s already exists", groupName)
+	}
+
+	return topics, g, nil
+}
+
+// Add group to the sSEPubSubService
+// If a group with the same name already exists, it is returned with false.
+func (s *SSEPubSubService) addGroup(g *Group) (*Group, bool) {
+	name := g.GetName()
+
 	s.lock.Lock()
-	s.groups[g.GetName()] = g
+	if existing, ok := s.groups[name]; ok {
+		s.lock.Unlock()
+		return existing, false
+	}
+	g.pubErrors = &s.onPubError
+	s.groups[name] = g
+	s.groupCount.Add(1)
 	s.lock.Unlock()
 
-	return g
+	// Emit event
+	s.onNewGroup.emit(g)
+
+	return g, true
 }
 
 // Remove group
 // 0. Check if group exists in sSEPubSubService
-// 1. Remove all topics from the group
-// 2. Remove all clients from the group
-// 3. Remove group from sSEPubSubService
+// 1. Emit the remove event
+// 2. Remove all topics from the group
+// 3. Remove all clients from the group
+// 4. Remove group from sSEPubSubService
 func (s *SSEPubSubService) RemoveGroup(g *Group) {
 	// Check if group exists in sSEPubSubService
 	checkIfExist := func() bool {
@@ -151,10 +574,13 @@ func (s *SSEPubSubService) RemoveGroup(g *Group) {
 		return false
 	}
 	if !checkIfExist() {
-		log.Errorf("Group %s does not exist in sSEPubSubService", g.GetName())
+		s.logger.Errorf("Group %s does not exist in sSEPubSubService", g.GetName())
 		return
 	}
 
+	// Emit event while the group still has all its topics and clients
+	s.onRemoveGroup.emit(g)
+
 	// Remove all topics from the group
 	for _, t := range g.GetTopics() {
 		g.RemoveTopic(t)
@@ -167,10 +593,231 @@ func (s *SSEPubSubService) RemoveGroup(g *Group) {
 
 	// Remove group from sSEPubSubService
 	s.lock.Lock()
-	delete(s.groups, g.GetName())
+	if group, ok := s.groups[g.GetName()]; ok && group == g {
+		delete(s.groups, g.GetName())
+		s.groupCount.Add(-1)
+	}
 	s.lock.Unlock()
 }
 
+// Get the number of groups
+func (s *SSEPubSubService) GroupCount() int {
+	return int(s.groupCount.Load())
+}
+
+// Publish a message to all clients of a group
+// This is an administrative broadcast that is not tied to a topic.
+// The update is tagged with the synthetic topic "group" and the name of the group.
+// 0. Check if group exists, return ErrGroupNotFound if it does not
+// 1. Build the JSON data
+// 2. Send the JSON data to all clients of the group
+func (s *SSEPubSubService) PubToGroup(groupName string, data interface{}) error {
+	// Check if group exists
+	g, ok := s.GetGroupByName(groupName)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, groupName)
+	}
+
+	// Build the JSON data
+	u, err := newUpdate(string(TopicTypeGroup), data)
+	if err != nil {
+		return err
+	}
+	u.Group = g.GetName()
+	fulldata := &eventData{
+		Updates: []eventDataUpdates{u},
+	}
+
+	// Send the JSON data to all clients of the group
+	for _, c := range g.GetClients() {
+		if err := c.send(fulldata); err != nil {
+			s.logger.Errorf("[G:%s]: Error sending data to client: %s", g.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// Publish a message to one client
+// The update is tagged with topicName, but the topic does not have to exist and the client
+// does not have to be subscribed to it. The message is written directly to all event streams of the client.
+// A client that is not receiving gets the message when its next event stream starts.
+// 0. Check if client exists, return ErrClientNotFound if it does not
+// 1. Build the JSON data
+// 2. Send the JSON data to the client
+func (s *SSEPubSubService) PubToClient(clientID, topicName string, data interface{}) error {
+	// Check if client exists
+	c, ok := s.GetClientByID(clientID)
+	if !ok {
+		return fmt.Errorf("client %s: %w", clientID, ErrClientNotFound)
+	}
+
+	// Build the JSON data
+	u, err := newUpdate(topicName, data)
+	if err != nil {
+		return err
+	}
+	fulldata := &eventData{
+		Updates: []eventDataUpdates{u},
+	}
+
+	// Send the JSON data to the client
+	return c.sendDirect(fulldata)
+}
+
+// PublishError is the error of a publish to one topic.
+type PublishError struct {
+	TopicName string
+	Err       error
+}
+
+// PublishErrors is returned if a publish to multiple topics failed for some of them.
+type PublishErrors []PublishError
+
+func (e PublishErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, pe := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", pe.TopicName, pe.Err))
+	}
+	return fmt.Sprintf("publish failed for %d topics: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of all failed topics, so errors.Is can be used.
+func (e PublishErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, pe := range e {
+		errs = append(errs, pe.Err)
+	}
+	return errs
+}
+
+// Publish a message to all topics of a type
+// Returns PublishErrors if the publish failed for any topic.
+// 0. Collect all topics of the type
+// 1. Publish the message to every topic
+func (s *SSEPubSubService) PubToTopicType(ttype topicType, msg interface{}) error {
+	// Collect all topics of the type
+	topics, err := s.topicsOfType(ttype)
+	if err != nil {
+		return err
+	}
+
+	// Publish the message to every topic
+	var errs PublishErrors
+	for _, t := range topics {
+		if err := t.Pub(msg); err != nil {
+			errs = append(errs, PublishError{TopicName: t.GetName(), Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Get all topics of a type, including the group topics and the private topics of all clients
+func (s *SSEPubSubService) topicsOfType(ttype topicType) ([]*Topic, error) {
+	topics := []*Topic{}
+	switch ttype {
+	case TopicTypePublic:
+		s.ForEachPublicTopic(func(t *Topic) bool {
+			topics = append(topics, t)
+			return true
+		})
+	case TopicTypeGroup:
+		s.ForEachGroup(func(g *Group) bool {
+			for _, t := range g.GetTopics() {
+				topics = append(topics, t)
+			}
+			return true
+		})
+	case TopicTypePrivate:
+		for _, c := range s.GetClients() {
+			for _, t := range c.GetPrivateTopics() {
+				topics = append(topics, t)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown topic type %s", ttype)
+	}
+	return topics, nil
+}
+
+// Publish a message to all topics of a group at the same time
+// Unlike PubToGroup, every topic publishes the message itself, so its validator, rate limit and hooks apply.
+// Returns PublishErrors, sorted by topic name, if the publish failed for any topic.
+// 0. Check if group exists, return ErrGroupNotFound if it does not
+// 1. Publish the message to every topic of the group concurrently
+// 2. Collect the errors of all topics
+func (s *SSEPubSubService) PubToGroupTopics(groupName string, msg interface{}) error {
+	// Check if group exists
+	g, ok := s.GetGroupByName(groupName)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, groupName)
+	}
+
+	// Publish the message to every topic of the group concurrently
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs PublishErrors
+	)
+	for _, t := range g.GetTopics() {
+		wg.Add(1)
+		go func(t *Topic) {
+			defer wg.Done()
+			if err := t.Pub(msg); err != nil {
+				lock.Lock()
+				errs = append(errs, PublishError{TopicName: t.GetName(), Err: err})
+				lock.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	// Collect the errors of all topics
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].TopicName < errs[j].TopicName })
+		return errs
+	}
+	return nil
+}
+
+// Publish a message to every receiving client regardless of its subscriptions
+// The update is tagged with the synthetic broadcast topic, "__broadcast__" by default.
+func (s *SSEPubSubService) BroadcastAll(data interface{}) error {
+	// Build the JSON data
+	u, err := newUpdate(s.broadcastTopicName, data)
+	if err != nil {
+		return err
+	}
+	fulldata := &eventData{
+		Updates: []eventDataUpdates{u},
+	}
+
+	// Send the JSON data to all receiving clients
+	for _, c := range s.GetClients() {
+		if c.GetStatus() != Receving {
+			continue
+		}
+		if err := c.send(fulldata); err != nil {
+			s.logger.Errorf("[C:%s]: Error sending broadcast to client: %s", c.GetID(), err)
+		}
+	}
+
+	return nil
+}
+
+// Close all open event streams of all clients
+// The clients and their subscriptions are kept, so they can reconnect, e.g. during a rolling restart.
+// Every event stream is stopped only once, even if it ends concurrently.
+func (s *SSEPubSubService) CloseAllConnections() error {
+	for _, c := range s.GetClients() {
+		c.stop()
+	}
+	return nil
+}
+
 // Get groups
 func (s *SSEPubSubService) GetGroups() map[string]*Group {
 	s.lock.Lock()
@@ -185,6 +832,44 @@ func (s *SSEPubSubService) GetGroups() map[string]*Group {
 	return newmap
 }
 
+// Iterate over all public topics
+// Stops early if fn returns false. The order of the topics is not defined.
+// fn is called without holding the lock of the sSEPubSubService, so it can use the sSEPubSubService.
+// The lock is not reentrant and fn often publishes, so the topics are copied into a slice instead of
+// holding the lock while fn runs.
+func (s *SSEPubSubService) ForEachPublicTopic(fn func(t *Topic) bool) {
+	s.lock.Lock()
+	topics := make([]*Topic, 0, len(s.publicTopics))
+	for _, t := range s.publicTopics {
+		topics = append(topics, t)
+	}
+	s.lock.Unlock()
+
+	for _, t := range topics {
+		if !fn(t) {
+			return
+		}
+	}
+}
+
+// Iterate over all groups
+// Stops early if fn returns false. The order of the groups is not defined.
+// fn is called without holding the lock of the sSEPubSubService, so it can use the sSEPubSubService.
+func (s *SSEPubSubService) ForEachGroup(fn func(g *Group) bool) {
+	s.lock.Lock()
+	groups := make([]*Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	s.lock.Unlock()
+
+	for _, g := range groups {
+		if !fn(g) {
+			return
+		}
+	}
+}
+
 // Get group by name
 func (s *SSEPubSubService) GetGroupByName(name string) (*Group, bool) {
 	s.lock.Lock()
@@ -194,6 +879,22 @@ func (s *SSEPubSubService) GetGroupByName(name string) (*Group, bool) {
 	return g, ok
 }
 
+// Check if a client is a member of a group
+// Uses the groups of the client, so the check is O(1).
+func (s *SSEPubSubService) ClientHasGroup(clientID, groupName string) (bool, error) {
+	c, ok := s.GetClientByID(clientID)
+	if !ok {
+		return false, ErrClientNotFound
+	}
+	g, ok := s.GetGroupByName(groupName)
+	if !ok {
+		return false, ErrGroupNotFound
+	}
+
+	cg, ok := c.GetGroupByName(groupName)
+	return ok && cg == g, nil
+}
+
 // Get clients
 func (s *SSEPubSubService) GetClients() map[string]*Client {
 	s.lock.Lock()
@@ -217,43 +918,263 @@ func (s *SSEPubSubService) GetClientByID(id string) (*Client, bool) {
 	return c, ok
 }
 
+// Check if a client with the ID exists
+func (s *SSEPubSubService) ClientExists(id string) bool {
+	_, ok := s.GetClientByID(id)
+	return ok
+}
+
+// Wait until a client with the ID exists
+// Returns ctx.Err() if ctx is done before the client is created.
+func (s *SSEPubSubService) WaitForClient(ctx context.Context, id string) (*Client, error) {
+	for {
+		s.lock.Lock()
+		c, ok := s.clients[id]
+		added := s.clientAdded
+		s.lock.Unlock()
+		if ok {
+			return c, nil
+		}
+
+		// Wait for the next client or the end of ctx
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Find a client
+// Returns the first client for which predicate returns true. The order of the clients is not defined.
+// predicate is called without holding the lock of the sSEPubSubService, so it can use the sSEPubSubService.
+func (s *SSEPubSubService) FindClient(predicate func(*Client) bool) (*Client, bool) {
+	s.lock.Lock()
+	clients := make([]*Client, 0, len(s.clients))
+	for _, c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.lock.Unlock()
+
+	for _, c := range clients {
+		if predicate(c) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
 // Create new public topic
+// The options are ignored if the topic already exists.
 // 0. Check if topic already exists, return it if it does
 // 1. Create a new public topic
-// 2. Add the topic to the sSEPubSubService
-// 3. Inform all clients about the new topic
-func (s *SSEPubSubService) NewPublicTopic(name string) *Topic {
+// 2. Add the topic to the sSEPubSubService and inform all clients about the new topic
+func (s *SSEPubSubService) NewPublicTopic(name string, opts ...TopicOption) *Topic {
 	// Check if topic already exists, return it if it does
 	if t, ok := s.GetPublicTopicByName(name); ok {
 		return t
 	}
 
-	// Create a new public topic
-	t := newTopic(name, TPublic)
+	// Create a new public topic. If it was created concurrently, return the existing one.
+	t, _ := s.addPublicTopic(newTopic(name, TPublic, s.logger, opts...))
+
+	return t
+}
+
+// Add public topic to the sSEPubSubService and inform all clients about the new topic
+// If a topic with the same name already exists, it is returned with false.
+func (s *SSEPubSubService) addPublicTopic(t *Topic) (*Topic, bool) {
 	s.lock.Lock()
+	if existing, ok := s.publicTopics[t.GetName()]; ok {
+		s.lock.Unlock()
+		return existing, false
+	}
+	t.pubErrors = &s.onPubError
 	s.publicTopics[t.GetName()] = t
 	s.lock.Unlock()
 
+	// Emit event
+	s.onNewPublicTopic.emit(t)
+
 	// Inform all clients about the new topic
 	for _, c := range s.GetClients() {
 		if err := c.sendTopicList(); err != nil {
-			log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+			s.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
 		}
+		c.onNewTopic.emit(t)
+		c.onNewPublicTopic.emit(t)
 	}
 
-	return t
+	return t, true
+}
+
+// Rename a public topic
+// Publishes of the topic are not interleaved with the rename: a publish either completed before
+// the clients are informed, or it uses the new name.
+// 0. Get the topic, return error if it does not exist
+// 1. Wait for running publishes of the topic
+// 2. Check that the new name is not taken and move the topic to the new name
+// 3. Send a topic_renamed sys event with the old and the new name to all clients
+func (s *SSEPubSubService) RenamePublicTopic(oldName, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("topic name must not be empty")
+	}
+
+	// Get the topic
+	t, ok := s.GetPublicTopicByName(oldName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", oldName)
+	}
+
+	// Wait for running publishes and block new ones until the clients are informed
+	t.renameLock.Lock()
+	defer t.renameLock.Unlock()
+
+	// Move the topic to the new name
+	s.lock.Lock()
+	if s.publicTopics[oldName] != t {
+		s.lock.Unlock()
+		return fmt.Errorf("topic %s does not exist", oldName)
+	}
+	if _, ok := s.publicTopics[newName]; ok {
+		s.lock.Unlock()
+		return fmt.Errorf("topic %s already exists", newName)
+	}
+	delete(s.publicTopics, oldName)
+	s.publicTopics[newName] = t
+	t.lock.Lock()
+	t.name = newName
+	t.lock.Unlock()
+	s.lock.Unlock()
+
+	// Inform all clients about the renamed topic
+	data, err := MarshalData(map[string]string{"old": oldName, "new": newName})
+	if err != nil {
+		return err
+	}
+	fulldata := &eventData{
+		Sys: []eventDataSys{{Type: "topic_renamed", Data: data}},
+	}
+	for _, c := range s.GetClients() {
+		if err := c.send(fulldata); err != nil {
+			s.logger.Errorf("[C:%s]: Error sending renamed topic to client: %s", c.id, err)
+		}
+	}
+
+	return nil
+}
+
+// Create new public topic with a message validator
+// fn is called with every message published to the topic. If it returns an error,
+// the publish returns it and the message is not sent. See WithValidator.
+// 0. Check if topic already exists, return error if it does
+// 1. Create a new public topic with the validator
+func (s *SSEPubSubService) NewPublicTopicWithValidator(name string, fn func(msg interface{}) error) (*Topic, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("validator must not be nil")
+	}
+
+	// Check if topic already exists, return error if it does
+	if _, ok := s.GetPublicTopicByName(name); ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	// Create a new public topic with the validator
+	t, ok := s.addPublicTopic(newTopic(name, TPublic, s.logger, WithValidator(fn)))
+	if !ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	return t, nil
+}
+
+// Create new read only public topic
+// Clients can not publish to this topic.
+// 0. Check if topic already exists, return error if it does
+// 1. Create a new public topic and mark it as read only
+func (s *SSEPubSubService) NewReadOnlyTopic(name string) (*Topic, error) {
+	// Check if topic already exists, return error if it does
+	if _, ok := s.GetPublicTopicByName(name); ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	// Create a new public topic and mark it as read only
+	t := newTopic(name, TPublic, s.logger)
+	t.SetReadOnly(true)
+	if _, ok := s.addPublicTopic(t); !ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	return t, nil
+}
+
+// Create new write only public topic
+// Clients can not subscribe to this topic.
+// 0. Check if topic already exists, return error if it does
+// 1. Create a new public topic and mark it as write only
+func (s *SSEPubSubService) NewWriteOnlyTopic(name string) (*Topic, error) {
+	// Check if topic already exists, return error if it does
+	if _, ok := s.GetPublicTopicByName(name); ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	// Create a new public topic and mark it as write only
+	t := newTopic(name, TPublic, s.logger)
+	t.SetWriteOnly(true)
+	if _, ok := s.addPublicTopic(t); !ok {
+		return nil, fmt.Errorf("topic %s already exists", name)
+	}
+
+	return t, nil
+}
+
+// Create a new topic with the configuration of an existing topic
+// The new topic has the same type as src and is added next to it: public topics to the sSEPubSubService,
+// group topics to the group of src and private topics to the client of src. Subscribers are not copied.
+// 0. Clone the configuration of src
+// 1. Add the new topic next to src, return error if a topic with the name already exists
+func (s *SSEPubSubService) NewTopicFromExisting(src *Topic, newName string) (*Topic, error) {
+	// Clone the configuration of src
+	t := src.clone(newName)
+
+	// Add the new topic next to src
+	added := false
+	switch src.GetTopicType() {
+	case TopicTypePublic:
+		_, added = s.addPublicTopic(t)
+	case TopicTypeGroup:
+		g, ok := s.groupOfTopic(src)
+		if !ok {
+			return nil, fmt.Errorf("group of topic %s does not exist", src.GetName())
+		}
+		_, added = g.addTopic(t)
+	case TopicTypePrivate:
+		c, ok := s.FindClient(func(c *Client) bool {
+			ct, ok := c.GetPrivateTopicByName(src.GetName())
+			return ok && ct == src
+		})
+		if !ok {
+			return nil, fmt.Errorf("client of topic %s does not exist", src.GetName())
+		}
+		_, added = c.addPrivateTopic(t)
+	}
+	if !added {
+		return nil, fmt.Errorf("topic %s already exists", newName)
+	}
+
+	return t, nil
 }
 
 // Remove public topic
 // 0. Check if topic is public
-// 1. Unsubscribe all clients from the topic
-// 2. Check if topic exists in sSEPubSubService
-// 3. Remove topic from sSEPubSubService
+// 1. Check if topic exists in sSEPubSubService
+// 2. Emit the remove event and unsubscribe all clients from the topic
+// 3. Remove topic from sSEPubSubService and cancel its scheduled publishes
 // 4. Inform all clients about the removed topic by sending the new topic list
 func (s *SSEPubSubService) RemovePublicTopic(t *Topic) {
 	// Check if topic is public
-	if t.GetType() != string(TPublic) {
-		log.Errorf("Topic %s is not public", t.GetName())
+	if t.GetTopicType() != TopicTypePublic {
+		s.logger.Errorf("Topic %s is not public", t.GetName())
 		return
 	}
 
@@ -267,14 +1188,20 @@ func (s *SSEPubSubService) RemovePublicTopic(t *Topic) {
 		return false
 	}
 	if !checkIfExist() {
-		log.Errorf("Topic %s does not exist in sSEPubSubService", t.GetName())
+		s.logger.Errorf("Topic %s does not exist in sSEPubSubService", t.GetName())
 		return
 	}
 
+	// Emit event while the topic still has all its clients
+	s.onRemovePublicTopic.emit(t)
+
+	// The topic is removed anyway, so it must not remove itself when the last client leaves
+	t.stopAutoDelete()
+
 	// Remove this topic from all clients
 	for _, c := range t.GetClients() {
 		if err := c.Unsub(t); err != nil {
-			log.Errorf("[C:%s]: Error unsubscribing from topic %s: %s", c.GetID(), t.GetName(), err)
+			s.logger.Errorf("[C:%s]: Error unsubscribing from topic %s: %s", c.GetID(), t.GetName(), err)
 		}
 	}
 
@@ -283,12 +1210,171 @@ func (s *SSEPubSubService) RemovePublicTopic(t *Topic) {
 	delete(s.publicTopics, t.GetName())
 	s.lock.Unlock()
 
+	// Cancel all pending scheduled publishes
+	t.CancelAllScheduled()
+
+	// Stop the workers of the consumer pool
+	if t.consumers != nil {
+		t.consumers.Close()
+	}
+
 	// Inform all clients about the removed topic by sending the new topic list
 	for _, c := range s.GetClients() {
 		if err := c.sendTopicList(); err != nil {
-			log.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+			s.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
 		}
+		c.onRemoveTopic.emit(t)
+		c.onRemovePublicTopic.emit(t)
+	}
+}
+
+// Publish a message to a public or group topic by name
+// The message is marshaled once and the same encoded frame is queued for every subscriber,
+// which is what every topic publish does. Already encoded JSON is embedded verbatim, see MarshalData.
+func (s *SSEPubSubService) PubJSON(topicName string, v interface{}) error {
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+	return t.Pub(v)
+}
+
+// Publish a message that is valid for ttl
+// The update contains an expires_at timestamp, so clients can discard stale messages.
+func (s *SSEPubSubService) PubWithTTL(topicName string, msg interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
 	}
+
+	u, ok, err := t.newUpdate(context.Background(), msg)
+	if err != nil || !ok {
+		return t.pubError(err)
+	}
+	u.ExpiresAt = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+	return t.pub(context.Background(), u, PriorityNormal)
+}
+
+// Publish a message to a topic and create it as public topic if it does not exist
+// Returns true if the topic was created.
+// 0. Get the topic by name
+// 1. Create a new public topic if it does not exist. If it was created concurrently, the existing one is used.
+// 2. Publish the message
+func (s *SSEPubSubService) PubOrCreate(topicName string, msg interface{}) (created bool, err error) {
+	// Get the topic by name
+	t, ok := s.getTopicByName(topicName)
+
+	// Create a new public topic if it does not exist
+	if !ok {
+		t, created = s.addPublicTopic(newTopic(topicName, TPublic, s.logger))
+	}
+
+	// Publish the message
+	return created, t.Pub(msg)
+}
+
+// Set the publish timeout of a topic
+// It overrides the write timeout of the sSEPubSubService. 0 resets it to the default.
+func (s *SSEPubSubService) SetTopicPublishTimeout(topicName string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("publish timeout must not be negative")
+	}
+
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	t.setPublishTimeout(d)
+	return nil
+}
+
+// Set metadata of a public topic and inform all clients by sending the new topic list
+// The metadata is sent to the clients in the meta field of the topic list.
+// 0. Get the public topic, return error if it does not exist
+// 1. Check that all values can be JSON encoded, return error if one can not
+// 2. Set the metadata
+// 3. Inform all clients about the new metadata
+func (s *SSEPubSubService) SetTopicMetadata(topicName string, metadata map[string]interface{}) error {
+	// Get the public topic
+	t, ok := s.GetPublicTopicByName(topicName)
+	if !ok {
+		return fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	// Check all values. Nothing is set if one of them is invalid.
+	for k, v := range metadata {
+		if _, err := json.Marshal(v); err != nil {
+			return fmt.Errorf("metadata %s of topic %s: %w", k, topicName, err)
+		}
+	}
+
+	// Set the metadata
+	t.metadataLock.Lock()
+	for k, v := range metadata {
+		t.metadata[k] = v
+	}
+	t.metadataLock.Unlock()
+
+	// Inform all clients about the new metadata
+	for _, c := range s.GetClients() {
+		if err := c.sendTopicList(); err != nil {
+			s.logger.Errorf("[C:%s]: Error sending new topic to client: %s", c.id, err)
+		}
+	}
+
+	return nil
+}
+
+// Set the write policy of a topic
+// WriteSerial guarantees that two Pub calls in sequence reach every subscriber in the same order.
+func (s *SSEPubSubService) SetTopicWritePolicy(topicName string, policy WritePolicy) {
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		s.logger.Errorf("Topic %s does not exist in sSEPubSubService", topicName)
+		return
+	}
+
+	t.setWritePolicy(policy)
+}
+
+// TopicSubEntry is a subscription of a client to a public topic.
+type TopicSubEntry struct {
+	TopicName    string
+	ClientID     string
+	SubscribedAt time.Time
+}
+
+// Get all subscriptions to public topics
+// The entries are sorted by topic name, then by client ID.
+func (s *SSEPubSubService) TopicSubMatrix() []TopicSubEntry {
+	entries := []TopicSubEntry{}
+	for _, t := range s.GetPublicTopics() {
+		for _, c := range t.GetClients() {
+			at, ok := t.GetSubscribedAt(c)
+			if !ok {
+				// Unsubscribed in the meantime
+				continue
+			}
+			entries = append(entries, TopicSubEntry{
+				TopicName:    t.GetName(),
+				ClientID:     c.GetID(),
+				SubscribedAt: at,
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].TopicName != entries[j].TopicName {
+			return entries[i].TopicName < entries[j].TopicName
+		}
+		return entries[i].ClientID < entries[j].ClientID
+	})
+	return entries
 }
 
 // Get public topics
@@ -305,6 +1391,12 @@ func (s *SSEPubSubService) GetPublicTopics() map[string]*Topic {
 	return newmap
 }
 
+// Get public topics sorted by name
+// Unlike GetPublicTopics, the order is stable, e.g. for listings.
+func (s *SSEPubSubService) ListPublicTopics() []*Topic {
+	return sortTopicsByName(s.GetPublicTopics())
+}
+
 // Get public topic by name
 func (s *SSEPubSubService) GetPublicTopicByName(name string) (*Topic, bool) {
 	s.lock.Lock()
@@ -313,3 +1405,39 @@ func (s *SSEPubSubService) GetPublicTopicByName(name string) (*Topic, bool) {
 	t, ok := s.publicTopics[name]
 	return t, ok
 }
+
+// Check if a public or group topic with the name exists
+func (s *SSEPubSubService) TopicExists(name string) bool {
+	_, ok := s.getTopicByName(name)
+	return ok
+}
+
+// Get topic by name
+// Looks up public topics first, then group topics
+func (s *SSEPubSubService) getTopicByName(name string) (*Topic, bool) {
+	if t, ok := s.GetPublicTopicByName(name); ok {
+		return t, true
+	}
+	var topic *Topic
+	s.ForEachGroup(func(g *Group) bool {
+		if t, ok := g.GetTopicByName(name); ok {
+			topic = t
+			return false
+		}
+		return true
+	})
+	return topic, topic != nil
+}
+
+// Get the group of a group topic
+func (s *SSEPubSubService) groupOfTopic(t *Topic) (*Group, bool) {
+	var group *Group
+	s.ForEachGroup(func(g *Group) bool {
+		if gt, ok := g.GetTopicByName(t.GetName()); ok && gt == t {
+			group = g
+			return false
+		}
+		return true
+	})
+	return group, group != nil
+}
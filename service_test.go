@@ -1,41 +1,152 @@
 package pubsubsse
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Tests for:
+// +NewSSEPubSubService(opts ...Option): *SSEPubSubService, error
+// +MustNewSSEPubSubService(opts ...Option): *SSEPubSubService
 // +NewClient(): *client
 // +RemoveClient(c *client)
 // +GetClients(): map[string]*client
 // +GetClientByID(id string): *client, bool
+// +ClientExists(id string): bool
+// +SetClientCustomData(clientID string, data interface{}): error
+// +GetClientCustomData(clientID string): interface{}, error
+// +BroadcastAll(data interface{}): error
+// +PubToClient(clientID, topicName string, data interface{}): error
+// +FindClient(predicate func(*Client) bool): *client, bool
+// +WaitForClient(ctx context.Context, id string): *client, error
+// +CloseAllConnections(): error
 
 // +NewGroup(name string): *group
+// +NewPublicTopicGroup(groupName string, names []string): []*topic, *group, error
 // +RemoveGroup(g *group)
 // +GetGroups(): map[string]*group
 // +GetGroupByName(name string): *group, bool
+// +GroupCount(): int
+// +ForEachGroup(fn func(g *group) bool)
+// +PubToGroup(groupName string, data interface{}): error
+// +ClientHasGroup(clientID, groupName string): bool, error
 
 // +NewPublicTopic(name string): *topic
+// +NewReadOnlyTopic(name string): *topic, error
+// +NewPublicTopicWithValidator(name string, fn func(msg interface{}) error): *topic, error
+// +NewWriteOnlyTopic(name string): *topic, error
+// +NewTopicFromExisting(src *topic, newName string): *topic, error
 // +RemovePublicTopic(t *topic)
 // +GetPublicTopics(): map[string]*topic
+// +ListPublicTopics(): []*topic
 // +GetPublicTopicByName(name string): *topic, bool
+// +TopicExists(name string): bool
+// +RenamePublicTopic(oldName, newName string): error
+// +SetTopicWritePolicy(topicName string, policy WritePolicy)
+// +SetTopicMetadata(topicName string, metadata map[string]interface{}): error
+// +PubWithTTL(topicName string, msg interface{}, ttl time.Duration): error
+// +PubJSON(topicName string, v interface{}): error
+// +PubOrCreate(topicName string, msg interface{}): bool, error
+// +ForEachPublicTopic(fn func(t *topic) bool)
+// +PubToTopicType(ttype topicType, msg interface{}): error
+// +PubToGroupTopics(groupName string, msg interface{}): error
+// +SetTopicPublishTimeout(topicName string, d time.Duration): error
+// +TopicSubMatrix(): []TopicSubEntry
 
 
 // Create a new SSEPubSubService
 func TestNewSSEPubSubService(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
-	if ssePubSub == nil {
-		t.Error("SSEPubSubService not created")
+	ssePubSub, err := NewSSEPubSubService()
+	if err != nil || ssePubSub == nil {
+		t.Errorf("SSEPubSubService not created: %v", err)
 	}
 }
 
+// Invalid and conflicting options are rejected
+func TestNewSSEPubSubService_InvalidOptions(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"write timeout":    {WithWriteTimeout(-time.Second)},
+		"shutdown timeout": {WithShutdownTimeout(-time.Second)},
+		"retry hint":       {WithRetryHint(-time.Second)},
+		"ping interval":    {WithPingInterval(-time.Second)},
+		"broadcast topic":  {WithBroadcastTopicName("")},
+		"ping topic":       {WithBroadcastTopicName(pingTopicName)},
+		"import mode":      {WithImportMode(ImportMode(42))},
+		"empty path":       {WithPath(EndpointEvent, "")},
+		"relative path":    {WithPath(EndpointEvent, "event")},
+		"unknown endpoint": {WithPath(Endpoint("unknown"), "/unknown")},
+		"same path":        {WithPath(EndpointEvent, "/stream"), WithPath(EndpointSubscribe, "/stream")},
+		"default path":     {WithPath(EndpointEvent, DefaultSubscribePath)},
+	} {
+		if s, err := NewSSEPubSubService(opts...); !errors.Is(err, ErrInvalidOption) || s != nil {
+			t.Errorf("%s: Expected ErrInvalidOption, got %v", name, err)
+		}
+	}
+
+	// All problems are reported
+	_, err := NewSSEPubSubService(WithWriteTimeout(-time.Second), WithRetryHint(-time.Second))
+	if err == nil || !strings.Contains(err.Error(), "write timeout") || !strings.Contains(err.Error(), "retry hint") {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+
+	// Valid options
+	if _, err := NewSSEPubSubService(WithWriteTimeout(0), WithPath(EndpointEvent, "/stream"), WithImportMode(ImportModeReplace)); err != nil {
+		t.Errorf("Expected valid options, got %s", err)
+	}
+
+	// MustNewSSEPubSubService panics
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected MustNewSSEPubSubService to panic")
+		}
+	}()
+	MustNewSSEPubSubService(WithRetryHint(-time.Second))
+}
+
 // --------------------------------------------
 // Clients
 // --------------------------------------------
 
+// GetClients returns a copy that can be used while clients are created concurrently
+func TestSSEPubSubService_GetClientsConcurrent(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.NewClient()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			ssePubSub.NewClient()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			// Iterate and modify the copy
+			clients := ssePubSub.GetClients()
+			for id := range clients {
+				delete(clients, id)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if len(ssePubSub.GetClients()) != 201 {
+		t.Errorf("Expected 201 clients, got %d", len(ssePubSub.GetClients()))
+	}
+}
+
 // Create a new client and get it by id
 func TestSSEPubSubService_NewClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	clientc := ssePubSub.NewClient()
 	client, ok := ssePubSub.GetClientByID(clientc.GetID())
 	if !ok {
@@ -51,7 +162,7 @@ func TestSSEPubSubService_NewClient(t *testing.T) {
 
 // Create a new client and remove it
 func TestSSEPubSubService_RemoveClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	client := ssePubSub.NewClient()
 	ssePubSub.RemoveClient(client)
 	_, ok := ssePubSub.GetClientByID(client.GetID())
@@ -65,7 +176,7 @@ func TestSSEPubSubService_RemoveClient(t *testing.T) {
 
 // Create a new client and get all clients
 func TestSSEPubSubService_GetClients(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	clientc := ssePubSub.NewClient()
 	clients := ssePubSub.GetClients()
 	if len(clients) != 1 {
@@ -81,7 +192,7 @@ func TestSSEPubSubService_GetClients(t *testing.T) {
 
 // Create a new client and get it by id
 func TestSSEPubSubService_GetClientByID(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	clientc := ssePubSub.NewClient()
 	client, ok := ssePubSub.GetClientByID(clientc.GetID())
 	if !ok {
@@ -95,13 +206,178 @@ func TestSSEPubSubService_GetClientByID(t *testing.T) {
 	}
 }
 
+// Set and get the custom data of a client
+func TestSSEPubSubService_ClientCustomData(t *testing.T) {
+	type userProfile struct {
+		Name string
+	}
+
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.SetClientCustomData("unknown", nil); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+	if _, err := ssePubSub.GetClientCustomData("unknown"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+
+	client := ssePubSub.NewClient()
+	data, err := ssePubSub.GetClientCustomData(client.GetID())
+	if err != nil || data != nil {
+		t.Error("Expected no custom data")
+	}
+
+	profile := &userProfile{Name: "test"}
+	if err := ssePubSub.SetClientCustomData(client.GetID(), profile); err != nil {
+		t.Error(err)
+	}
+	data, err = ssePubSub.GetClientCustomData(client.GetID())
+	if err != nil {
+		t.Error(err)
+	}
+	if p, ok := data.(*userProfile); !ok || p != profile {
+		t.Error("Custom data not stored")
+	}
+}
+
+// Broadcast to all clients and check that clients without subscriptions receive it
+func TestSSEPubSubService_BroadcastAll(t *testing.T) {
+	for _, tc := range []struct {
+		opts  []Option
+		topic string
+	}{
+		{nil, "__broadcast__"},
+		{[]Option{WithBroadcastTopicName("announcements")}, "announcements"},
+	} {
+		ssePubSub := MustNewSSEPubSubService(tc.opts...)
+		client1 := ssePubSub.NewClient()
+		client2 := ssePubSub.NewClient()
+		events1, stop1 := startClient(t, client1)
+		events2, stop2 := startClient(t, client2)
+
+		if err := ssePubSub.BroadcastAll("maintenance"); err != nil {
+			t.Error(err)
+		}
+		stop1()
+		stop2()
+
+		for _, events := range [][]eventData{events1(), events2()} {
+			received := false
+			for _, d := range events {
+				for _, u := range d.Updates {
+					if u.Topic == tc.topic && decodeData(u.Data) == "maintenance" {
+						received = true
+					}
+				}
+			}
+			if !received {
+				t.Errorf("Client did not receive the broadcast on topic %s", tc.topic)
+			}
+		}
+	}
+}
+
+// Publish to one client without a subscription
+func TestSSEPubSubService_PubToClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubToClient("unknown", "test", "testdata"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+
+	client := ssePubSub.NewClient()
+	other := ssePubSub.NewClient()
+	private := other.NewPrivateTopic("private")
+
+	// Without an event stream the message is delivered when the stream starts
+	if client.GetStatus() != Waiting {
+		t.Fatal("Expected status Waiting")
+	}
+	if err := ssePubSub.PubToClient(client.GetID(), "waiting", "zeroth"); err != nil {
+		t.Error(err)
+	}
+
+	events, stop := startClient(t, client)
+	otherEvents, stopOther := startClient(t, other)
+
+	// Neither the topic nor a subscription is required, not even access to the topic
+	if err := ssePubSub.PubToClient(client.GetID(), "unknown", "first"); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.PubToClient(client.GetID(), private.GetName(), json.RawMessage(`{"a":1}`)); err != nil {
+		t.Error(err)
+	}
+
+	stop()
+	stopOther()
+
+	updates := []eventDataUpdates{}
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 3 || updates[0].Topic != "waiting" || decodeData(updates[0].Data) != "zeroth" ||
+		updates[1].Topic != "unknown" || decodeData(updates[1].Data) != "first" ||
+		updates[2].Topic != "private" || string(updates[2].Data) != `{"a":1}` {
+		t.Errorf("Unexpected updates: %+v", updates)
+	}
+	for _, d := range otherEvents() {
+		if len(d.Updates) > 0 {
+			t.Error("Other client received the message")
+		}
+	}
+
+	// At most the stream size of the client is queued
+	small, err := ssePubSub.NewClientWithOptions("", ClientOptions{MaxBuffer: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ssePubSub.PubToClient(small.GetID(), "test", "first"); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.PubToClient(small.GetID(), "test", "second"); err == nil {
+		t.Error("Expected error if the queue of a client that is not receiving is full")
+	}
+}
+
+// Find a client by its custom data
+func TestSSEPubSubService_FindClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	byName := func(name string) func(*Client) bool {
+		return func(c *Client) bool { return c.GetCustomData() == name }
+	}
+
+	if _, ok := ssePubSub.FindClient(byName("alice")); ok {
+		t.Error("Expected no client")
+	}
+
+	alice := ssePubSub.NewClient()
+	alice.SetCustomData("alice")
+	bob := ssePubSub.NewClient()
+	bob.SetCustomData("bob")
+
+	client, ok := ssePubSub.FindClient(byName("bob"))
+	if !ok || client != bob {
+		t.Error("Client bob not found")
+	}
+	if _, ok := ssePubSub.FindClient(byName("carol")); ok {
+		t.Error("Expected no client")
+	}
+
+	// predicate can use the sSEPubSubService
+	client, ok = ssePubSub.FindClient(func(c *Client) bool {
+		found, _ := ssePubSub.GetClientByID(c.GetID())
+		return found == alice
+	})
+	if !ok || client != alice {
+		t.Error("Client alice not found")
+	}
+}
+
 // --------------------------------------------
 // Groups
 // --------------------------------------------
 
 // Create a new group and get it by name
 func TestSSEPubSubService_NewGroup(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	groupc := ssePubSub.NewGroup("test")
 	group, ok := ssePubSub.GetGroupByName("test")
 	if !ok {
@@ -115,9 +391,66 @@ func TestSSEPubSubService_NewGroup(t *testing.T) {
 	}
 }
 
+// Create a new group with its topics
+func TestSSEPubSubService_NewPublicTopicGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	// The group has all its topics when OnNewGroup is called
+	topicCount := -1
+	ssePubSub.OnNewGroup(func(g *Group) { topicCount = len(g.GetTopics()) })
+
+	topics, group, err := ssePubSub.NewPublicTopicGroup("room", []string{"chat", "slides"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := ssePubSub.GetGroupByName("room"); !ok || g != group {
+		t.Error("Expected the group to be added")
+	}
+	if topicCount != 2 {
+		t.Errorf("Expected the group to have 2 topics when it is added, got %d", topicCount)
+	}
+	if len(topics) != 2 || topics[0].GetName() != "chat" || topics[1].GetName() != "slides" {
+		t.Fatalf("Expected the topics chat and slides, got %v", topics)
+	}
+	for _, topic := range topics {
+		if gt, ok := group.GetTopicByName(topic.GetName()); !ok || gt != topic || topic.GetTopicType() != TopicTypeGroup {
+			t.Errorf("Expected %s to be a group topic of the group", topic.GetName())
+		}
+	}
+
+	// Clients of the group can subscribe to the topics
+	client := ssePubSub.NewClient()
+	group.AddClient(client)
+	if err := client.Sub(topics[0]); err != nil {
+		t.Error(err)
+	}
+
+	// Invalid names and existing groups
+	for _, names := range [][]string{{"a", ""}, {"a", "a"}} {
+		if _, _, err := ssePubSub.NewPublicTopicGroup("other", names); err == nil {
+			t.Errorf("Expected error for the names %v", names)
+		}
+	}
+	if _, ok := ssePubSub.GetGroupByName("other"); ok {
+		t.Error("Expected no group for invalid names")
+	}
+	if _, _, err := ssePubSub.NewPublicTopicGroup("", []string{"a"}); err == nil {
+		t.Error("Expected error for an empty group name")
+	}
+	if _, ok := ssePubSub.GetGroupByName(""); ok {
+		t.Error("Expected no group for an empty group name")
+	}
+	if _, _, err := ssePubSub.NewPublicTopicGroup("room", []string{"video"}); err == nil {
+		t.Error("Expected error for an existing group")
+	}
+	if _, ok := group.GetTopicByName("video"); ok {
+		t.Error("Expected the existing group not to be changed")
+	}
+}
+
 // Create a new group and remove it
 func TestSSEPubSubService_RemoveGroup(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	group := ssePubSub.NewGroup("test")
 	ssePubSub.RemoveGroup(group)
 	_, ok := ssePubSub.GetGroupByName(group.GetName())
@@ -131,7 +464,7 @@ func TestSSEPubSubService_RemoveGroup(t *testing.T) {
 
 // Create a new group and get all groups
 func TestSSEPubSubService_GetGroups(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	groupc := ssePubSub.NewGroup("test")
 	groups := ssePubSub.GetGroups()
 	if len(groups) != 1 {
@@ -147,7 +480,7 @@ func TestSSEPubSubService_GetGroups(t *testing.T) {
 
 // Create a new group and get it by name
 func TestSSEPubSubService_GetGroupByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	groupc := ssePubSub.NewGroup("test")
 	group, ok := ssePubSub.GetGroupByName("test")
 	if !ok {
@@ -161,13 +494,206 @@ func TestSSEPubSubService_GetGroupByName(t *testing.T) {
 	}
 }
 
+// Create and remove groups and count them
+func TestSSEPubSubService_GroupCount(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if ssePubSub.GroupCount() != 0 {
+		t.Error("GroupCount() != 0")
+	}
+
+	group1 := ssePubSub.NewGroup("test1")
+	ssePubSub.NewGroup("test1")
+	ssePubSub.NewGroup("test2")
+	if ssePubSub.GroupCount() != 2 {
+		t.Error("GroupCount() != 2")
+	}
+
+	ssePubSub.RemoveGroup(group1)
+	ssePubSub.RemoveGroup(group1)
+	if ssePubSub.GroupCount() != 1 {
+		t.Error("GroupCount() != 1")
+	}
+}
+
+// Iterate over all groups and stop early
+func TestSSEPubSubService_ForEachGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.NewGroup("test1")
+	ssePubSub.NewGroup("test2")
+	ssePubSub.NewGroup("test3")
+
+	seen := map[string]bool{}
+	ssePubSub.ForEachGroup(func(g *Group) bool {
+		seen[g.GetName()] = true
+		return true
+	})
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 groups, got %d", len(seen))
+	}
+
+	count := 0
+	ssePubSub.ForEachGroup(func(g *Group) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after 1 group, got %d", count)
+	}
+
+	// fn can use the sSEPubSubService
+	ssePubSub.ForEachGroup(func(g *Group) bool {
+		ssePubSub.RemoveGroup(g)
+		return true
+	})
+	if ssePubSub.GroupCount() != 0 {
+		t.Errorf("Expected all groups to be removed, got %d", ssePubSub.GroupCount())
+	}
+}
+
+// Publish to a group and check that only group members receive it
+func TestSSEPubSubService_PubToGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubToGroup("test", "testdata"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Expected ErrGroupNotFound, got %v", err)
+	}
+
+	group := ssePubSub.NewGroup("test")
+	member := ssePubSub.NewClient()
+	other := ssePubSub.NewClient()
+	group.AddClient(member)
+
+	memberEvents, stopMember := startClient(t, member)
+	otherEvents, stopOther := startClient(t, other)
+
+	if err := ssePubSub.PubToGroup("test", "testdata"); err != nil {
+		t.Error(err)
+	}
+
+	stopMember()
+	stopOther()
+
+	received := false
+	for _, d := range memberEvents() {
+		if len(d.Updates) > 0 && d.Updates[0].Group == "test" && decodeData(d.Updates[0].Data) == "testdata" {
+			received = true
+		}
+	}
+	if !received {
+		t.Error("Group member did not receive the broadcast")
+	}
+	for _, d := range otherEvents() {
+		if len(d.Updates) > 0 {
+			t.Error("Client outside of the group received the broadcast")
+		}
+	}
+}
+
+// Check the group membership of a client
+func TestSSEPubSubService_ClientHasGroup(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("test")
+
+	if _, err := ssePubSub.ClientHasGroup("unknown", "test"); err != ErrClientNotFound {
+		t.Error("Expected ErrClientNotFound")
+	}
+	if _, err := ssePubSub.ClientHasGroup(client.GetID(), "unknown"); err != ErrGroupNotFound {
+		t.Error("Expected ErrGroupNotFound")
+	}
+
+	if ok, err := ssePubSub.ClientHasGroup(client.GetID(), "test"); ok || err != nil {
+		t.Error("Expected client not to be a member of the group")
+	}
+	group.AddClient(client)
+	if ok, err := ssePubSub.ClientHasGroup(client.GetID(), "test"); !ok || err != nil {
+		t.Error("Expected client to be a member of the group")
+	}
+	group.RemoveClient(client)
+	if ok, err := ssePubSub.ClientHasGroup(client.GetID(), "test"); ok || err != nil {
+		t.Error("Expected client not to be a member of the group")
+	}
+}
+
 // --------------------------------------------
 // Public Topics
 // --------------------------------------------
 
+// Rename a public topic while it is published to
+func TestSSEPubSubService_RenamePublicTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("old")
+	ssePubSub.NewPublicTopic("taken")
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ssePubSub.RenamePublicTopic("unknown", "new"); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	if err := ssePubSub.RenamePublicTopic("old", "taken"); err == nil {
+		t.Error("Expected error for a taken name")
+	}
+
+	events, stop := startClient(t, client)
+
+	// Publish concurrently to the rename
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := topic.Pub(i); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	if err := ssePubSub.RenamePublicTopic("old", "new"); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+	if err := topic.Pub("after"); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	if _, ok := ssePubSub.GetPublicTopicByName("old"); ok {
+		t.Error("Expected the old name to be free")
+	}
+	if renamed, ok := ssePubSub.GetPublicTopicByName("new"); !ok || renamed != topic || topic.GetName() != "new" {
+		t.Error("Expected the topic under the new name")
+	}
+
+	// Updates before the event use the old name, all updates after it the new name
+	renamed := false
+	updates := 0
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			if sys.Type == "topic_renamed" {
+				if string(sys.Data) != `{"new":"new","old":"old"}` {
+					t.Errorf("Unexpected event data %s", sys.Data)
+				}
+				renamed = true
+			}
+		}
+		for _, u := range d.Updates {
+			updates++
+			if expected := map[bool]string{false: "old", true: "new"}[renamed]; u.Topic != expected {
+				t.Errorf("Expected topic %s, got %s", expected, u.Topic)
+			}
+		}
+	}
+	if !renamed {
+		t.Error("Expected the topic_renamed event")
+	}
+	if updates != 51 {
+		t.Errorf("Expected 51 updates, got %d", updates)
+	}
+}
+
 // Create a new public topic and get it by name
 func TestSSEPubSubService_NewPublicTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	topicc := ssePubSub.NewPublicTopic("test")
 	topic, ok := ssePubSub.GetPublicTopicByName("test")
 	if !ok {
@@ -181,9 +707,158 @@ func TestSSEPubSubService_NewPublicTopic(t *testing.T) {
 	}
 }
 
+// Create a topic with a validator and check that invalid messages are not sent
+func TestSSEPubSubService_NewPublicTopicWithValidator(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	errTooLong := errors.New("message too long")
+	topic, err := ssePubSub.NewPublicTopicWithValidator("chat", func(msg interface{}) error {
+		if s, ok := msg.(string); ok && len(s) > 5 {
+			return errTooLong
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ssePubSub.NewPublicTopicWithValidator("chat", func(interface{}) error { return nil }); err == nil {
+		t.Error("Expected error for existing topic")
+	}
+	if _, err := ssePubSub.NewPublicTopicWithValidator("other", nil); err == nil {
+		t.Error("Expected error for nil validator")
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := startClient(t, client)
+
+	if err := topic.Pub("hi"); err != nil {
+		t.Error(err)
+	}
+	if err := topic.Pub("too long"); !errors.Is(err, errTooLong) {
+		t.Errorf("Expected the validator error, got %v", err)
+	}
+	if err := <-topic.PubAsync("too long"); !errors.Is(err, errTooLong) {
+		t.Errorf("Expected the validator error from PubAsync, got %v", err)
+	}
+	// A transaction is sent completely or not at all
+	err = topic.TxPublish(func(tx *TopicTx) error {
+		tx.Pub("ok")
+		tx.Pub("too long")
+		return nil
+	})
+	if !errors.Is(err, errTooLong) {
+		t.Errorf("Expected the validator error from TxPublish, got %v", err)
+	}
+	stop()
+
+	if data := updatesData(events()); len(data) != 1 || data[0] != "hi" {
+		t.Errorf("Expected only the valid message, got %v", data)
+	}
+}
+
+// Create a new read only topic and check the topics list
+func TestSSEPubSubService_NewReadOnlyTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	events, stop := startClient(t, client)
+
+	topic, err := ssePubSub.NewReadOnlyTopic("test")
+	if err != nil {
+		t.Error(err)
+	}
+	if !topic.IsReadOnly() {
+		t.Error("Topic is not read only")
+	}
+	if _, err := ssePubSub.NewReadOnlyTopic("test"); err == nil {
+		t.Error("Expected error for existing topic")
+	}
+	stop()
+
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			for _, l := range sys.List {
+				if sys.Type == "topics" && l.Name == "test" && l.ReadOnly {
+					return
+				}
+			}
+		}
+	}
+	t.Error("Expected readOnly in the topics list")
+}
+
+// Create a new write only topic and try to subscribe to it
+func TestSSEPubSubService_NewWriteOnlyTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	events, stop := startClient(t, client)
+
+	topic, err := ssePubSub.NewWriteOnlyTopic("test")
+	if err != nil {
+		t.Error(err)
+	}
+	if !topic.IsWriteOnly() {
+		t.Error("Topic is not write only")
+	}
+	if _, err := ssePubSub.NewWriteOnlyTopic("test"); err == nil {
+		t.Error("Expected error for existing topic")
+	}
+	if err := client.Sub(topic); err != ErrWriteOnlyTopic {
+		t.Error("Expected ErrWriteOnlyTopic")
+	}
+	stop()
+
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			for _, l := range sys.List {
+				if sys.Type == "topics" && l.Name == "test" && l.WriteOnly {
+					return
+				}
+			}
+		}
+	}
+	t.Error("Expected writeOnly in the topics list")
+}
+
+// Create public topics and clients concurrently. Must pass go test -race.
+func TestSSEPubSubService_NewPublicTopicConcurrent(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithLogger(NoOpLogger{}))
+
+	wg := sync.WaitGroup{}
+	topics := make([]*Topic, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			topics[i] = ssePubSub.NewPublicTopic("test")
+		}(i)
+		go func() {
+			defer wg.Done()
+			client := ssePubSub.NewClient()
+			client.GetAllTopics()
+		}()
+	}
+	wg.Wait()
+
+	// All callers must get the same topic
+	for _, topic := range topics {
+		if topic != topics[0] {
+			t.Error("NewPublicTopic created the topic twice")
+		}
+	}
+
+	// Clients created before and after the topic see it
+	for _, client := range ssePubSub.GetClients() {
+		if _, ok := client.GetPublicTopicByName("test"); !ok {
+			t.Error("Client does not see the public topic")
+		}
+	}
+}
+
 // Create a new public topic and remove it
 func TestSSEPubSubService_RemovePublicTopic(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	topic := ssePubSub.NewPublicTopic("test")
 	ssePubSub.RemovePublicTopic(topic)
 	_, ok := ssePubSub.GetPublicTopicByName(topic.GetName())
@@ -197,7 +872,7 @@ func TestSSEPubSubService_RemovePublicTopic(t *testing.T) {
 
 // Create a new public topic and get all public topics
 func TestSSEPubSubService_GetPublicTopics(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	topicc := ssePubSub.NewPublicTopic("test")
 	topics := ssePubSub.GetPublicTopics()
 	if len(topics) != 1 {
@@ -213,7 +888,7 @@ func TestSSEPubSubService_GetPublicTopics(t *testing.T) {
 
 // Create a new public topic and get it by name
 func TestSSEPubSubService_GetPublicTopicByName(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	topicc := ssePubSub.NewPublicTopic("test")
 	topic, ok := ssePubSub.GetPublicTopicByName("test")
 	if !ok {
@@ -226,3 +901,779 @@ func TestSSEPubSubService_GetPublicTopicByName(t *testing.T) {
 		t.Error("Public topic not found: wrong pointer")
 	}
 }
+
+// Set the metadata of a public topic and check the topic list of the client
+func TestSSEPubSubService_SetTopicMetadata(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	client := ssePubSub.NewClient()
+
+	if err := ssePubSub.SetTopicMetadata("unknown", map[string]interface{}{"region": "eu"}); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	if err := ssePubSub.SetTopicMetadata("test", map[string]interface{}{"region": "eu", "invalid": func() {}}); err == nil {
+		t.Error("Expected error for a value that can not be encoded")
+	}
+	if _, ok := topic.GetMetadataKey("region"); ok {
+		t.Error("Expected no metadata to be set after an error")
+	}
+
+	events, stop := startClient(t, client)
+	if err := ssePubSub.SetTopicMetadata("test", map[string]interface{}{"region": "eu", "version": 2, "owner": "team-a"}); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	// The values keep their type
+	expected := map[string]interface{}{"region": "eu", "version": 2, "owner": "team-a"}
+	if metadata := topic.GetMetadata(); fmt.Sprint(metadata) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, metadata)
+	}
+	if v, ok := topic.GetMetadataKey("version"); !ok || v != 2 {
+		t.Errorf("Expected version 2 as int, got %#v", v)
+	}
+
+	// The new topic list contains the metadata
+	var list []eventDataSysList
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			if sys.Type == "topics" {
+				list = sys.List
+			}
+		}
+	}
+	if len(list) != 1 || fmt.Sprint(list[0].Metadata) != fmt.Sprint(expected) {
+		t.Errorf("Expected the metadata in the topic list, got %+v", list)
+	}
+	if data, err := json.Marshal(list[0]); err != nil || !strings.Contains(string(data), `"meta":{"owner":"team-a","region":"eu","version":2}`) {
+		t.Errorf("Expected the metadata in the meta field, got %s", data)
+	}
+}
+
+// Set the write policy of a topic and check the order of the received messages
+func TestSSEPubSubService_SetTopicWritePolicy(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	if topic.GetWritePolicy() != WriteConcurrent {
+		t.Error("Default write policy is not WriteConcurrent")
+	}
+
+	ssePubSub.SetTopicWritePolicy("test", WriteSerial)
+	if topic.GetWritePolicy() != WriteSerial {
+		t.Error("Write policy not set")
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	events, stop := startClient(t, client)
+
+	for i := 0; i < 5; i++ {
+		if err := topic.Pub(float64(i)); err != nil {
+			t.Error(err)
+		}
+	}
+	stop()
+
+	i := 0
+	for _, d := range events() {
+		for _, u := range d.Updates {
+			if decodeData(u.Data) != float64(i) {
+				t.Errorf("Expected message %d, got %s", i, u.Data)
+			}
+			i++
+		}
+	}
+	if i != 5 {
+		t.Errorf("Expected 5 messages, got %d", i)
+	}
+}
+
+// Publish JSON to a topic by name, every subscriber gets the same frame
+func TestSSEPubSubService_PubJSON(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubJSON("unknown", "testdata"); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test")
+	clients := []*Client{ssePubSub.NewClient(), ssePubSub.NewClient()}
+	events := make([]func() []eventData, len(clients))
+	stops := make([]func(), len(clients))
+	for i, c := range clients {
+		if err := c.Sub(topic); err != nil {
+			t.Fatal(err)
+		}
+		events[i], stops[i] = startClient(t, c)
+	}
+
+	if err := ssePubSub.PubJSON("test", map[string]int{"a": 1}); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.PubJSON("test", json.RawMessage(`{"b":2}`)); err != nil {
+		t.Error(err)
+	}
+	for i := range clients {
+		stops[i]()
+		data := []string{}
+		for _, d := range events[i]() {
+			for _, u := range d.Updates {
+				data = append(data, string(u.Data))
+			}
+		}
+		if strings.Join(data, ",") != `{"a":1},{"b":2}` {
+			t.Errorf("Unexpected data of client %d: %v", i, data)
+		}
+	}
+}
+
+// Publish a message with a TTL and check the expires_at field
+func TestSSEPubSubService_PubWithTTL(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubWithTTL("test", "testdata", time.Minute); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := ssePubSub.PubWithTTL("test", "testdata", 0); err == nil {
+		t.Error("Expected error for invalid ttl")
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	events, stop := startClient(t, client)
+
+	if err := ssePubSub.PubWithTTL("test", "testdata", time.Minute); err != nil {
+		t.Error(err)
+	}
+	if err := topic.Pub("testdata"); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	updates := []eventDataUpdates{}
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %d", len(updates))
+	}
+	expiresAt, err := time.Parse(time.RFC3339, updates[0].ExpiresAt)
+	if err != nil {
+		t.Error(err)
+	}
+	if expiresAt.Before(time.Now()) || expiresAt.After(time.Now().Add(time.Minute+time.Second)) {
+		t.Error("expires_at is not within the ttl")
+	}
+	if updates[1].ExpiresAt != "" {
+		t.Error("Expected no expires_at without ttl")
+	}
+}
+
+// Wait for an existing and a missing client
+func TestSSEPubSubService_WaitForClient(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+
+	c, err := ssePubSub.WaitForClient(context.Background(), client.GetID())
+	if err != nil || c != client {
+		t.Error("Expected the existing client")
+	}
+
+	// Early cancellation while other clients are created
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := ssePubSub.WaitForClient(ctx, "unknown")
+		done <- err
+	}()
+	ssePubSub.NewClient()
+	ssePubSub.NewClient()
+	select {
+	case <-done:
+		t.Fatal("Expected WaitForClient to block for an unknown client")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected WaitForClient to return after cancel")
+	}
+
+	// Deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ssePubSub.WaitForClient(ctx, "unknown"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// Use clients, groups and topics concurrently. Inconsistent lock ordering would deadlock.
+func TestSSEPubSubService_LockOrdering(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.NewTopic("grouptopic")
+	ssePubSub.NewPublicTopic("test")
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for _, f := range []func(i int){
+		func(i int) {
+			group.AddClient(client)
+			group.RemoveClient(client)
+		},
+		func(i int) {
+			client.GetAllTopics()
+			client.GetSubscribedTopics()
+		},
+		func(i int) {
+			client.RemovePrivateTopic(client.NewPrivateTopic("private"))
+		},
+		func(i int) {
+			ssePubSub.RemoveClient(ssePubSub.NewClient())
+		},
+		func(i int) {
+			if topic, ok := client.GetTopicByName("test"); ok {
+				client.Sub(topic)
+				topic.Pub(i)
+				client.Unsub(topic)
+			}
+		},
+	} {
+		wg.Add(1)
+		go func(f func(i int)) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				f(i)
+			}
+		}(f)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Deadlock")
+	}
+}
+
+// Close all event streams and reconnect a client
+func TestSSEPubSubService_CloseAllConnections(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+
+	// Start two clients, one of them with two event streams
+	clients := []*Client{ssePubSub.NewClient(), ssePubSub.NewClient()}
+	done := make(chan struct{}, 3)
+	for _, c := range clients {
+		if err := c.Sub(topic); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, c := range []*Client{clients[0], clients[0], clients[1]} {
+		c := c
+		go func() {
+			c.Start(context.Background(), func(string) {})
+			done <- struct{}{}
+		}()
+	}
+	for _, c := range clients {
+		for c.GetStatus() != Receving {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for {
+		clients[0].lock.Lock()
+		n := len(clients[0].connections)
+		clients[0].lock.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := ssePubSub.CloseAllConnections(); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.CloseAllConnections(); err != nil {
+		t.Error(err)
+	}
+
+	// All event streams return
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected all event streams to return")
+		}
+	}
+
+	// Clients and subscriptions are kept
+	if len(ssePubSub.GetClients()) != 2 {
+		t.Error("Expected clients to be kept")
+	}
+	if !topic.IsSubscribed(clients[0]) || !topic.IsSubscribed(clients[1]) {
+		t.Error("Expected subscriptions to be kept")
+	}
+
+	// A client can reconnect and receives messages again
+	events, stop := startClient(t, clients[0])
+	if err := topic.Pub("testdata"); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	updates := []eventDataUpdates{}
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 1 {
+		t.Errorf("Expected 1 update after reconnect, got %d", len(updates))
+	}
+}
+
+// Clone public, group and private topics
+func TestSSEPubSubService_NewTopicFromExisting(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	src := ssePubSub.NewPublicTopic("src", WithHistory(5, time.Minute))
+	src.SetMetadata("description", "template")
+	src.SetReadOnly(true)
+	ssePubSub.SetTopicWritePolicy("src", WriteSerial)
+	if err := ssePubSub.SetTopicPublishTimeout("src", time.Second); err != nil {
+		t.Error(err)
+	}
+	client := ssePubSub.NewClient()
+	if err := client.Sub(src); err != nil {
+		t.Error(err)
+	}
+
+	clone, err := ssePubSub.NewTopicFromExisting(src, "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.GetName() != "clone" || clone.GetID() == src.GetID() || clone.GetType() != string(TPublic) {
+		t.Error("Expected a new public topic")
+	}
+	if pt, ok := ssePubSub.GetPublicTopicByName("clone"); !ok || pt != clone {
+		t.Error("Expected the clone to be a public topic")
+	}
+	if v, _ := clone.GetMetadataKey("description"); v != "template" {
+		t.Error("Expected metadata to be copied")
+	}
+	if !clone.IsReadOnly() || clone.GetWritePolicy() != WriteSerial || clone.GetPublishTimeout() != time.Second {
+		t.Error("Expected configuration to be copied")
+	}
+	if clone.history == nil || len(clone.history.entries) != 5 {
+		t.Error("Expected history size to be copied")
+	}
+	if len(clone.GetClients()) != 0 {
+		t.Error("Expected no subscribers")
+	}
+	if _, err := ssePubSub.NewTopicFromExisting(src, "clone"); err == nil {
+		t.Error("Expected error for existing topic")
+	}
+
+	// Group topics are added to the group of src
+	group := ssePubSub.NewGroup("group")
+	groupClone, err := ssePubSub.NewTopicFromExisting(group.NewTopic("src"), "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gt, ok := group.GetTopicByName("clone"); !ok || gt != groupClone {
+		t.Error("Expected the clone to be a group topic")
+	}
+
+	// Private topics are added to the client of src
+	privClone, err := ssePubSub.NewTopicFromExisting(client.NewPrivateTopic("src"), "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct, ok := client.GetPrivateTopicByName("clone"); !ok || ct != privClone {
+		t.Error("Expected the clone to be a private topic")
+	}
+}
+
+// Publish to a missing and to an existing topic
+func TestSSEPubSubService_PubOrCreate(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+
+	created, err := ssePubSub.PubOrCreate("test", "testdata")
+	if err != nil {
+		t.Error(err)
+	}
+	if !created {
+		t.Error("Expected topic to be created")
+	}
+	topic, ok := ssePubSub.GetPublicTopicByName("test")
+	if !ok {
+		t.Fatal("Expected public topic to exist")
+	}
+
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	events, stop := startClient(t, client)
+
+	created, err = ssePubSub.PubOrCreate("test", "testdata")
+	if err != nil {
+		t.Error(err)
+	}
+	if created {
+		t.Error("Expected existing topic to be used")
+	}
+
+	// Group topics are used as well
+	group := ssePubSub.NewGroup("group")
+	group.NewTopic("grouptopic")
+	if created, _ := ssePubSub.PubOrCreate("grouptopic", "testdata"); created {
+		t.Error("Expected existing group topic to be used")
+	}
+	if _, ok := ssePubSub.GetPublicTopicByName("grouptopic"); ok {
+		t.Error("Expected no public topic for a group topic")
+	}
+	stop()
+
+	updates := []eventDataUpdates{}
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 1 || decodeData(updates[0].Data) != "testdata" {
+		t.Errorf("Expected 1 update, got %v", updates)
+	}
+}
+
+// Iterate over all public topics and stop early
+func TestSSEPubSubService_ForEachPublicTopic(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.NewPublicTopic("a")
+	ssePubSub.NewPublicTopic("b")
+	ssePubSub.NewGroup("group").NewTopic("c")
+
+	names := map[string]bool{}
+	ssePubSub.ForEachPublicTopic(func(topic *Topic) bool {
+		names[topic.GetName()] = true
+		return true
+	})
+	if len(names) != 2 || !names["a"] || !names["b"] {
+		t.Errorf("Expected the public topics a and b, got %v", names)
+	}
+
+	calls := 0
+	ssePubSub.ForEachPublicTopic(func(*Topic) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+
+	// fn can use the sSEPubSubService
+	ssePubSub.ForEachPublicTopic(func(topic *Topic) bool {
+		ssePubSub.RemovePublicTopic(topic)
+		return true
+	})
+	if len(ssePubSub.GetPublicTopics()) != 0 {
+		t.Errorf("Expected all public topics to be removed, got %d", len(ssePubSub.GetPublicTopics()))
+	}
+}
+
+// Publish to all topics of a type
+func TestSSEPubSubService_PubToTopicType(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubToTopicType("unknown", "testdata"); err == nil {
+		t.Error("Expected error for unknown topic type")
+	}
+
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client)
+	for _, topic := range []*Topic{
+		ssePubSub.NewPublicTopic("a"),
+		ssePubSub.NewPublicTopic("b"),
+		group.NewTopic("c"),
+		client.NewPrivateTopic("d"),
+	} {
+		if err := client.Sub(topic); err != nil {
+			t.Error(err)
+		}
+	}
+	events, stop := startClient(t, client)
+
+	for _, ttype := range []topicType{TPublic, TGroup, TPrivate} {
+		if err := ssePubSub.PubToTopicType(ttype, string(ttype)); err != nil {
+			t.Error(err)
+		}
+	}
+	stop()
+
+	topics := map[string]interface{}{}
+	for _, d := range events() {
+		for _, u := range d.Updates {
+			topics[u.Topic] = decodeData(u.Data)
+		}
+	}
+	expected := map[string]interface{}{"a": "public", "b": "public", "c": "group", "d": "private"}
+	if len(topics) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, topics)
+	}
+	for k, v := range expected {
+		if topics[k] != v {
+			t.Errorf("Expected %s on topic %s, got %v", v, k, topics[k])
+		}
+	}
+
+	// Failed topics are reported
+	if err := ssePubSub.NewCircuitBreaker("a", CircuitBreakerOptions{Threshold: 1, Window: time.Minute, OpenDuration: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	topicA, _ := ssePubSub.GetPublicTopicByName("a")
+	topicA.breaker.failure() // open the circuit
+	topicA.breaker.failure()
+	err := ssePubSub.PubToTopicType(TPublic, "testdata")
+	var pubErrs PublishErrors
+	if !errors.As(err, &pubErrs) || len(pubErrs) != 1 || pubErrs[0].TopicName != "a" {
+		t.Fatalf("Expected PublishErrors for topic a, got %v", err)
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Error("Expected ErrCircuitOpen")
+	}
+}
+
+// Publish to all topics of a group
+func TestSSEPubSubService_PubToGroupTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if err := ssePubSub.PubToGroupTopics("group", "testdata"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Expected ErrGroupNotFound, got %v", err)
+	}
+
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	group.AddClient(client)
+	errInvalid := errors.New("invalid")
+	for _, topic := range []*Topic{
+		group.NewTopic("a"),
+		group.NewTopic("b"),
+		group.NewTopic("c", WithValidator(func(interface{}) error { return errInvalid })),
+		ssePubSub.NewPublicTopic("public"), // not in the group
+	} {
+		if err := client.Sub(topic); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The hooks of the topics are called
+	published := 0
+	group.GetTopics()["a"].OnPub(func(interface{}) { published++ })
+
+	events, stop := startClient(t, client)
+	err := ssePubSub.PubToGroupTopics("group", "testdata")
+	stop()
+
+	// The validator of topic c rejects the message
+	var pubErrs PublishErrors
+	if !errors.As(err, &pubErrs) || len(pubErrs) != 1 || pubErrs[0].TopicName != "c" || !errors.Is(err, errInvalid) {
+		t.Fatalf("Expected PublishErrors for topic c, got %v", err)
+	}
+	if published != 1 {
+		t.Errorf("Expected OnPub of topic a to be called once, got %d", published)
+	}
+
+	topics := []string{}
+	for _, d := range events() {
+		for _, u := range d.Updates {
+			topics = append(topics, u.Topic)
+		}
+	}
+	sort.Strings(topics)
+	if strings.Join(topics, ",") != "a,b" {
+		t.Errorf("Expected updates of topics a and b, got %v", topics)
+	}
+}
+
+// Set the publish timeout of a topic and check how long a publish to a full stream blocks
+func TestSSEPubSubService_SetTopicPublishTimeout(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithWriteTimeout(20 * time.Millisecond))
+	if err := ssePubSub.SetTopicPublishTimeout("test", time.Second); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+
+	fast := ssePubSub.NewPublicTopic("fast")
+	slow := ssePubSub.NewPublicTopic("slow")
+	if err := ssePubSub.SetTopicPublishTimeout("slow", 200*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	if slow.GetPublishTimeout() != 200*time.Millisecond {
+		t.Error("Publish timeout not set")
+	}
+
+	client := ssePubSub.NewClient()
+	client.Sub(fast)
+	client.Sub(slow)
+
+	// Block the event stream, so the stream of the client fills up
+	gate := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(msg string) { <-gate })
+	}()
+	defer func() {
+		cancel()
+		close(gate)
+		<-done
+	}()
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < streamSize+1; i++ {
+		fast.Pub("fill")
+	}
+
+	start := time.Now()
+	fast.Pub("test")
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Errorf("Expected the default write timeout, publish took %s", d)
+	}
+
+	start = time.Now()
+	slow.Pub("test")
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("Expected the topic publish timeout, publish took %s", d)
+	}
+}
+
+// Subscribe clients to public topics and check the subscription matrix
+func TestSSEPubSubService_TopicSubMatrix(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if len(ssePubSub.TopicSubMatrix()) != 0 {
+		t.Error("Expected no subscriptions")
+	}
+
+	topicB := ssePubSub.NewPublicTopic("b")
+	topicA := ssePubSub.NewPublicTopic("a")
+	client1 := ssePubSub.NewClient()
+	client2 := ssePubSub.NewClient()
+	before := time.Now()
+	client1.Sub(topicB)
+	client2.Sub(topicB)
+	client1.Sub(topicA)
+
+	// Private topics are not part of the matrix
+	privTopic := client1.NewPrivateTopic("c")
+	client1.Sub(privTopic)
+
+	entries := ssePubSub.TopicSubMatrix()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 subscriptions, got %d", len(entries))
+	}
+	if entries[0].TopicName != "a" || entries[0].ClientID != client1.GetID() {
+		t.Error("Expected topic a first")
+	}
+	if entries[1].TopicName != "b" || entries[2].TopicName != "b" || entries[1].ClientID > entries[2].ClientID {
+		t.Error("Expected topic b sorted by client ID")
+	}
+	for _, e := range entries {
+		if e.SubscribedAt.Before(before) {
+			t.Error("SubscribedAt is before the subscription")
+		}
+	}
+}
+
+// TestSSEPubSubService_ListPublicTopics tests SSEPubSubService.ListPublicTopics()
+func TestSSEPubSubService_ListPublicTopics(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	for _, name := range []string{"c", "a", "b"} {
+		ssePubSub.NewPublicTopic(name)
+	}
+
+	topics := ssePubSub.ListPublicTopics()
+	if len(topics) != 3 || topics[0].GetName() != "a" || topics[1].GetName() != "b" || topics[2].GetName() != "c" {
+		t.Errorf("Expected topics a, b, c")
+	}
+
+	// Topics created concurrently do not break the listing
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ssePubSub.NewPublicTopic(fmt.Sprintf("topic-%d-%d", i, j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				topics := ssePubSub.ListPublicTopics()
+				for k := 1; k < len(topics); k++ {
+					if topics[k-1].GetName() > topics[k].GetName() {
+						t.Error("Expected the topics to be sorted by name")
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ssePubSub.ListPublicTopics()) != 503 {
+		t.Errorf("Expected 503 topics, got %d", len(ssePubSub.ListPublicTopics()))
+	}
+}
+
+// TestExists tests TopicExists and ClientExists while topics and clients are created concurrently
+func TestExists(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if ssePubSub.TopicExists("missing") || ssePubSub.ClientExists("missing") {
+		t.Error("Expected unknown topic and client not to exist")
+	}
+
+	group := ssePubSub.NewGroup("group")
+	group.NewTopic("grouptopic")
+	if !ssePubSub.TopicExists("grouptopic") {
+		t.Error("Expected the group topic to exist")
+	}
+
+	// Every topic and client exists once it is created
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("topic%d", i)
+			if !ssePubSub.TopicExists(name) {
+				ssePubSub.NewPublicTopic(name)
+			}
+			if !ssePubSub.TopicExists(name) {
+				t.Errorf("Expected topic %s to exist", name)
+			}
+
+			c := ssePubSub.NewClient()
+			if !ssePubSub.ClientExists(c.GetID()) {
+				t.Errorf("Expected client %s to exist", c.GetID())
+			}
+			ssePubSub.RemoveClient(c)
+			if ssePubSub.ClientExists(c.GetID()) {
+				t.Errorf("Expected client %s to be removed", c.GetID())
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(ssePubSub.GetPublicTopics()) != 50 {
+		t.Errorf("Expected 50 topics, got %d", len(ssePubSub.GetPublicTopics()))
+	}
+}
@@ -0,0 +1,129 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Default time Close waits for the event streams to end
+const defaultShutdownTimeout = 5 * time.Second
+
+var (
+	// ErrServiceClosed is returned if an event stream is started after Close.
+	ErrServiceClosed = errors.New("sSEPubSubService is closed")
+	// ErrShutdownTimeout is returned by Close if the event streams did not end within the shutdown timeout.
+	ErrShutdownTimeout = errors.New("shutdown timeout exceeded")
+)
+
+// WithShutdownTimeout sets how long Close waits for the event streams to end.
+// 0 keeps the default, a negative duration is invalid.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *SSEPubSubService) {
+		if d != 0 {
+			s.shutdownTimeout = d
+		}
+	}
+}
+
+// NewSSEPubSubServiceWithContext creates a new sSEPubSubService that is closed when ctx is done.
+// Returns the error of NewSSEPubSubService if the options are invalid.
+func NewSSEPubSubServiceWithContext(ctx context.Context, opts ...Option) (*SSEPubSubService, error) {
+	s, err := NewSSEPubSubService(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := s.Close(); err != nil {
+				s.logger.Errorf("Error closing sSEPubSubService: %s", err)
+			}
+		case <-s.done:
+		}
+	}()
+
+	return s, nil
+}
+
+// Check if the sSEPubSubService is closed
+func (s *SSEPubSubService) IsClosed() bool {
+	return s.closed.Load()
+}
+
+// Close the sSEPubSubService gracefully
+// Close can be called multiple times. Every call returns the result of the first one.
+// 0. Reject new event streams and stop the timers of all topics, so no scheduled publish or auto delete fires
+// 1. Send a "server_closing" message to every receiving client
+// 2. Wait until the queued messages are delivered, then close all event streams
+// 3. Wait for the event streams to end, return ErrShutdownTimeout after the shutdown timeout
+// 4. Stop the background goroutines, e.g. the pings and the throttled publishers
+func (s *SSEPubSubService) Close() error {
+	s.closeOnce.Do(func() {
+		// Reject new event streams
+		s.closed.Store(true)
+		deadline := time.Now().Add(s.shutdownTimeout)
+
+		// Stop the timers of all topics
+		for _, ttype := range []topicType{TopicTypePublic, TopicTypeGroup, TopicTypePrivate} {
+			topics, _ := s.topicsOfType(ttype)
+			for _, t := range topics {
+				t.CancelAllScheduled()
+				t.stopAutoDelete()
+			}
+		}
+
+		// Send a "server_closing" message to every receiving client. It is queued after all other messages.
+		fulldata := &eventData{
+			Sys: []eventDataSys{
+				{
+					Type: "server_closing",
+				},
+			},
+		}
+		clients := s.GetClients()
+		for _, c := range clients {
+			if c.GetStatus() != Receving {
+				continue
+			}
+			if err := c.send(fulldata); err != nil {
+				s.logger.Errorf("[C:%s]: Error sending server closing to client: %s", c.GetID(), err)
+			}
+		}
+
+		// Wait until the queued messages are delivered, then close all event streams
+		waitUntil(deadline, func() bool {
+			for _, c := range clients {
+				if c.queued() > 0 {
+					return false
+				}
+			}
+			return true
+		})
+		s.CloseAllConnections()
+
+		// Wait for the event streams to end
+		if !waitUntil(deadline, func() bool { return s.activeStreams.Load() == 0 }) {
+			s.closeErr = ErrShutdownTimeout
+		}
+
+		// Stop the background goroutines
+		close(s.done)
+		s.workers.Wait()
+		s.closeThrottledPublishers()
+	})
+	return s.closeErr
+}
+
+// Check cond every 10ms until it is true or the deadline is reached
+// Returns false if the deadline was reached.
+func waitUntil(deadline time.Time, cond func() bool) bool {
+	for !cond() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
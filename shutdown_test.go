@@ -0,0 +1,157 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +Close(): error
+// +Close(): error stops scheduled publishes and auto deletes
+// +IsClosed(): bool
+// +WithShutdownTimeout(d time.Duration): Option
+// +NewSSEPubSubServiceWithContext(ctx context.Context, opts ...Option): *SSEPubSubService, error
+
+// TestClose tests that Close delivers the queued messages and a server closing message before it ends the event streams
+func TestClose(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	client := ssePubSub.NewClient()
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := startClient(t, client)
+	defer stop()
+
+	if err := topic.Pub("testdata"); err != nil {
+		t.Error(err)
+	}
+	if err := ssePubSub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !ssePubSub.IsClosed() {
+		t.Error("Expected the sSEPubSubService to be closed")
+	}
+	if client.GetStatus() != Waiting {
+		t.Error("Expected the event stream to be closed")
+	}
+
+	// The published message is delivered before the server closing message
+	received := events()
+	last := received[len(received)-1]
+	if len(last.Sys) != 1 || last.Sys[0].Type != "server_closing" {
+		t.Errorf("Expected server_closing as last message, got %+v", last)
+	}
+	if len(received) < 2 || len(received[len(received)-2].Updates) != 1 {
+		t.Errorf("Expected the published message before server_closing, got %+v", received)
+	}
+
+	// No new event streams after Close
+	if err := client.Start(context.Background(), func(string) {}); err != ErrServiceClosed {
+		t.Errorf("Expected ErrServiceClosed, got %v", err)
+	}
+	rec := httptest.NewRecorder()
+	Event(ssePubSub, rec, httptest.NewRequest("GET", "/event?client_id="+client.GetID(), nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
+
+// TestClose_Timeout tests that Close returns ErrShutdownTimeout if an event stream does not end
+func TestClose_Timeout(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithShutdownTimeout(50 * time.Millisecond))
+	client := ssePubSub.NewClient()
+
+	// The event stream hangs while it writes the server closing message
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(context.Background(), func(msg string) {
+			if strings.Contains(msg, "server_closing") {
+				<-release
+			}
+		})
+	}()
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := ssePubSub.Close(); err != ErrShutdownTimeout {
+		t.Errorf("Expected ErrShutdownTimeout, got %v", err)
+	}
+	if err := ssePubSub.Close(); err != ErrShutdownTimeout { // same result
+		t.Errorf("Expected ErrShutdownTimeout again, got %v", err)
+	}
+
+	close(release)
+	<-done
+}
+
+// TestNewSSEPubSubServiceWithContext tests that the sSEPubSubService is closed when the context is done
+func TestNewSSEPubSubServiceWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ssePubSub, err := NewSSEPubSubServiceWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ssePubSub.IsClosed() {
+		t.Fatal("Expected the sSEPubSubService to be open")
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for !ssePubSub.IsClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the sSEPubSubService to be closed after the context is done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Invalid options
+	if _, err := NewSSEPubSubServiceWithContext(context.Background(), WithShutdownTimeout(-time.Second)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Expected ErrInvalidOption, got %v", err)
+	}
+}
+
+// TestClose_StopsTimers tests that no scheduled publish or auto delete fires after Close
+func TestClose_StopsTimers(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	group := ssePubSub.NewGroup("group")
+	topics := []*Topic{
+		ssePubSub.NewPublicTopic("public"),
+		group.NewTopic("group"),
+		client.NewPrivateTopic("private"),
+	}
+
+	published := make(chan interface{}, len(topics))
+	for _, topic := range topics {
+		topic.OnPub(func(msg interface{}) { published <- msg })
+		if _, err := topic.PubScheduled(50*time.Millisecond, topic.GetName()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ssePubSub.SetTopicAutoDelete("group", AutoDeleteAfter(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ssePubSub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case msg := <-published:
+		t.Errorf("Expected no scheduled publish after Close, got %v", msg)
+	default:
+	}
+	if _, ok := group.GetTopicByName("group"); !ok {
+		t.Error("Expected no auto delete after Close")
+	}
+}
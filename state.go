@@ -0,0 +1,235 @@
+package pubsubsse
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Version of the format of ExportState
+const stateVersion = 1
+
+// ImportMode controls how ImportState handles clients, topics and groups that already exist.
+type ImportMode int
+
+const (
+	// ImportModeSkip keeps the existing client, topic or group and skips the imported one.
+	ImportModeSkip ImportMode = iota
+	// ImportModeReplace removes the existing client, topic or group and imports the new one.
+	ImportModeReplace
+)
+
+// WithImportMode sets how ImportState resolves conflicts with the existing state.
+func WithImportMode(mode ImportMode) Option {
+	return func(s *SSEPubSubService) {
+		s.importMode = mode
+	}
+}
+
+type state struct {
+	Version      int           `json:"version"`
+	Clients      []stateClient `json:"clients"`
+	PublicTopics []stateTopic  `json:"public_topics"`
+	Groups       []stateGroup  `json:"groups"`
+}
+
+type stateClient struct {
+	ID            string       `json:"id"`
+	PrivateTopics []stateTopic `json:"private_topics,omitempty"`
+}
+
+type stateGroup struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Topics  []stateTopic `json:"topics,omitempty"`
+	Clients []string     `json:"clients,omitempty"`
+}
+
+type stateTopic struct {
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	ReadOnly       bool                   `json:"read_only,omitempty"`
+	WriteOnly      bool                   `json:"write_only,omitempty"`
+	MaxSubscribers int                    `json:"max_subscribers,omitempty"`
+	WritePolicy    WritePolicy            `json:"write_policy,omitempty"`
+	PublishTimeout time.Duration          `json:"publish_timeout,omitempty"`
+	Subscribers    []string               `json:"subscribers,omitempty"`
+}
+
+// Build the state of a topic
+func exportTopic(t *Topic) stateTopic {
+	_, max := t.GetCapacity()
+	st := stateTopic{
+		ID:             t.GetID(),
+		Name:           t.GetName(),
+		Metadata:       t.GetMetadata(),
+		ReadOnly:       t.IsReadOnly(),
+		WriteOnly:      t.IsWriteOnly(),
+		MaxSubscribers: max,
+		WritePolicy:    t.GetWritePolicy(),
+		PublishTimeout: t.GetPublishTimeout(),
+	}
+	for id := range t.GetClients() {
+		st.Subscribers = append(st.Subscribers, id)
+	}
+	sort.Strings(st.Subscribers)
+	return st
+}
+
+// Create a topic from its state. Subscribers are added later.
+func importTopic(st stateTopic, ttype topicType, logger Logger) *Topic {
+	t := newTopic(st.Name, ttype, logger)
+	t.id = st.ID
+	t.readOnly = st.ReadOnly
+	t.writeOnly = st.WriteOnly
+	t.maxSubscribers = st.MaxSubscribers
+	t.writePolicy = st.WritePolicy
+	t.publishTimeout = st.PublishTimeout
+	for k, v := range st.Metadata {
+		t.metadata[k] = v
+	}
+	return t
+}
+
+// Export the clients, topics, groups and subscriptions as JSON
+// Open event streams, custom data and auth tokens are not exported.
+func (s *SSEPubSubService) ExportState() ([]byte, error) {
+	st := state{
+		Version:      stateVersion,
+		Clients:      []stateClient{},
+		PublicTopics: []stateTopic{},
+		Groups:       []stateGroup{},
+	}
+
+	for _, c := range s.GetClients() {
+		sc := stateClient{ID: c.GetID()}
+		for _, t := range c.GetPrivateTopics() {
+			sc.PrivateTopics = append(sc.PrivateTopics, exportTopic(t))
+		}
+		sort.Slice(sc.PrivateTopics, func(i, j int) bool { return sc.PrivateTopics[i].Name < sc.PrivateTopics[j].Name })
+		st.Clients = append(st.Clients, sc)
+	}
+	sort.Slice(st.Clients, func(i, j int) bool { return st.Clients[i].ID < st.Clients[j].ID })
+
+	s.ForEachPublicTopic(func(t *Topic) bool {
+		st.PublicTopics = append(st.PublicTopics, exportTopic(t))
+		return true
+	})
+	sort.Slice(st.PublicTopics, func(i, j int) bool { return st.PublicTopics[i].Name < st.PublicTopics[j].Name })
+
+	s.ForEachGroup(func(g *Group) bool {
+		sg := stateGroup{ID: g.GetID(), Name: g.GetName()}
+		for _, t := range g.GetTopics() {
+			sg.Topics = append(sg.Topics, exportTopic(t))
+		}
+		sort.Slice(sg.Topics, func(i, j int) bool { return sg.Topics[i].Name < sg.Topics[j].Name })
+		for id := range g.GetClients() {
+			sg.Clients = append(sg.Clients, id)
+		}
+		sort.Strings(sg.Clients)
+		st.Groups = append(st.Groups, sg)
+		return true
+	})
+	sort.Slice(st.Groups, func(i, j int) bool { return st.Groups[i].Name < st.Groups[j].Name })
+
+	return json.Marshal(st)
+}
+
+// Import a state of ExportState and merge it with the existing state
+// Imported clients are waiting until they open an event stream.
+// Existing clients, topics and groups are skipped or replaced, see WithImportMode.
+// 0. Check the version
+// 1. Import the clients with their private topics
+// 2. Import the public topics
+// 3. Import the groups with their topics and clients
+// 4. Subscribe the clients to the imported topics
+func (s *SSEPubSubService) ImportState(data []byte) error {
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+
+	// Check the version
+	if st.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", st.Version)
+	}
+
+	// Imported topics with their subscribers
+	subs := map[*Topic][]string{}
+
+	// Import the clients with their private topics
+	for _, sc := range st.Clients {
+		if existing, ok := s.GetClientByID(sc.ID); ok {
+			if s.importMode == ImportModeSkip {
+				continue
+			}
+			s.RemoveClient(existing)
+		}
+
+		c := newClient(s)
+		c.id = sc.ID
+		if _, ok := s.addClient(c); !ok {
+			continue
+		}
+		for _, stt := range sc.PrivateTopics {
+			t, _ := c.addPrivateTopic(importTopic(stt, TPrivate, s.logger))
+			subs[t] = stt.Subscribers
+		}
+	}
+
+	// Import the public topics
+	for _, stt := range st.PublicTopics {
+		if existing, ok := s.GetPublicTopicByName(stt.Name); ok {
+			if s.importMode == ImportModeSkip {
+				continue
+			}
+			s.RemovePublicTopic(existing)
+		}
+
+		if t, ok := s.addPublicTopic(importTopic(stt, TPublic, s.logger)); ok {
+			subs[t] = stt.Subscribers
+		}
+	}
+
+	// Import the groups with their topics and clients
+	for _, sg := range st.Groups {
+		if existing, ok := s.GetGroupByName(sg.Name); ok {
+			if s.importMode == ImportModeSkip {
+				continue
+			}
+			s.RemoveGroup(existing)
+		}
+
+		g := newGroup(sg.Name, s.logger)
+		g.id = sg.ID
+		if _, ok := s.addGroup(g); !ok {
+			continue
+		}
+		for _, stt := range sg.Topics {
+			t, _ := g.addTopic(importTopic(stt, TGroup, s.logger))
+			subs[t] = stt.Subscribers
+		}
+		for _, id := range sg.Clients {
+			if c, ok := s.GetClientByID(id); ok {
+				g.AddClient(c)
+			}
+		}
+	}
+
+	// Subscribe the clients to the imported topics
+	for t, ids := range subs {
+		for _, id := range ids {
+			c, ok := s.GetClientByID(id)
+			if !ok {
+				continue
+			}
+			if err := c.Sub(t); err != nil {
+				s.logger.Errorf("[C:%s]: Error subscribing to imported topic %s: %s", id, t.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
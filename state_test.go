@@ -0,0 +1,116 @@
+package pubsubsse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests for:
+// +ExportState(): []byte, error
+// +ImportState(data []byte): error
+
+// Build a state with clients, public, group and private topics and subscriptions
+func newComplexState(t *testing.T) *SSEPubSubService {
+	ssePubSub := MustNewSSEPubSubService()
+	client1 := ssePubSub.NewClient()
+	client2 := ssePubSub.NewClient()
+
+	topicA := ssePubSub.NewPublicTopic("a")
+	topicA.SetMetadata("description", "first topic")
+	ssePubSub.SetTopicWritePolicy("a", WriteSerial)
+	ssePubSub.SetTopicPublishTimeout("a", time.Second)
+	if _, err := ssePubSub.NewWriteOnlyTopic("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	group := ssePubSub.NewGroup("group")
+	groupTopic := group.NewTopic("grouptopic")
+	group.AddClient(client1)
+
+	privTopic := client2.NewPrivateTopic("private")
+
+	for _, sub := range []struct {
+		c *Client
+		t *Topic
+	}{{client1, topicA}, {client2, topicA}, {client1, groupTopic}, {client2, privTopic}} {
+		if err := sub.c.Sub(sub.t); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ssePubSub
+}
+
+// TestExportImportState tests a round trip of a complex state
+func TestExportImportState(t *testing.T) {
+	src := newComplexState(t)
+	data, err := src.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := MustNewSSEPubSubService()
+	if err := dst.ImportState(data); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := dst.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(roundTrip) != string(data) {
+		t.Errorf("Expected the same state after the round trip\nexported: %s\nimported: %s", data, roundTrip)
+	}
+
+	// Imported clients wait for an event stream
+	for _, c := range dst.GetClients() {
+		if c.GetStatus() != Waiting {
+			t.Error("Expected imported clients to be waiting")
+		}
+	}
+	topicA, _ := dst.GetPublicTopicByName("a")
+	if len(topicA.GetClients()) != 2 || topicA.GetWritePolicy() != WriteSerial {
+		t.Error("Expected the subscribers and configuration of topic a")
+	}
+	if dst.GroupCount() != 1 {
+		t.Error("Expected 1 group")
+	}
+
+	// Unsupported versions are rejected
+	if err := dst.ImportState([]byte(strings.Replace(string(data), `"version":1`, `"version":99`, 1))); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+// TestImportState_Mode tests the conflict resolution of ImportState
+func TestImportState_Mode(t *testing.T) {
+	data, err := newComplexState(t).ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []ImportMode{ImportModeSkip, ImportModeReplace} {
+		dst := MustNewSSEPubSubService(WithImportMode(mode))
+		existing := dst.NewPublicTopic("a")
+		existing.SetMetadata("description", "existing")
+
+		if err := dst.ImportState(data); err != nil {
+			t.Fatal(err)
+		}
+
+		topicA, _ := dst.GetPublicTopicByName("a")
+		desc, _ := topicA.GetMetadataKey("description")
+		switch mode {
+		case ImportModeSkip:
+			if topicA != existing || desc != "existing" || len(topicA.GetClients()) != 0 {
+				t.Error("Expected the existing topic to be kept")
+			}
+		case ImportModeReplace:
+			if topicA == existing || desc != "first topic" || len(topicA.GetClients()) != 2 {
+				t.Error("Expected the existing topic to be replaced")
+			}
+		}
+		if _, ok := dst.GetPublicTopicByName("b"); !ok {
+			t.Error("Expected topic b to be imported")
+		}
+	}
+}
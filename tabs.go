@@ -0,0 +1,133 @@
+package pubsubsse
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Name of the cookie that identifies the browser of an event stream, see TabManager.
+// It has to be set by the application and shared by all tabs of a browser.
+const BrowserIDCookie = "pubsubsse_browser"
+
+// TabManager multiplexes the clients of all tabs of a browser over a single event stream.
+// Browsers limit the number of connections per domain, so only the primary tab of a browser
+// keeps an event stream open. Every other tab is a client without an event stream, its messages
+// are sent over the stream of the primary tab, wrapped in a sys event:
+//
+//	{"sys":[{"type":"tab","tabID":"<client ID of the tab>","data":{"sys":...,"updates":...}}]}
+//
+// The primary tab passes them on to the tab, e.g. with a BroadcastChannel.
+type TabManager struct {
+	lock sync.Mutex // not held while other locks are taken
+
+	// Primary client of every browser
+	primaries map[string]*Client
+
+	// Browser of every tab that is not the primary tab. Maps the client ID to the browser ID.
+	tabs map[string]string
+}
+
+// Create a new tab manager
+func newTabManager() *TabManager {
+	return &TabManager{
+		primaries: make(map[string]*Client),
+		tabs:      make(map[string]string),
+	}
+}
+
+// Get the tab manager of the sSEPubSubService
+func (s *SSEPubSubService) TabManager() *TabManager {
+	return s.tabs
+}
+
+// Set the primary client of a browser
+// Its event stream is used for all tabs of the browser. A tab that becomes primary is no longer routed.
+func (m *TabManager) SetPrimary(browserID string, c *Client) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.primaries[browserID] = c
+	delete(m.tabs, c.GetID())
+}
+
+// Get the primary client of a browser
+func (m *TabManager) GetPrimary(browserID string) (*Client, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c, ok := m.primaries[browserID]
+	return c, ok
+}
+
+// Add a tab to a browser
+// Messages to the client are sent over the event stream of the primary client of the browser
+// as long as the client has no event stream of its own. A primary client can not be added as tab.
+func (m *TabManager) AddTab(browserID string, c *Client) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// A primary client is never routed over another client
+	for _, primary := range m.primaries {
+		if primary == c {
+			return
+		}
+	}
+	m.tabs[c.GetID()] = browserID
+}
+
+// Remove a client from the tab manager, either as tab or as primary client
+func (m *TabManager) RemoveTab(c *Client) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.tabs, c.GetID())
+	for browserID, primary := range m.primaries {
+		if primary == c {
+			delete(m.primaries, browserID)
+		}
+	}
+}
+
+// Check if the client is a tab of a browser
+func (m *TabManager) isTab(c *Client) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.tabs[c.GetID()]
+	return ok
+}
+
+// Get the client IDs of the tabs that are routed over a primary client
+func (m *TabManager) tabsOf(c *Client) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ids := []string{}
+	for tabID, browserID := range m.tabs {
+		if m.primaries[browserID] == c {
+			ids = append(ids, tabID)
+		}
+	}
+	return ids
+}
+
+// Get the primary client that receives the messages of a tab
+// ok is false if the client is no tab or its browser has no primary client.
+func (m *TabManager) route(c *Client) (*Client, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	browserID, ok := m.tabs[c.GetID()]
+	if !ok {
+		return nil, false
+	}
+	primary, ok := m.primaries[browserID]
+	return primary, ok
+}
+
+// Wrap the message of a tab into a tab sys event for the primary client
+func tabFrame(tabID string, jsonData []byte) *eventData {
+	return &eventData{
+		Sys: []eventDataSys{{Type: "tab", TabID: tabID, Data: json.RawMessage(jsonData)}},
+	}
+}
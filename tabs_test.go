@@ -0,0 +1,130 @@
+package pubsubsse
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Tests for:
+// +TabManager(): *TabManager
+// TabManager:
+// +SetPrimary(browserID string, c *Client)
+// +GetPrimary(browserID string): *Client, bool
+// +AddTab(browserID string, c *Client)
+// +RemoveTab(c *Client)
+
+// TestTabManager tests that the messages of a tab are sent over the event stream of the primary client
+func TestTabManager(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	tabs := ssePubSub.TabManager()
+	primary := ssePubSub.NewClient()
+	tab := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("test")
+	if err := tab.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a primary client the tab does not receive
+	tabs.AddTab("browser", tab)
+	if err := topic.Pub("lost"); err != nil {
+		t.Error(err)
+	}
+
+	tabs.SetPrimary("browser", primary)
+	tabs.AddTab("browser", primary) // a primary client is no tab
+	if c, ok := tabs.GetPrimary("browser"); !ok || c != primary {
+		t.Error("Expected the primary client")
+	}
+
+	events, stop := startClient(t, primary)
+	if err := topic.Pub("hello"); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	// The update of the tab is wrapped in a tab event
+	frames := []eventDataSys{}
+	for _, d := range events() {
+		if len(d.Updates) > 0 {
+			t.Error("Expected the primary client not to receive the updates of the tab directly")
+		}
+		for _, sys := range d.Sys {
+			if sys.Type == "tab" {
+				frames = append(frames, sys)
+			}
+		}
+	}
+	if len(frames) != 1 || frames[0].TabID != tab.GetID() {
+		t.Fatalf("Expected 1 tab event of the tab, got %+v", frames)
+	}
+	var inner eventData
+	if err := json.Unmarshal(frames[0].Data, &inner); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.Updates) != 1 || inner.Updates[0].Topic != "test" || decodeData(inner.Updates[0].Data) != "hello" {
+		t.Errorf("Unexpected tab data %s", frames[0].Data)
+	}
+
+	// Removing the primary client stops the routing
+	tabs.RemoveTab(primary)
+	if _, ok := tabs.GetPrimary("browser"); ok {
+		t.Error("Expected no primary client")
+	}
+	if err := tab.send(&eventData{}); err == nil {
+		t.Error("Expected error without primary client")
+	}
+}
+
+// TestEvent_Tabs tests that a second tab of a browser does not open an event stream
+func TestEvent_Tabs(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	first := ssePubSub.NewClient()
+	second := ssePubSub.NewClient()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Event(ssePubSub, w, r) }))
+	defer srv.Close()
+
+	// Open the event stream of a tab with the browser cookie
+	open := func(c *Client) *http.Response {
+		req, err := http.NewRequest("GET", srv.URL+"/event?client_id="+c.GetID(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.AddCookie(&http.Cookie{Name: BrowserIDCookie, Value: "browser"})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// The first tab becomes the primary tab
+	resp := open(first)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	reader.ReadString('\n')
+	if primary, ok := ssePubSub.TabManager().GetPrimary("browser"); !ok || primary != first {
+		t.Fatal("Expected the first tab to be the primary tab")
+	}
+
+	// The second tab gets no event stream, its init message is sent over the primary tab
+	resp2 := open(second)
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for the second tab, got %d", resp2.StatusCode)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"type":"tab"`) || !strings.Contains(line, `"tabID":"`+second.GetID()+`"`) {
+		t.Errorf("Expected the init message of the second tab, got %s", line)
+	}
+}
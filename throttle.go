@@ -0,0 +1,201 @@
+package pubsubsse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Size of the buffer of a ThrottledPublisher
+const throttledBufferSize = 100
+
+// ThrottledPublisher publishes to a topic with at most a fixed number of messages per second.
+type ThrottledPublisher struct {
+	s       *SSEPubSubService
+	topic   *Topic
+	limiter *rate.Limiter
+
+	lock   sync.Mutex
+	buffer []interface{}
+
+	// Held while buffered messages are published, so they keep their order
+	pubLock sync.Mutex
+
+	// Signals the worker that a message was buffered
+	queued chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Create a new throttled publisher for a topic
+// The publisher is stopped by Close of the publisher or of the sSEPubSubService.
+// 0. Check the rate
+// 1. Get the topic by name
+// 2. Register the publisher, return ErrServiceClosed after Close
+// 3. Start the worker that publishes the buffered messages
+func (s *SSEPubSubService) NewThrottledPublisher(topicName string, rps float64) (*ThrottledPublisher, error) {
+	// Check the rate
+	if rps <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+
+	// Get the topic by name
+	t, ok := s.getTopicByName(topicName)
+	if !ok {
+		return nil, fmt.Errorf("topic %s does not exist", topicName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ThrottledPublisher{
+		s:       s,
+		topic:   t,
+		limiter: rate.NewLimiter(rate.Limit(rps), 1),
+		buffer:  make([]interface{}, 0, throttledBufferSize),
+		queued:  make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	// Register the publisher
+	s.lock.Lock()
+	if s.IsClosed() {
+		s.lock.Unlock()
+		cancel()
+		return nil, ErrServiceClosed
+	}
+	s.throttled[p] = struct{}{}
+	s.lock.Unlock()
+
+	// Start the worker that publishes the buffered messages
+	go p.run()
+
+	return p, nil
+}
+
+// Publish a message as soon as the rate limit allows it
+// Blocks until the message is published.
+func (p *ThrottledPublisher) Pub(msg interface{}) error {
+	if err := p.limiter.Wait(p.ctx); err != nil {
+		return err
+	}
+	return p.topic.Pub(msg)
+}
+
+// Buffer a message without blocking
+// The message is published as soon as the rate limit allows it. It is dropped if the buffer is full.
+func (p *ThrottledPublisher) PubAsync(msg interface{}) {
+	p.lock.Lock()
+	if len(p.buffer) >= throttledBufferSize {
+		p.lock.Unlock()
+		p.topic.logger.Errorf("[T:%s]: Throttled publisher buffer is full, message dropped", p.topic.GetName())
+		return
+	}
+	p.buffer = append(p.buffer, msg)
+	p.lock.Unlock()
+
+	// Wake up the worker
+	select {
+	case p.queued <- struct{}{}:
+	default:
+	}
+}
+
+// Publish all buffered messages immediately, ignoring the rate limit
+func (p *ThrottledPublisher) Flush() error {
+	p.pubLock.Lock()
+	defer p.pubLock.Unlock()
+
+	p.lock.Lock()
+	msgs := p.buffer
+	p.buffer = make([]interface{}, 0, throttledBufferSize)
+	p.lock.Unlock()
+
+	var errs PublishErrors
+	for _, msg := range msgs {
+		if err := p.topic.Pub(msg); err != nil {
+			errs = append(errs, PublishError{TopicName: p.topic.GetName(), Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Stop the worker
+// Messages that are still buffered are not published. Call Flush before to publish them.
+// Close can be called multiple times.
+func (p *ThrottledPublisher) Close() {
+	p.cancel()
+	<-p.done
+
+	p.s.lock.Lock()
+	delete(p.s.throttled, p)
+	p.s.lock.Unlock()
+}
+
+// Stop all throttled publishers of the sSEPubSubService
+func (s *SSEPubSubService) closeThrottledPublishers() {
+	s.lock.Lock()
+	publishers := make([]*ThrottledPublisher, 0, len(s.throttled))
+	for p := range s.throttled {
+		publishers = append(publishers, p)
+	}
+	s.lock.Unlock()
+
+	for _, p := range publishers {
+		p.Close()
+	}
+}
+
+// Get the number of buffered messages
+func (p *ThrottledPublisher) Buffered() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.buffer)
+}
+
+// run publishes the buffered messages with the rate limit until the publisher is closed
+func (p *ThrottledPublisher) run() {
+	defer close(p.done)
+
+	for {
+		// Wait for a buffered message
+		if p.Buffered() == 0 {
+			select {
+			case <-p.queued:
+			case <-p.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		// Wait for the rate limit
+		if err := p.limiter.Wait(p.ctx); err != nil {
+			return
+		}
+
+		// Publish the oldest message. It may have been flushed in the meantime.
+		p.pubLock.Lock()
+		p.lock.Lock()
+		if len(p.buffer) == 0 {
+			p.lock.Unlock()
+			p.pubLock.Unlock()
+			continue
+		}
+		msg := p.buffer[0]
+		p.buffer = p.buffer[1:]
+		p.lock.Unlock()
+
+		if err := p.topic.Pub(msg); err != nil {
+			p.topic.logger.Errorf("[T:%s]: Error publishing throttled message: %s", p.topic.GetName(), err)
+		}
+		p.pubLock.Unlock()
+	}
+}
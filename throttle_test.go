@@ -0,0 +1,136 @@
+package pubsubsse
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// Tests for:
+// +NewThrottledPublisher(topicName string, rps float64): *ThrottledPublisher, error
+// ThrottledPublisher:
+// +Pub(msg interface{}): error
+// +PubAsync(msg interface{})
+// +Flush(): error
+// +Close()
+// -closeThrottledPublishers() by SSEPubSubService.Close
+
+// TestThrottledPublisher_Pub tests the rate limit of Pub and PubAsync
+func TestThrottledPublisher_Pub(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	if _, err := ssePubSub.NewThrottledPublisher("test", 20); err == nil {
+		t.Error("Expected error for unknown topic")
+	}
+	_, recorder := ssePubSub.NewRecordingTopic("test")
+	if _, err := ssePubSub.NewThrottledPublisher("test", 0); err == nil {
+		t.Error("Expected error for invalid rate")
+	}
+
+	p, err := ssePubSub.NewThrottledPublisher("test", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// The first message passes at once, then one message every 50ms
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := p.Pub(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("Expected Pub to be throttled, took %s", d)
+	}
+
+	// PubAsync does not block and keeps the order
+	start = time.Now()
+	for i := 3; i < 6; i++ {
+		p.PubAsync(i)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("Expected PubAsync not to block, took %s", d)
+	}
+	if err := recorder.WaitFor(6, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 140*time.Millisecond {
+		t.Errorf("Expected PubAsync to be throttled, took %s", d)
+	}
+	for i, m := range recorder.Messages() {
+		if m.Data != i {
+			t.Errorf("Expected message %d, got %v", i, m.Data)
+		}
+	}
+}
+
+// TestThrottledPublisher_Flush tests the buffer of PubAsync and Flush
+func TestThrottledPublisher_Flush(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithLogger(NoOpLogger{}))
+	_, recorder := ssePubSub.NewRecordingTopic("test")
+
+	p, err := ssePubSub.NewThrottledPublisher("test", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// Messages are dropped when the buffer is full
+	for i := 0; i < throttledBufferSize+50; i++ {
+		p.PubAsync(i)
+	}
+	if n := p.Buffered(); n > throttledBufferSize {
+		t.Errorf("Expected at most %d buffered messages, got %d", throttledBufferSize, n)
+	}
+
+	// Flush ignores the rate limit
+	if err := p.Flush(); err != nil {
+		t.Error(err)
+	}
+	if p.Buffered() != 0 {
+		t.Error("Expected an empty buffer after Flush")
+	}
+	messages := recorder.Messages()
+	if len(messages) < throttledBufferSize || len(messages) > throttledBufferSize+1 {
+		t.Fatalf("Expected %d messages, got %d", throttledBufferSize, len(messages))
+	}
+	for i, m := range messages {
+		if m.Data != i {
+			t.Errorf("Expected message %d, got %v", i, m.Data)
+			break
+		}
+	}
+}
+
+// TestThrottledPublisher_ServiceClose tests that Close of the sSEPubSubService stops the throttled publishers
+func TestThrottledPublisher_ServiceClose(t *testing.T) {
+	ignore := goleak.IgnoreCurrent()
+
+	ssePubSub := MustNewSSEPubSubService()
+	ssePubSub.NewPublicTopic("test")
+	p, err := ssePubSub.NewThrottledPublisher("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := ssePubSub.NewThrottledPublisher("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	for i := 0; i < 3; i++ {
+		p.PubAsync(i) // the worker waits for the rate limit
+	}
+
+	if err := ssePubSub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	goleak.VerifyNone(t, ignore)
+	p.Close() // safe after the sSEPubSubService stopped it
+	if len(ssePubSub.throttled) != 0 {
+		t.Errorf("Expected no registered publishers, got %d", len(ssePubSub.throttled))
+	}
+	if _, err := ssePubSub.NewThrottledPublisher("test", 1); err != ErrServiceClosed {
+		t.Errorf("Expected ErrServiceClosed, got %v", err)
+	}
+}
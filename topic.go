@@ -1,10 +1,16 @@
 package pubsubsse
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/apex/log"
 	"github.com/google/uuid"
+	"golang.org/x/time/rate"
 )
 
 // Topic Types
@@ -16,23 +22,174 @@ const (
 	TGroup   topicType = "group"
 )
 
+// Topic types to compare with GetTopicType
+const (
+	TopicTypePublic  = TPublic
+	TopicTypePrivate = TPrivate
+	TopicTypeGroup   = TGroup
+)
+
+// WritePolicy controls how concurrent Pub calls write to the subscribers of a topic.
+type WritePolicy int
+
+const (
+	// WriteConcurrent lets multiple Pub calls write to the subscribers in parallel.
+	WriteConcurrent WritePolicy = iota
+	// WriteSerial serialises all Pub calls of a topic, so every subscriber receives
+	// the messages in the same order.
+	WriteSerial
+)
+
+// ErrWriteOnlyTopic is returned by Client.Sub if the topic is write only.
+var ErrWriteOnlyTopic = errors.New("topic is write only")
+
+// ErrTopicFull is returned by Client.Sub if the topic has reached its maximum number of subscribers.
+var ErrTopicFull = errors.New("topic is full")
+
 // Topic represents a messaging Topic in the SSE pub-sub system.
 type Topic struct {
 	name    string
 	id      string
 	ttype   topicType
 	clients map[string]*Client
-	lock    sync.Mutex
+	lock    sync.Mutex // see the lock ordering of SSEPubSubService
+
+	// Time of the subscription of each client
+	subscribedAt map[string]time.Time
+
+	// Closed and replaced when a client subscribes. Used by WaitForSubscriber.
+	clientAdded chan struct{}
+
+	// Clients subscribed with SubOnce. true once the message was taken by a publish, see takeOnce.
+	once map[string]bool
+
+	breaker *circuitBreaker
+
+	// Limits the publish rate. nil if the topic has no limit.
+	limiter         *rate.Limiter
+	limiterBlocking bool
+
+	// Published messages for replay. nil if the topic has no history.
+	history *history
+
+	// Persists the published messages. nil if the topic has no store, see SetTopicPersistence.
+	store MessageStore
+
+	writePolicy WritePolicy
+	writeLock   sync.Mutex
+
+	// Held for reading while a publish sends, and for writing while the topic is renamed
+	renameLock sync.RWMutex
+
+	// Overrides the write timeout of the sSEPubSubService. 0 uses the default.
+	publishTimeout time.Duration
+
+	logger Logger
+
+	metadata     map[string]interface{}
+	metadataLock sync.RWMutex
+
+	readOnly  bool
+	writeOnly bool
+
+	// Maximum number of subscribers. 0 means unlimited.
+	maxSubscribers int
+
+	// Checks every published message. nil accepts all messages.
+	validator func(msg interface{}) error
+
+	// Replaces every published message. nil publishes the messages unchanged.
+	transformer TransformFunc
+
+	// Sends the messages of Pub off the publish path. nil sends them directly.
+	consumers *ConsumerPool
+
+	// OnPubError hooks of the sSEPubSubService. nil if the topic was not added to it.
+	pubErrors *hooks[PublishError]
+
+	// Pending scheduled publishes. Maps an ID to its *time.Timer.
+	scheduledPubs sync.Map
+
+	// Keys of PubIdempotent and PubDeduplicated
+	idempotencyKeys keyWindow
+	dedupeKeys      keyWindow
+	dedupeWindow    time.Duration // 0 if the topic has no dedupe window, see WithDedupeWindow
+
+	autoDelete       AutoDeletePolicy
+	autoDeleteRemove func()
+	autoDeleteTimer  *time.Timer
+
+	// Events:
+	onNewClient      hooks[*Client]
+	onRemoveClient   hooks[*Client]
+	onNewSubOfClient hooks[*Client]
+	onUnsubOfClient  hooks[*Client]
+	onPub            hooks[interface{}]
 }
 
 // Create a new topic
-func newTopic(name string, ttype topicType) *Topic {
-	return &Topic{
+func newTopic(name string, ttype topicType, logger Logger, opts ...TopicOption) *Topic {
+	t := &Topic{
 		name:    name,
 		id:      uuid.New().String(),
 		ttype:   ttype,
 		clients: make(map[string]*Client),
+		logger:  logger,
+
+		subscribedAt: make(map[string]time.Time),
+		clientAdded:  make(chan struct{}),
+		once:         make(map[string]bool),
+
+		metadata: make(map[string]interface{}),
 	}
+
+	// Apply the options
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// WithValidator checks every message published to the topic with fn
+// If fn returns an error, the publish returns it and the message is not sent.
+func WithValidator(fn func(msg interface{}) error) TopicOption {
+	return func(t *Topic) {
+		t.validator = fn
+	}
+}
+
+// Create a new topic with the configuration of this topic
+// Subscribers, pending scheduled publishes and the messages of the history are not copied.
+func (t *Topic) clone(name string) *Topic {
+	t.lock.Lock()
+	c := newTopic(name, t.ttype, t.logger)
+	c.writePolicy = t.writePolicy
+	c.publishTimeout = t.publishTimeout
+	c.readOnly = t.readOnly
+	c.writeOnly = t.writeOnly
+	c.maxSubscribers = t.maxSubscribers
+	c.validator = t.validator
+	c.dedupeWindow = t.dedupeWindow
+	c.transformer = t.transformer
+	c.store = t.store
+	if t.breaker != nil {
+		c.breaker = newCircuitBreaker(t.breaker.opts)
+	}
+	if t.history != nil {
+		c.history = newHistory(len(t.history.entries), t.history.ttl)
+	}
+	if t.limiter != nil {
+		c.limiter = rate.NewLimiter(t.limiter.Limit(), t.limiter.Burst())
+		c.limiterBlocking = t.limiterBlocking
+	}
+	t.lock.Unlock()
+
+	for k, v := range t.GetMetadata() {
+		c.metadata[k] = v
+	}
+
+	return c
 }
 
 // Get Name
@@ -52,27 +209,289 @@ func (t *Topic) GetID() string {
 }
 
 // Get Type
+//
+// Deprecated: Use GetTopicType, which can be compared with TopicTypePublic, TopicTypePrivate and TopicTypeGroup.
 func (t *Topic) GetType() string {
+	return string(t.GetTopicType())
+}
+
+// Get the type of the topic
+func (t *Topic) GetTopicType() topicType {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	return string(t.ttype)
+	return t.ttype
 }
 
-// Add a client to the topic
-func (t *Topic) addClient(c *Client) {
+// Set read only
+// Clients can not publish to a read only topic.
+func (t *Topic) SetReadOnly(v bool) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.clients[c.id] = c
+	t.readOnly = v
 }
 
-// Remove a client from the topic
-func (t *Topic) removeClient(c *Client) {
+// Is read only
+func (t *Topic) IsReadOnly() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.readOnly
+}
+
+// Set write only
+// Clients can not subscribe to a write only topic.
+func (t *Topic) SetWriteOnly(v bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.writeOnly = v
+}
+
+// Is write only
+func (t *Topic) IsWriteOnly() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.writeOnly
+}
+
+// Set the maximum number of subscribers
+// Client.Sub returns ErrTopicFull if the topic has n subscribers. n <= 0 removes the limit.
+// Clients that are already subscribed stay subscribed if the new limit is lower.
+func (t *Topic) SetMaxSubscribers(n int) {
+	if n < 0 {
+		n = 0
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.maxSubscribers = n
+}
+
+// Get the current number of subscribers and the maximum number of subscribers
+// max is 0 if the topic has no limit.
+func (t *Topic) GetCapacity() (current, max int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return len(t.clients), t.maxSubscribers
+}
+
+// TopicACL holds the access control settings of a topic, see Topic.UpdateACL.
+type TopicACL struct {
+	ReadOnly       bool // see SetReadOnly
+	WriteOnly      bool // see SetWriteOnly
+	MaxSubscribers int  // see SetMaxSubscribers
+}
+
+// Get a copy of the access control settings
+func (t *Topic) GetACL() TopicACL {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return TopicACL{ReadOnly: t.readOnly, WriteOnly: t.writeOnly, MaxSubscribers: t.maxSubscribers}
+}
+
+// Update the access control settings atomically
+// fn is called with the current settings and can change any of them. Concurrent updates of
+// different fields are not lost. fn must not call methods of the topic, the topic is locked.
+func (t *Topic) UpdateACL(fn func(acl *TopicACL)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	acl := TopicACL{ReadOnly: t.readOnly, WriteOnly: t.writeOnly, MaxSubscribers: t.maxSubscribers}
+	fn(&acl)
+	if acl.MaxSubscribers < 0 {
+		acl.MaxSubscribers = 0
+	}
+	t.readOnly = acl.ReadOnly
+	t.writeOnly = acl.WriteOnly
+	t.maxSubscribers = acl.MaxSubscribers
+}
+
+// Set metadata
+// The value is sent to the clients in the topics list, so it has to be JSON encodable, see SSEPubSubService.SetTopicMetadata.
+func (t *Topic) SetMetadata(key string, value interface{}) {
+	t.metadataLock.Lock()
+	defer t.metadataLock.Unlock()
+
+	t.metadata[key] = value
+}
+
+// Get metadata by key
+func (t *Topic) GetMetadataKey(key string) (interface{}, bool) {
+	t.metadataLock.RLock()
+	defer t.metadataLock.RUnlock()
+
+	v, ok := t.metadata[key]
+	return v, ok
+}
+
+// Delete metadata by key
+func (t *Topic) DeleteMetadata(key string) {
+	t.metadataLock.Lock()
+	defer t.metadataLock.Unlock()
+
+	delete(t.metadata, key)
+}
+
+// Get all metadata
+func (t *Topic) GetMetadata() map[string]interface{} {
+	t.metadataLock.RLock()
+	defer t.metadataLock.RUnlock()
+
+	// Create a copy of the map
+	newmap := make(map[string]interface{})
+	for k, v := range t.metadata {
+		newmap[k] = v
+	}
+	return newmap
+}
+
+// Get all metadata
+//
+// Deprecated: use GetMetadata.
+func (t *Topic) GetAllMetadata() map[string]interface{} {
+	return t.GetMetadata()
+}
+
+// Get publish timeout
+// 0 means the write timeout of the sSEPubSubService is used.
+func (t *Topic) GetPublishTimeout() time.Duration {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	return t.publishTimeout
+}
+
+// Set publish timeout
+func (t *Topic) setPublishTimeout(d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.publishTimeout = d
+}
+
+// Get write policy
+func (t *Topic) GetWritePolicy() WritePolicy {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.writePolicy
+}
+
+// Set write policy
+func (t *Topic) setWritePolicy(policy WritePolicy) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.writePolicy = policy
+}
+
+// Add a client to the topic
+// Returns false if the client was already added and ErrTopicFull if the topic has reached its maximum number of subscribers.
+func (t *Topic) addClient(c *Client) (bool, error) {
+	t.lock.Lock()
+	if _, ok := t.clients[c.id]; ok {
+		t.lock.Unlock()
+		return false, nil
+	}
+	if t.maxSubscribers > 0 && len(t.clients) >= t.maxSubscribers {
+		t.lock.Unlock()
+		return false, ErrTopicFull
+	}
+	t.clients[c.id] = c
+	t.subscribedAt[c.id] = time.Now()
+
+	// Wake up WaitForSubscriber
+	close(t.clientAdded)
+	t.clientAdded = make(chan struct{})
+	t.lock.Unlock()
+
+	// A new subscriber stops a pending auto delete
+	t.checkAutoDelete()
+
+	// Emit event
+	t.onNewClient.emit(c)
+	return true, nil
+}
+
+// Remove a client from the topic
+// Returns false if the client was not added.
+func (t *Topic) removeClient(c *Client) bool {
+	t.lock.Lock()
+	if _, ok := t.clients[c.id]; !ok {
+		t.lock.Unlock()
+		return false
+	}
 	delete(t.clients, c.id)
+	delete(t.subscribedAt, c.id)
+	delete(t.once, c.id)
+	t.lock.Unlock()
+
+	// Emit event
+	t.onRemoveClient.emit(c)
+
+	// Remove the topic if the auto delete policy applies
+	t.checkAutoDelete()
+	return true
+}
+
+// Event: When client is added to the topic
+// Fires for every way a client is added. OnNewSubOfClient only fires for Client.Sub.
+func (t *Topic) OnNewClient(f funcClient) string {
+	return t.onNewClient.add(f)
+}
+
+// Remove Event: When client is added to the topic
+func (t *Topic) RemoveOnNewClient(id string) {
+	t.onNewClient.remove(id)
+}
+
+// Event: When client is removed from the topic
+// Fires for every way a client is removed. OnUnsubOfClient only fires for Client.Unsub.
+func (t *Topic) OnRemoveClient(f funcClient) string {
+	return t.onRemoveClient.add(f)
+}
+
+// Remove Event: When client is removed from the topic
+func (t *Topic) RemoveOnRemoveClient(id string) {
+	t.onRemoveClient.remove(id)
+}
+
+// Event: When client subscribes to the topic
+// Fires in Client.Sub after the client was added, but not if it was already subscribed.
+func (t *Topic) OnNewSubOfClient(f funcClient) string {
+	return t.onNewSubOfClient.add(f)
+}
+
+// Remove Event: When client subscribes to the topic
+func (t *Topic) RemoveOnNewSubOfClient(id string) {
+	t.onNewSubOfClient.remove(id)
+}
+
+// Event: When client unsubscribes from the topic
+// Fires in Client.Unsub after the client was removed.
+func (t *Topic) OnUnsubOfClient(f funcClient) string {
+	return t.onUnsubOfClient.add(f)
+}
+
+// Remove Event: When client unsubscribes from the topic
+func (t *Topic) RemoveOnUnsubOfClient(id string) {
+	t.onUnsubOfClient.remove(id)
+}
+
+// Event: When a message is published to the topic
+func (t *Topic) OnPub(f func(msg interface{})) string {
+	return t.onPub.add(f)
+}
+
+// Remove Event: When a message is published to the topic
+func (t *Topic) RemoveOnPub(id string) {
+	t.onPub.remove(id)
 }
 
 // Get all clients in the topic
@@ -88,6 +507,15 @@ func (t *Topic) GetClients() map[string]*Client {
 	return newmap
 }
 
+// Get the time a client subscribed to the topic
+func (t *Topic) GetSubscribedAt(c *Client) (time.Time, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	at, ok := t.subscribedAt[c.id]
+	return at, ok
+}
+
 // Check if a client is subscribed to the topic
 func (t *Topic) IsSubscribed(c *Client) bool {
 	t.lock.Lock()
@@ -97,45 +525,486 @@ func (t *Topic) IsSubscribed(c *Client) bool {
 	return ok
 }
 
+// Wait until the topic has a subscriber
+// Returns the client that subscribed first, so a message published afterwards reaches at least one client
+// unless it unsubscribes in the meantime. Returns ctx.Err() if ctx is done before a client subscribes.
+func (t *Topic) WaitForSubscriber(ctx context.Context) (*Client, error) {
+	for {
+		t.lock.Lock()
+		var first *Client
+		for id, c := range t.clients {
+			if first == nil || t.subscribedAt[id].Before(t.subscribedAt[first.id]) {
+				first = c
+			}
+		}
+		added := t.clientAdded
+		t.lock.Unlock()
+		if first != nil {
+			return first, nil
+		}
+
+		// Wait for the next subscriber or the end of ctx
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Subscribe a client for a single message
+// The client is subscribed like with Client.Sub and unsubscribed after the first message published to the topic.
+// Returns an error if the client is already subscribed.
+// 0. Mark the client, so the first message after the subscription can not be missed
+// 1. Subscribe the client
+func (t *Topic) SubOnce(c *Client) error {
+	// Mark the client
+	t.lock.Lock()
+	if _, ok := t.clients[c.id]; ok {
+		t.lock.Unlock()
+		return fmt.Errorf("[C:%s]: client is already subscribed to topic %s", c.GetID(), t.GetName())
+	}
+	t.once[c.id] = false
+	t.lock.Unlock()
+
+	// Subscribe the client
+	if err := c.Sub(t); err != nil {
+		t.lock.Lock()
+		delete(t.once, c.id)
+		t.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Take the clients of SubOnce that get their message from this publish
+// Returns the clients the message is sent to and the clients to unsubscribe afterwards.
+// Clients whose message was taken by an earlier publish are skipped until they are unsubscribed.
+func (t *Topic) takeOnce(clients map[string]*Client) (map[string]*Client, []*Client) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.once) == 0 {
+		return clients, nil
+	}
+
+	to := make(map[string]*Client, len(clients))
+	var done []*Client
+	for id, c := range clients {
+		if taken, ok := t.once[id]; ok {
+			if taken {
+				continue
+			}
+			t.once[id] = true
+			done = append(done, c)
+		}
+		to[id] = c
+	}
+	return to, done
+}
+
+// Check if the topic has no subscribers
+func (t *Topic) IsEmpty() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return len(t.clients) == 0
+}
+
 type eventData struct {
 	Sys     []eventDataSys     `json:"sys"`
 	Updates []eventDataUpdates `json:"updates"`
 }
 
 type eventDataSys struct {
-	Type string             `json:"type"`
-	List []eventDataSysList `json:"list,omitempty"`
+	Type  string             `json:"type"`
+	List  []eventDataSysList `json:"list,omitempty"`
+	TabID string             `json:"tabID,omitempty"` // client ID of the tab of a tab event, see TabManager
+	Data  json.RawMessage    `json:"data,omitempty"`  // payload of custom sys events, see Client.SendSysEvent
 }
 
 type eventDataSysList struct {
-	Name string `json:"name"`
-	Type string `json:"type,omitempty"` // topics, subscribed, unsubscribed
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type,omitempty"` // topics, subscribed, unsubscribed, groups, or the code of an error
+	Metadata  map[string]interface{} `json:"meta,omitempty"`
+	ReadOnly  bool                   `json:"readOnly,omitempty"`
+	WriteOnly bool                   `json:"writeOnly,omitempty"`
+}
+
+// topicListEntry builds the entry of the topic for the topics list sys event
+func (t *Topic) topicListEntry() eventDataSysList {
+	return eventDataSysList{
+		Name:      t.GetName(),
+		Type:      string(t.GetTopicType()),
+		Metadata:  t.GetMetadata(),
+		ReadOnly:  t.IsReadOnly(),
+		WriteOnly: t.IsWriteOnly(),
+	}
 }
 
 type eventDataUpdates struct {
-	Topic string      `json:"topic"`
-	Data  interface{} `json:"data"`
+	Topic     string          `json:"topic"`
+	Group     string          `json:"group,omitempty"`      // only set for group broadcasts
+	ExpiresAt string          `json:"expires_at,omitempty"` // RFC3339, only set for messages with a TTL
+	Data      json.RawMessage `json:"data"`                 // see MarshalData
+
+	// Published message before encoding, for the OnPub event and the history
+	msg interface{}
 }
 
 // Publish a message to all clients in the topic
 func (t *Topic) Pub(msg interface{}) error {
-	// Build the JSON data
-	fulldata := &eventData{
-		Updates: []eventDataUpdates{},
+	return t.PubWithPriority(msg, PriorityNormal)
+}
+
+// Publish a message to all clients in the topic without blocking
+// The message is sent to all clients concurrently. The returned channel receives the error of every
+// failed send and is closed when all sends completed. It is buffered, so it can also be ignored.
+func (t *Topic) PubAsync(msg interface{}) <-chan error {
+	clients := t.GetClients()
+	errs := make(chan error, len(clients)+1)
+
+	go func() {
+		defer close(errs)
+
+		u, ok, err := t.newUpdate(context.Background(), msg)
+		if err != nil {
+			errs <- t.pubError(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if err := t.publish(context.Background(), []eventDataUpdates{u}, PriorityNormal, clients, errs); err != nil {
+			errs <- err
+		}
+	}()
+
+	return errs
+}
+
+// Emit the OnPubError hooks of the sSEPubSubService if err is not nil and return err
+func (t *Topic) pubError(err error) error {
+	if err != nil && t.pubErrors != nil {
+		t.pubErrors.emit(PublishError{TopicName: t.GetName(), Err: err})
+	}
+	return err
+}
+
+// Publish a message to all clients in the topic
+// The publish stops as soon as ctx is done, e.g. during server shutdown.
+func (t *Topic) PubCtx(ctx context.Context, msg interface{}) error {
+	u, ok, err := t.newUpdate(ctx, msg)
+	if err != nil || !ok {
+		return t.pubError(err)
 	}
-	u := eventDataUpdates{
-		Topic: t.GetName(),
-		Data:  msg,
+	return t.pub(ctx, u, PriorityNormal)
+}
+
+// Publish a message with a priority to all clients in the topic
+// High priority messages are delivered before all queued normal and low priority messages.
+func (t *Topic) PubWithPriority(msg interface{}, p Priority) error {
+	u, ok, err := t.newUpdate(context.Background(), msg)
+	if err != nil || !ok {
+		return t.pubError(err)
+	}
+	return t.pub(context.Background(), u, p)
+}
+
+// Publish a message to all clients in the topic after delay
+// Returns a function that cancels the pending publish.
+// 0. Check the delay
+// 1. Start a timer that publishes the message
+// 2. Track the timer until it fired or was cancelled
+func (t *Topic) PubScheduled(delay time.Duration, msg interface{}) (func(), error) {
+	// Check the delay
+	if delay < 0 {
+		return nil, fmt.Errorf("delay must not be negative")
 	}
-	fulldata.Updates = append(fulldata.Updates, u)
 
-	// Send the JSON data to all clients
-	for _, c := range t.GetClients() {
-		err := c.send(fulldata) // ignore error. Fire and forget.
+	id := uuid.New().String()
+
+	// Start a timer that publishes the message
+	// The lock makes sure the timer is stored before it can remove itself.
+	lock := sync.Mutex{}
+	lock.Lock()
+	timer := time.AfterFunc(delay, func() {
+		// Skip the publish if it was cancelled in the meantime
+		lock.Lock()
+		_, ok := t.scheduledPubs.LoadAndDelete(id)
+		lock.Unlock()
+		if !ok {
+			return
+		}
+
+		if err := t.Pub(msg); err != nil {
+			t.logger.Errorf("[T:%s]: Error publishing scheduled message: %s", t.GetName(), err.Error())
+		}
+	})
+
+	// Track the timer
+	t.scheduledPubs.Store(id, timer)
+	lock.Unlock()
+
+	cancel := func() {
+		if v, ok := t.scheduledPubs.LoadAndDelete(id); ok {
+			v.(*time.Timer).Stop()
+		}
+	}
+	return cancel, nil
+}
+
+// Cancel all pending scheduled publishes of the topic
+func (t *Topic) CancelAllScheduled() {
+	t.scheduledPubs.Range(func(key, value interface{}) bool {
+		if _, ok := t.scheduledPubs.LoadAndDelete(key); ok {
+			value.(*time.Timer).Stop()
+		}
+		return true
+	})
+}
+
+// ErrTxDone is returned by TopicTx.Pub after the transaction has ended.
+var ErrTxDone = errors.New("transaction has already ended")
+
+// TopicTx collects the messages of a transactional publish, see Topic.TxPublish.
+type TopicTx struct {
+	topic *Topic
+
+	lock    sync.Mutex
+	updates []eventDataUpdates
+	done    bool
+}
+
+// Add a message to the transaction
+// The message is published when the transaction function returns without error.
+func (tx *TopicTx) Pub(msg interface{}) error {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.done {
+		return ErrTxDone
+	}
+	u, ok, err := tx.topic.newUpdate(context.Background(), msg)
+	if err != nil || !ok {
+		return err
+	}
+	tx.updates = append(tx.updates, u)
+	return nil
+}
+
+// Publish a series of related messages as a single delivery
+// All messages published with tx.Pub reach every subscriber together and in order,
+// so messages of other publishers can not interleave. If fn returns an error, nothing is published.
+// 0. Collect the messages of fn
+// 1. End the transaction
+// 2. Publish all messages as a single message
+func (t *Topic) TxPublish(fn func(tx *TopicTx) error) error {
+	// Collect the messages of fn
+	tx := &TopicTx{topic: t}
+	err := fn(tx)
+
+	// End the transaction
+	tx.lock.Lock()
+	tx.done = true
+	updates := tx.updates
+	tx.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Publish all messages as a single message
+	return t.pubUpdates(context.Background(), updates, PriorityNormal)
+}
+
+// sendSerial sends the data to one client after the other until ctx is done
+// Failed sends are logged. Returns the number of clients the data was sent to and the number of failed sends,
+// see isSendFailure.
+func (t *Topic) sendSerial(ctx context.Context, e *encodedEvent, p Priority, clients map[string]*Client) (delivered, failed int) {
+	timeout := t.GetPublishTimeout()
+	for _, c := range clients {
+		// Stop publishing if the context is done
+		if ctx.Err() != nil {
+			break
+		}
+
+		err := c.sendEncoded(ctx, e, p, timeout) // ignore error. Fire and forget.
 		if err != nil {
-			log.Errorf("[T:%s]: Error sending data to client: %s", t.GetName(), err.Error())
+			t.logger.Errorf("[T:%s]: Error sending data to client: %s", t.GetName(), err.Error())
+			if isSendFailure(err) {
+				failed++
+			}
+			continue
+		}
+		delivered++
+	}
+	return delivered, failed
+}
+
+// sendConcurrent sends the data to all clients at the same time and waits until every send completed
+// Failed sends are logged and put into errs. Returns the number of clients the data was sent to and the number
+// of failed sends, see isSendFailure.
+func (t *Topic) sendConcurrent(ctx context.Context, e *encodedEvent, p Priority, clients map[string]*Client, errs chan<- error) (delivered, failed int) {
+	timeout := t.GetPublishTimeout()
+	var sent, failures atomic.Int64
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			if err := c.sendEncoded(ctx, e, p, timeout); err != nil {
+				t.logger.Errorf("[T:%s]: Error sending data to client: %s", t.GetName(), err.Error())
+				if isSendFailure(err) {
+					failures.Add(1)
+				}
+				errs <- err
+				return
+			}
+			sent.Add(1)
+		}(c)
+	}
+	wg.Wait()
+	return int(sent.Load()), int(failures.Load())
+}
+
+// Check if a send error is a failure of the publish path, e.g. a full stream or a write timeout
+// A subscriber without an event stream is not a failure, it only misses the message.
+func isSendFailure(err error) bool {
+	return !errors.Is(err, ErrClientNotReceiving)
+}
+
+// pub sends the update to all clients in the topic
+// A topic with a consumer pool queues the update for its workers instead.
+func (t *Topic) pub(ctx context.Context, u eventDataUpdates, p Priority) error {
+	if t.consumers != nil {
+		if err := t.consumers.enqueue(ctx, consumerJob{update: u, priority: p}); err != errConsumerPoolClosed {
+			return err
+		}
+	}
+	return t.pubUpdates(ctx, []eventDataUpdates{u}, p)
+}
+
+// pubUpdates sends the updates as a single message to all clients in the topic
+func (t *Topic) pubUpdates(ctx context.Context, us []eventDataUpdates, p Priority) error {
+	return t.publish(ctx, us, p, nil, nil)
+}
+
+// publish sends the updates as a single message to the clients
+// clients nil sends to all current clients of the topic. If errs is not nil, the clients are sent to
+// concurrently and every failed send is put into errs, which must have space for one error per client.
+// 0. Validate the messages, wait for the publish rate and check the circuit breaker
+// 1. Serialise the writes if required by the write policy
+// 2. Send the updates with the current name of the topic to the clients until ctx is done, then unsubscribe the clients of SubOnce
+// 3. Report the result to the circuit breaker
+// 4. Add the updates to the history and the message store
+// 5. Emit the OnPub event for every update
+func (t *Topic) publish(ctx context.Context, us []eventDataUpdates, p Priority, clients map[string]*Client, errs chan<- error) (err error) {
+	// Report the error to the OnPubError hooks
+	defer func() { t.pubError(err) }()
+
+	// Validate the messages. Nothing is sent if one of them is invalid.
+	t.lock.Lock()
+	validator := t.validator
+	t.lock.Unlock()
+	if validator != nil {
+		for _, u := range us {
+			if err := validator(u.msg); err != nil {
+				return err
+			}
 		}
 	}
 
+	// Wait for the publish rate
+	if err := t.waitPublishRate(ctx); err != nil {
+		return err
+	}
+
+	// Check the circuit breaker
+	t.lock.Lock()
+	breaker := t.breaker
+	t.lock.Unlock()
+	if breaker != nil && !breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	// Serialise the writes if required by the write policy
+	if t.GetWritePolicy() == WriteSerial {
+		t.writeLock.Lock()
+		defer t.writeLock.Unlock()
+	}
+
+	// Build the JSON data with the current name. A rename waits until the send completed.
+	// It is encoded once and the same frame is sent to every client.
+	t.renameLock.RLock()
+	name := t.GetName()
+	for i := range us {
+		us[i].Topic = name
+	}
+	e, err := encodeEvent(&eventData{
+		Updates: us,
+	})
+	if err != nil {
+		t.renameLock.RUnlock()
+		if breaker != nil {
+			breaker.release()
+		}
+		return err
+	}
+
+	// Send the JSON data to the clients
+	if clients == nil {
+		clients = t.GetClients()
+	}
+	clients, once := t.takeOnce(clients)
+	var delivered, failed int
+	if errs == nil {
+		delivered, failed = t.sendSerial(ctx, e, p, clients)
+	} else {
+		delivered, failed = t.sendConcurrent(ctx, e, p, clients, errs)
+	}
+	t.renameLock.RUnlock()
+
+	// Unsubscribe the clients of SubOnce
+	for _, c := range once {
+		if err := c.Unsub(t); err != nil {
+			t.logger.Errorf("[C:%s]: Error unsubscribing client after its single message: %s", c.GetID(), err)
+		}
+	}
+
+	// A cancelled publish is not a failure of the subscribers
+	if err := ctx.Err(); err != nil {
+		if breaker != nil {
+			breaker.release()
+		}
+		return err
+	}
+
+	// Report the result to the circuit breaker
+	if breaker != nil {
+		if failed > 0 {
+			breaker.failure()
+		} else {
+			breaker.success()
+		}
+	}
+
+	// Keep the message for replay
+	t.lock.Lock()
+	h := t.history
+	t.lock.Unlock()
+	for _, u := range us {
+		if h != nil {
+			h.add(u, delivered)
+		}
+
+		// Emit event
+		t.onPub.emit(u.msg)
+	}
+	t.persist(name, us)
+
 	return nil
 }
@@ -1,22 +1,45 @@
 package pubsubsse
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Tests for:
 // +GetName(): string
 // +GetID(): string
 // +GetType(): string
+// +GetTopicType(): topicType
 // +GetClients(): map[string]*client
 // +IsSubscribed(c *client): bool
+// +SubOnce(c *client): error
+// +WaitForSubscriber(ctx context.Context): *client, error
 // +Pub(msg interface): error
+// +PubWithPriority(msg interface, p Priority): error
+// +PubAsync(msg interface): <-chan error
+// +PubScheduled(delay time.Duration, msg interface): func(), error
+// +CancelAllScheduled()
+// +TxPublish(fn func(tx *TopicTx) error): error
+// +SetMetadata(key string, value interface{})
+// +GetMetadataKey(key string): interface{}, bool
+// +DeleteMetadata(key string)
+// +GetMetadata(): map[string]interface{}
+// +SetMaxSubscribers(n int)
+// +GetCapacity(): int, int
+// +GetACL(): TopicACL
+// +UpdateACL(fn func(acl *TopicACL))
 // -addClient(c *client)
 // -removeClient(c *client)
 
 // TestGetName tests the GetName() method.
 func TestGetName(t *testing.T) {
-	topic := newTopic("test", "public")
+	topic := newTopic("test", "public", ApexLogger{})
 	if topic.GetName() != "test" {
 		t.Error("Expected topic name to be \"test\"")
 	}
@@ -24,7 +47,7 @@ func TestGetName(t *testing.T) {
 
 // TestGetID tests the GetID() method.
 func TestGetID(t *testing.T) {
-	topic := newTopic("test", "public")
+	topic := newTopic("test", "public", ApexLogger{})
 	if topic.GetID() == "" {
 		t.Error("Expected topic ID to be non-empty")
 	}
@@ -32,30 +55,51 @@ func TestGetID(t *testing.T) {
 
 // TestGetType tests the GetType() method.
 func TestGetType(t *testing.T) {
-	topic1 := newTopic("test", "public")
+	topic1 := newTopic("test", "public", ApexLogger{})
 	if topic1.GetType() != "public" {
 		t.Error("Expected topic type to be \"public\"")
 	}
 
-	topic2 := newTopic("test", "private")
+	topic2 := newTopic("test", "private", ApexLogger{})
 	if topic2.GetType() != "private" {
 		t.Error("Expected topic type to be \"private\"")
 	}
 
-	topic3 := newTopic("test", "group")
+	topic3 := newTopic("test", "group", ApexLogger{})
 	if topic3.GetType() != "group" {
 		t.Error("Expected topic type to be \"group\"")
 	}
 }
 
+// TestGetTopicType tests the GetTopicType() method.
+func TestGetTopicType(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	for _, tc := range []struct {
+		topic    *Topic
+		expected topicType
+	}{
+		{ssePubSub.NewPublicTopic("public"), TopicTypePublic},
+		{client.NewPrivateTopic("private"), TopicTypePrivate},
+		{ssePubSub.NewGroup("group").NewTopic("grouptopic"), TopicTypeGroup},
+	} {
+		if tc.topic.GetTopicType() != tc.expected {
+			t.Errorf("%s: expected type %s, got %s", tc.topic.GetName(), tc.expected, tc.topic.GetTopicType())
+		}
+		if tc.topic.GetType() != string(tc.expected) {
+			t.Errorf("%s: expected GetType to match GetTopicType", tc.topic.GetName())
+		}
+	}
+}
+
 // TestGetClients tests the GetClients() method.
 func TestGetClients(t *testing.T) {
-	topic := newTopic("test", "public")
+	topic := newTopic("test", "public", ApexLogger{})
 	if len(topic.GetClients()) != 0 {
 		t.Error("Expected topic to have no clients")
 	}
 
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	c1 := ssePubSub.NewClient()
 	c2 := ssePubSub.NewClient()
 	topic.addClient(c1)
@@ -74,7 +118,7 @@ func TestGetClients(t *testing.T) {
 
 // TestIsSubscribed tests the IsSubscribed() method.
 func TestIsSubscribed(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	c1 := ssePubSub.NewClient()
 
 	topic := c1.NewPrivateTopic("testTopic")
@@ -102,7 +146,7 @@ func TestIsSubscribed(t *testing.T) {
 
 // TestPub tests the Pub() method.
 func TestPub(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 
 	c1 := ssePubSub.NewClient()
 	c2 := ssePubSub.NewClient()
@@ -161,7 +205,7 @@ func TestPub(t *testing.T) {
 	}
 	for _, d := range data1 {
 		if len(d.Updates) > 0 {
-			if d.Updates[0].Data != testData1 {
+			if decodeData(d.Updates[0].Data) != testData1 {
 				t.Error("data[].Updates[0].Data != testData")
 				return
 			}
@@ -172,9 +216,128 @@ func TestPub(t *testing.T) {
 
 }
 
+// TestSubOnce tests that a client of SubOnce receives exactly one message
+func TestSubOnce(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	other := ssePubSub.NewClient()
+	topic := ssePubSub.NewPublicTopic("poll")
+	if err := other.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	events, stop := startClient(t, client)
+	if err := topic.SubOnce(client); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.GetSubscribedTopics()["poll"]; !ok {
+		t.Error("Expected the topic to be subscribed")
+	}
+	if err := topic.SubOnce(client); err == nil {
+		t.Error("Expected error for a subscribed client")
+	}
+
+	// Many messages in quick succession
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := topic.Pub(i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stop()
+
+	updates := 0
+	sys := []string{}
+	for _, d := range events() {
+		updates += len(d.Updates)
+		for _, s := range d.Sys {
+			if s.Type == "subscribed" || s.Type == "unsubscribed" {
+				sys = append(sys, s.Type)
+			}
+		}
+	}
+	if updates != 1 {
+		t.Errorf("Expected exactly 1 message, got %d", updates)
+	}
+	if strings.Join(sys, ",") != "subscribed,unsubscribed" {
+		t.Errorf("Expected the subscribed and unsubscribed events, got %v", sys)
+	}
+	if topic.IsSubscribed(client) {
+		t.Error("Expected the client to be unsubscribed")
+	}
+	if !topic.IsSubscribed(other) {
+		t.Error("Expected the normal subscription to stay")
+	}
+
+	// The client can subscribe again
+	if err := topic.SubOnce(client); err != nil {
+		t.Error(err)
+	}
+	if err := other.NewPrivateTopic("private").SubOnce(client); err == nil {
+		t.Error("Expected error for a client that can not subscribe")
+	}
+}
+
+// TestWaitForSubscriber tests that a message published after WaitForSubscriber reaches a subscriber
+func TestWaitForSubscriber(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+
+	// No subscriber before the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if c, err := topic.WaitForSubscriber(ctx); err != context.DeadlineExceeded || c != nil {
+		t.Errorf("Expected DeadlineExceeded, got %v, %v", c, err)
+	}
+
+	// Publish as soon as the first client subscribes
+	first := ssePubSub.NewClient()
+	second := ssePubSub.NewClient()
+	events, stop := startClient(t, first)
+	done := make(chan error, 1)
+	go func() {
+		c, err := topic.WaitForSubscriber(context.Background())
+		if err == nil && c != first {
+			err = fmt.Errorf("expected client %s, got %v", first.GetID(), c)
+		}
+		if err == nil {
+			err = topic.Pub("testdata")
+		}
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := first.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	updates := 0
+	for _, d := range events() {
+		updates += len(d.Updates)
+	}
+	if updates != 1 {
+		t.Errorf("Expected the message to reach the subscriber, got %d updates", updates)
+	}
+
+	// Returns at once with the earliest subscriber
+	if err := second.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := topic.WaitForSubscriber(context.Background()); err != nil || c != first {
+		t.Errorf("Expected the first subscriber, got %v, %v", c, err)
+	}
+}
+
 // TestAddClient tests the addClient() method.
 func TestAddClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	c1 := ssePubSub.NewClient()
 
 	topic := c1.NewPrivateTopic("testTopic")
@@ -193,9 +356,111 @@ func TestAddClient(t *testing.T) {
 	}
 }
 
+// TestMaxSubscribers tests the SetMaxSubscribers() and GetCapacity() methods.
+func TestMaxSubscribers(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("room")
+	topic.SetMaxSubscribers(100)
+
+	// The 100th subscriber is accepted
+	clients := []*Client{}
+	for i := 0; i < 100; i++ {
+		c := ssePubSub.NewClient()
+		if err := c.Sub(topic); err != nil {
+			t.Fatalf("Expected subscriber %d to be accepted, got %s", i+1, err)
+		}
+		clients = append(clients, c)
+	}
+	if current, max := topic.GetCapacity(); current != 100 || max != 100 {
+		t.Errorf("Expected capacity 100/100, got %d/%d", current, max)
+	}
+
+	// The 101st subscriber is rejected
+	late := ssePubSub.NewClient()
+	if err := late.Sub(topic); !errors.Is(err, ErrTopicFull) {
+		t.Errorf("Expected ErrTopicFull, got %v", err)
+	}
+	if topic.IsSubscribed(late) {
+		t.Error("Expected the 101st client not to be subscribed")
+	}
+
+	// Subscribing again is no error for a subscriber
+	if err := clients[0].Sub(topic); err != nil {
+		t.Errorf("Expected no error for an existing subscriber, got %s", err)
+	}
+
+	// A free place can be taken
+	if err := clients[0].Unsub(topic); err != nil {
+		t.Error(err)
+	}
+	if err := late.Sub(topic); err != nil {
+		t.Errorf("Expected a free place, got %s", err)
+	}
+
+	// No limit
+	topic.SetMaxSubscribers(0)
+	if err := ssePubSub.NewClient().Sub(topic); err != nil {
+		t.Errorf("Expected no limit, got %s", err)
+	}
+	if current, max := topic.GetCapacity(); current != 101 || max != 0 {
+		t.Errorf("Expected capacity 101/0, got %d/%d", current, max)
+	}
+}
+
+// TestUpdateACL tests that concurrent updates of different ACL fields are not lost
+func TestUpdateACL(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("room")
+	if acl := topic.GetACL(); acl != (TopicACL{}) {
+		t.Errorf("Expected an empty ACL, got %+v", acl)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			topic.UpdateACL(func(acl *TopicACL) { acl.ReadOnly = !acl.ReadOnly })
+		}()
+		go func() {
+			defer wg.Done()
+			topic.UpdateACL(func(acl *TopicACL) { acl.WriteOnly = !acl.WriteOnly })
+		}()
+		go func() {
+			defer wg.Done()
+			topic.UpdateACL(func(acl *TopicACL) { acl.MaxSubscribers++ })
+		}()
+	}
+	wg.Wait()
+
+	// Every field was toggled an even number of times
+	if acl := topic.GetACL(); acl != (TopicACL{MaxSubscribers: 100}) {
+		t.Errorf("Expected no lost updates, got %+v", acl)
+	}
+
+	// The ACL is used by the topic
+	topic.UpdateACL(func(acl *TopicACL) {
+		acl.ReadOnly = true
+		acl.MaxSubscribers = -1
+	})
+	if !topic.IsReadOnly() {
+		t.Error("Expected the topic to be read only")
+	}
+	if _, max := topic.GetCapacity(); max != 0 {
+		t.Errorf("Expected no subscriber limit, got %d", max)
+	}
+
+	// GetACL returns a copy
+	acl := topic.GetACL()
+	acl.WriteOnly = true
+	if topic.IsWriteOnly() {
+		t.Error("Expected GetACL to return a copy")
+	}
+}
+
 // TestRemoveClient tests the removeClient() method.
 func TestRemoveClient(t *testing.T) {
-	ssePubSub := NewSSEPubSubService()
+	ssePubSub := MustNewSSEPubSubService()
 	c1 := ssePubSub.NewClient()
 
 	topic := c1.NewPrivateTopic("testTopic")
@@ -218,4 +483,485 @@ func TestRemoveClient(t *testing.T) {
 	if len(topic.GetClients()) != 0 {
 		t.Error("Expected topic to have no clients")
 	}
-}
\ No newline at end of file
+}
+
+// TestMetadata tests the SetMetadata(), GetMetadataKey(), DeleteMetadata() and GetMetadata() methods.
+func TestMetadata(t *testing.T) {
+	topic := newTopic("test", "public", ApexLogger{})
+	if _, ok := topic.GetMetadataKey("room_id"); ok {
+		t.Error("Expected topic to have no metadata")
+	}
+
+	topic.SetMetadata("room_id", "1")
+	if v, ok := topic.GetMetadataKey("room_id"); !ok || v != "1" {
+		t.Error("Expected metadata room_id to be \"1\"")
+	}
+	if len(topic.GetMetadata()) != 1 {
+		t.Error("Expected topic to have 1 metadata entry")
+	}
+
+	topic.DeleteMetadata("room_id")
+	if _, ok := topic.GetMetadataKey("room_id"); ok {
+		t.Error("Expected metadata room_id to be deleted")
+	}
+
+	// Concurrent reads and writes
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			topic.SetMetadata("key"+strconv.Itoa(i), strconv.Itoa(i))
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			topic.GetMetadataKey("key" + strconv.Itoa(i))
+			topic.GetMetadata()
+		}(i)
+	}
+	wg.Wait()
+	if len(topic.GetMetadata()) != 10 {
+		t.Error("Expected topic to have 10 metadata entries")
+	}
+}
+
+// TestMetadataInit tests that the metadata is sent to the client in the init message.
+func TestMetadataInit(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+	topic.SetMetadata("owner", "bot")
+
+	client := ssePubSub.NewClient()
+	events, stop := startClient(t, client)
+	stop()
+
+	for _, d := range events() {
+		for _, sys := range d.Sys {
+			for _, l := range sys.List {
+				if sys.Type == "topics" && l.Name == "test" && l.Metadata["owner"] == "bot" {
+					return
+				}
+			}
+		}
+	}
+	t.Error("Expected metadata in the init message")
+}
+
+// TestPubWithPriority tests that high priority messages are not blocked by queued normal messages.
+func TestPubWithPriority(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+
+	// Block the event stream until all messages are queued
+	gate := make(chan struct{})
+	lock := sync.Mutex{}
+	msgs := []string{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Start(ctx, func(msg string) {
+			<-gate
+			lock.Lock()
+			msgs = append(msgs, msg)
+			lock.Unlock()
+		})
+	}()
+	for client.GetStatus() != Receving {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Queue a burst of normal messages followed by a high priority message
+	for i := 0; i < 10; i++ {
+		if err := topic.Pub("normal"); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := topic.PubWithPriority("high", PriorityHigh); err != nil {
+		t.Error(err)
+	}
+	close(gate)
+
+	for {
+		lock.Lock()
+		n := len(msgs)
+		lock.Unlock()
+		if n == 12 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// msgs[0] is the init message
+	if !strings.Contains(msgs[1], `"data":"high"`) {
+		t.Errorf("Expected the high priority message first, got %s", msgs[1])
+	}
+}
+
+// TestPubScheduled tests the PubScheduled() and CancelAllScheduled() methods.
+func TestPubScheduled(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	events, stop := startClient(t, client)
+
+	if _, err := topic.PubScheduled(-time.Second, "invalid"); err == nil {
+		t.Error("Expected error for negative delay")
+	}
+
+	// A cancelled publish is not delivered
+	cancel, err := topic.PubScheduled(20*time.Millisecond, "cancelled")
+	if err != nil {
+		t.Error(err)
+	}
+	cancel()
+
+	// All pending publishes are cancelled
+	for i := 0; i < 3; i++ {
+		if _, err := topic.PubScheduled(20*time.Millisecond, "cancelledall"); err != nil {
+			t.Error(err)
+		}
+	}
+	topic.CancelAllScheduled()
+
+	// The scheduled publish arrives after the delay
+	start := time.Now()
+	if _, err := topic.PubScheduled(30*time.Millisecond, "scheduled"); err != nil {
+		t.Error(err)
+	}
+	var received time.Time
+	for received.IsZero() && time.Since(start) < time.Second {
+		for _, d := range events() {
+			if len(d.Updates) > 0 {
+				received = time.Now()
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	updates := []eventDataUpdates{}
+	for _, d := range events() {
+		updates = append(updates, d.Updates...)
+	}
+	if len(updates) != 1 || decodeData(updates[0].Data) != "scheduled" {
+		t.Fatalf("Expected only the scheduled update, got %v", updates)
+	}
+	if received.Sub(start) < 30*time.Millisecond {
+		t.Error("Expected the scheduled update after the delay")
+	}
+}
+
+// TestTxPublish tests the TxPublish() method.
+func TestTxPublish(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService(WithWriteTimeout(time.Second))
+	client := ssePubSub.NewClient()
+	topic := client.NewPrivateTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Error(err)
+	}
+	events, stop := startClient(t, client)
+
+	// A failed transaction publishes nothing and its tx can not be used afterwards
+	var leaked *TopicTx
+	if err := topic.TxPublish(func(tx *TopicTx) error {
+		leaked = tx
+		tx.Pub("rollback")
+		return errors.New("failed")
+	}); err == nil {
+		t.Error("Expected the error of the transaction")
+	}
+	if err := leaked.Pub("late"); err != ErrTxDone {
+		t.Error("Expected ErrTxDone")
+	}
+
+	// Concurrent transactions and publishes do not interleave
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			err := topic.TxPublish(func(tx *TopicTx) error {
+				for j := 0; j < 3; j++ {
+					if err := tx.Pub(fmt.Sprintf("tx%d-%d", i, j)); err != nil {
+						return err
+					}
+					time.Sleep(time.Millisecond)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := topic.Pub("single"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	stop()
+
+	// Every transaction arrives as one message with its updates in order
+	txs := 0
+	singles := 0
+	for _, d := range events() {
+		if len(d.Updates) == 0 {
+			continue
+		}
+		if decodeData(d.Updates[0].Data) == "single" {
+			singles++
+			continue
+		}
+		txs++
+		if len(d.Updates) != 3 {
+			t.Fatalf("Expected 3 updates in a transaction, got %v", d.Updates)
+		}
+		prefix := strings.TrimSuffix(decodeData(d.Updates[0].Data).(string), "-0")
+		for j, u := range d.Updates {
+			if decodeData(u.Data) != fmt.Sprintf("%s-%d", prefix, j) {
+				t.Errorf("Expected %s-%d, got %s", prefix, j, u.Data)
+			}
+		}
+	}
+	if txs != 10 || singles != 10 {
+		t.Errorf("Expected 10 transactions and 10 single messages, got %d and %d", txs, singles)
+	}
+}
+
+// TestPubAsync tests the PubAsync() method.
+func TestPubAsync(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic := ssePubSub.NewPublicTopic("test")
+
+	// 3 receiving clients and 1 client without event stream
+	events := make([]func() []eventData, 3)
+	for i := range events {
+		client := ssePubSub.NewClient()
+		if err := client.Sub(topic); err != nil {
+			t.Fatal(err)
+		}
+		var stop func()
+		events[i], stop = startClient(t, client)
+		defer stop()
+	}
+	if err := ssePubSub.NewClient().Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+
+	pubs := 0
+	topic.OnPub(func(interface{}) { pubs++ })
+
+	// The channel is closed after all sends completed
+	errs := []error{}
+	for err := range topic.PubAsync("testdata") {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error for the client without event stream, got %v", errs)
+	}
+	if pubs != 1 {
+		t.Errorf("Expected 1 OnPub event, got %d", pubs)
+	}
+
+	// The channel can be ignored
+	topic.PubAsync("ignored")
+
+	for i := range events {
+		deadline := time.Now().Add(time.Second)
+		for len(updatesData(events[i]())) < 2 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected client %d to receive both messages, got %v", i, updatesData(events[i]()))
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if data := updatesData(events[i]()); data[0] != "testdata" || data[1] != "ignored" {
+			t.Errorf("Expected testdata and ignored, got %v", data)
+		}
+	}
+}
+
+// Start n clients with an event stream that discards all messages
+func startDiscardClients(b *testing.B, ssePubSub *SSEPubSubService, topic *Topic, n int) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		client := ssePubSub.NewClient()
+		if err := client.Sub(topic); err != nil {
+			b.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Start(ctx, func(string) {})
+		}()
+		for client.GetStatus() != Receving {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// BenchmarkPub_FanOut compares the serial fan-out of Pub with the concurrent fan-out of PubAsync
+// to 1000 subscribers with 1KB payloads.
+func BenchmarkPub_FanOut(b *testing.B) {
+	payload := strings.Repeat("x", 1024)
+
+	for _, bm := range []struct {
+		name string
+		pub  func(topic *Topic) error
+	}{
+		{"Serial", func(topic *Topic) error { return topic.Pub(payload) }},
+		{"Async", func(topic *Topic) error {
+			for err := range topic.PubAsync(payload) {
+				return err
+			}
+			return nil
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ssePubSub := MustNewSSEPubSubService(WithLogger(NoOpLogger{}))
+			topic := ssePubSub.NewPublicTopic("test")
+			stop := startDiscardClients(b, ssePubSub, topic, 1000)
+			defer stop()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := bm.pub(topic); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPubJSON_FanOut compares encoding the message once per subscriber with encoding it once per publish
+// for 10,000 subscribers with 1KB payloads.
+func BenchmarkPubJSON_FanOut(b *testing.B) {
+	payload := strings.Repeat("x", 1024)
+
+	for _, bm := range []struct {
+		name string
+		pub  func(ssePubSub *SSEPubSubService, topic *Topic) error
+	}{
+		{"PerClient", func(ssePubSub *SSEPubSubService, topic *Topic) error {
+			u, err := newUpdate(topic.GetName(), payload)
+			if err != nil {
+				return err
+			}
+			for _, c := range topic.GetClients() {
+				c.send(&eventData{Updates: []eventDataUpdates{u}})
+			}
+			return nil
+		}},
+		{"PubJSON", func(ssePubSub *SSEPubSubService, topic *Topic) error {
+			return ssePubSub.PubJSON(topic.GetName(), payload)
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ssePubSub := MustNewSSEPubSubService(WithLogger(NoOpLogger{}))
+			topic := ssePubSub.NewPublicTopic("test")
+			stop := startDiscardClients(b, ssePubSub, topic, 10000)
+			defer stop()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := bm.pub(ssePubSub, topic); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPubWithPriority measures the time until a message is delivered to a subscriber after a burst of
+// normal messages. A high priority message is not blocked by the queued normal messages, a normal message waits for them.
+func BenchmarkPubWithPriority(b *testing.B) {
+	for _, bm := range []struct {
+		name     string
+		priority Priority
+	}{
+		{"Normal", PriorityNormal},
+		{"High", PriorityHigh},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ssePubSub := MustNewSSEPubSubService(WithLogger(NoOpLogger{}))
+			client := ssePubSub.NewClient()
+			topic := client.NewPrivateTopic("test")
+			if err := client.Sub(topic); err != nil {
+				b.Fatal(err)
+			}
+
+			// The event stream waits while hold is locked, takes 5µs per message and reports the delivery of the marker message
+			hold := sync.Mutex{}
+			delivered := make(chan time.Time, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				client.Start(ctx, func(msg string) {
+					hold.Lock()
+					hold.Unlock()
+					if strings.Contains(msg, `"data":"marker"`) {
+						delivered <- time.Now()
+						return
+					}
+
+					// Writing a message to a connection takes time
+					for start := time.Now(); time.Since(start) < 5*time.Microsecond; {
+					}
+				})
+			}()
+			for client.GetStatus() != Receving {
+				time.Sleep(time.Millisecond)
+			}
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			// The time to delivery is measured from the release of the event stream, b.StartTimer is too slow for it
+			var total time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Fill the normal stream while the event stream waits, then publish the marker
+				hold.Lock()
+				for j := 0; j < streamSize-1; j++ {
+					if err := topic.Pub("normal"); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := topic.PubWithPriority("marker", bm.priority); err != nil {
+					b.Fatal(err)
+				}
+
+				// Wait for the delivery of the marker
+				start := time.Now()
+				hold.Unlock()
+				total += (<-delivered).Sub(start)
+
+				// Wait until the stream is empty
+				for client.queued() > 0 {
+					time.Sleep(10 * time.Microsecond)
+				}
+			}
+			b.ReportMetric(float64(total.Nanoseconds())/float64(b.N), "ns/delivery")
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package pubsubsse
+
+import "context"
+
+// TransformFunc transforms a message before it is published, e.g. to remove personal data or add a server timestamp.
+// Returning nil, nil suppresses the message.
+type TransformFunc func(ctx context.Context, msg interface{}) (interface{}, error)
+
+// Set the transformer of the topic
+// Every message published to the topic is replaced by the result of fn before it is validated and sent.
+// If fn returns an error, the publish returns it and nothing is sent. A nil fn removes the transformer.
+func (t *Topic) SetTransformer(fn TransformFunc) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.transformer = fn
+}
+
+// Build the update of a message with the transformer of the topic
+// ok is false if the transformer suppressed the message.
+func (t *Topic) newUpdate(ctx context.Context, msg interface{}) (u eventDataUpdates, ok bool, err error) {
+	t.lock.Lock()
+	transformer := t.transformer
+	t.lock.Unlock()
+
+	// Transform the message
+	if transformer != nil {
+		msg, err = transformer(ctx, msg)
+		if err != nil {
+			return eventDataUpdates{}, false, err
+		}
+		if msg == nil {
+			return eventDataUpdates{}, false, nil
+		}
+	}
+
+	u, err = newUpdate(t.GetName(), msg)
+	if err != nil {
+		return eventDataUpdates{}, false, err
+	}
+	return u, true, nil
+}
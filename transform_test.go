@@ -0,0 +1,112 @@
+package pubsubsse
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Tests for:
+// Topic:
+// +SetTransformer(fn TransformFunc)
+
+// Replace all string values of a message with their length
+func stringLengths(ctx context.Context, msg interface{}) (interface{}, error) {
+	switch v := msg.(type) {
+	case string:
+		return len(v), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k], _ = stringLengths(ctx, e)
+		}
+		return out, nil
+	}
+	return msg, nil
+}
+
+// TestSetTransformer tests that the subscribers receive the transformed messages
+func TestSetTransformer(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	client := ssePubSub.NewClient()
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+	if err := client.Sub(topic); err != nil {
+		t.Fatal(err)
+	}
+	topic.SetTransformer(stringLengths)
+
+	events, stop := startClient(t, client)
+	if err := topic.Pub(map[string]interface{}{"name": "alice", "age": 30}); err != nil {
+		t.Error(err)
+	}
+	if err := topic.Pub("hello"); err != nil {
+		t.Error(err)
+	}
+	stop()
+
+	updates := []string{}
+	for _, d := range events() {
+		for _, u := range d.Updates {
+			updates = append(updates, string(u.Data))
+		}
+	}
+	if len(updates) != 2 || updates[0] != `{"age":30,"name":5}` || updates[1] != "5" {
+		t.Errorf("Expected the transformed messages, got %v", updates)
+	}
+
+	// The OnPub event gets the transformed message
+	if msgs := recorder.Messages(); len(msgs) != 2 || msgs[1].Data != 5 {
+		t.Errorf("Expected the transformed messages to be recorded, got %v", msgs)
+	}
+}
+
+// TestSetTransformer_ErrorAndSuppress tests that an error aborts the publish and nil suppresses the message
+func TestSetTransformer_ErrorAndSuppress(t *testing.T) {
+	ssePubSub := MustNewSSEPubSubService()
+	topic, recorder := ssePubSub.NewRecordingTopic("test")
+
+	errSecret := errors.New("secret")
+	type ctxKey struct{}
+	topic.SetTransformer(func(ctx context.Context, msg interface{}) (interface{}, error) {
+		if ctx.Value(ctxKey{}) != nil {
+			return "from context", nil
+		}
+		switch msg {
+		case "secret":
+			return nil, errSecret
+		case "drop":
+			return nil, nil
+		}
+		return msg, nil
+	})
+
+	if err := topic.Pub("secret"); !errors.Is(err, errSecret) {
+		t.Errorf("Expected the error of the transformer, got %v", err)
+	}
+	if err := topic.Pub("drop"); err != nil {
+		t.Error(err)
+	}
+	if err := topic.PubCtx(context.WithValue(context.Background(), ctxKey{}, true), "ctx"); err != nil {
+		t.Error(err)
+	}
+	if err := topic.TxPublish(func(tx *TopicTx) error {
+		tx.Pub("drop")
+		return tx.Pub("tx")
+	}); err != nil {
+		t.Error(err)
+	}
+
+	msgs := recorder.Messages()
+	if len(msgs) != 2 || msgs[0].Data != "from context" || msgs[1].Data != "tx" {
+		t.Errorf("Expected only the messages of the context and the transaction, got %v", msgs)
+	}
+
+	// A nil transformer publishes the messages unchanged
+	topic.SetTransformer(nil)
+	if err := topic.Pub("secret"); err != nil {
+		t.Error(err)
+	}
+	if msgs := recorder.Messages(); len(msgs) != 3 {
+		t.Errorf("Expected 3 messages, got %d", len(msgs))
+	}
+}